@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goserve/server"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyPrefix starts every API key: gs_<id>_<secret>
+const APIKeyPrefix = "gs_"
+
+// ErrInvalidAPIKey is returned by Authenticate for malformed, unknown,
+// expired and revoked keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey is a stored key. The secret is only known when the key is created
+// or rotated; the store keeps a salted SHA-256 hash of it.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Salt   string   `json:"salt"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes,omitempty"`
+	// Requests allowed per minute, unlimited when 0
+	RateLimit int       `json:"rateLimit,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	RotatedAt time.Time `json:"rotatedAt,omitzero"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	RevokedAt time.Time `json:"revokedAt,omitzero"`
+}
+
+// Status is "active", "expired" or "revoked"
+func (k *APIKey) Status(now time.Time) string {
+	switch {
+	case !k.RevokedAt.IsZero():
+		return "revoked"
+	case !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt):
+		return "expired"
+	}
+	return "active"
+}
+
+func (k *APIKey) matches(secret string) bool {
+	salt, err := base64.RawStdEncoding.DecodeString(k.Salt)
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(k.Hash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hashSecret(salt, secret), expected) == 1
+}
+
+func hashSecret(salt []byte, secret string) []byte {
+	digest := sha256.Sum256(append(append([]byte{}, salt...), secret...))
+	return digest[:]
+}
+
+// setSecret draws a new secret and salt, and returns the full key
+func (k *APIKey) setSecret() (string, error) {
+	random := make([]byte, 16+32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	salt, secret := random[:16], base64.RawURLEncoding.EncodeToString(random[16:])
+	k.Salt = base64.RawStdEncoding.EncodeToString(salt)
+	k.Hash = base64.RawStdEncoding.EncodeToString(hashSecret(salt, secret))
+	return APIKeyPrefix + k.ID + "_" + secret, nil
+}
+
+// KeyOptions are the settings of a new key
+type KeyOptions struct {
+	Scopes []string
+	// Validity of the key, no expiry when 0
+	TTL time.Duration
+	// Requests allowed per minute, unlimited when 0
+	RateLimit int
+}
+
+// APIKeys authenticates machine clients with keys from a store:
+//
+//	keys := auth.NewAPIKeys(store)
+//	builder.
+//		AddGlobalMiddleware("apikey", keys.Middleware()).
+//		AddGlobalMiddleware("policy", policy.Middleware()).
+//		AddRoutes(auth.RequireAPIKey(routes...))
+//
+// The key is read from the X-API-Key header, or from a query parameter
+// enabled with WithQuery.
+type APIKeys struct {
+	store  KeyStore
+	header string
+	query  string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+func NewAPIKeys(store KeyStore) *APIKeys {
+	return &APIKeys{
+		store:    store,
+		header:   "X-API-Key",
+		now:      time.Now,
+		limiters: make(map[string]*limiter),
+	}
+}
+
+// WithHeader changes the header carrying the key
+func (k *APIKeys) WithHeader(name string) *APIKeys {
+	k.header = name
+	return k
+}
+
+// WithQuery also accepts the key in a query parameter, e.g. "api_key".
+// Query strings end up in access logs: prefer the header.
+func (k *APIKeys) WithQuery(name string) *APIKeys {
+	k.query = name
+	return k
+}
+
+func newKeyID() (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// Create stores a new key and returns it with its secret, shown only once
+func (k *APIKeys) Create(name string, options KeyOptions) (string, *APIKey, error) {
+	id, err := newKeyID()
+	if err != nil {
+		return "", nil, err
+	}
+	key := &APIKey{
+		ID:        id,
+		Name:      name,
+		Scopes:    options.Scopes,
+		RateLimit: options.RateLimit,
+		CreatedAt: k.now().UTC(),
+	}
+	if options.TTL > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(options.TTL)
+	}
+	secret, err := key.setSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := k.store.Save(key); err != nil {
+		return "", nil, err
+	}
+	return secret, key, nil
+}
+
+// Rotate replaces the secret of a key, keeping its ID and settings; the
+// previous secret stops working
+func (k *APIKeys) Rotate(id string) (string, *APIKey, error) {
+	key, err := k.store.Get(id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !key.RevokedAt.IsZero() {
+		return "", nil, fmt.Errorf("API key %s is revoked", id)
+	}
+	secret, err := key.setSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	key.RotatedAt = k.now().UTC()
+	if err := k.store.Save(key); err != nil {
+		return "", nil, err
+	}
+	return secret, key, nil
+}
+
+// Revoke disables a key, kept in the store for auditing
+func (k *APIKeys) Revoke(id string) (*APIKey, error) {
+	key, err := k.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt.IsZero() {
+		key.RevokedAt = k.now().UTC()
+		if err := k.store.Save(key); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func (k *APIKeys) List() ([]*APIKey, error) {
+	return k.store.List()
+}
+
+// Authenticate returns the active key matching the full key
+func (k *APIKeys) Authenticate(full string) (*APIKey, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(full, APIKeyPrefix), "_")
+	if !strings.HasPrefix(full, APIKeyPrefix) || !ok {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidAPIKey)
+	}
+	key, err := k.store.Get(id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !key.matches(secret) {
+		return nil, ErrInvalidAPIKey
+	}
+	if status := key.Status(k.now()); status != "active" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAPIKey, status)
+	}
+	return key, nil
+}
+
+// Middleware authenticates the routes selected with RequireAPIKey or
+// WithMeta("auth", "apikey"). Invalid keys get a 401 problem and keys over
+// their rate limit a 429 with Retry-After.
+func (k *APIKeys) Middleware() server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := server.RouteFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if method, _ := route.GetMeta(MetaAuth); method != "apikey" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			full := r.Header.Get(k.header)
+			if full == "" && k.query != "" {
+				full = r.URL.Query().Get(k.query)
+			}
+			if full == "" {
+				unauthorized(w, "ApiKey", "missing API key")
+				return
+			}
+			key, err := k.Authenticate(full)
+			if errors.Is(err, ErrInvalidAPIKey) {
+				unauthorized(w, "ApiKey", err.Error())
+				return
+			}
+			if err != nil {
+				log.Printf("Authenticating an API key: %v", err)
+				server.WriteError(w, err)
+				return
+			}
+			if wait := k.limiter(key).take(k.now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				server.WriteProblem(w, server.NewProblem(http.StatusTooManyRequests, fmt.Sprintf("rate limit of %d requests per minute exceeded", key.RateLimit)))
+				return
+			}
+
+			principal := &Principal{Subject: key.ID, Method: "apikey", Scopes: key.Scopes}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+		return server.EnforcedHandler{Handler: handler, Enforces: authMethod("apikey")}
+	}
+}
+
+// RequireAPIKey marks a group of routes as authenticated with API keys
+func RequireAPIKey(routes ...server.RouteInfo) []server.RouteInfo {
+	for _, route := range routes {
+		route.WithMeta(MetaAuth, "apikey")
+	}
+	return routes
+}
+
+// limiter returns the token bucket of a key, replaced when its limit changes
+func (k *APIKeys) limiter(key *APIKey) *limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	current, ok := k.limiters[key.ID]
+	if !ok || current.perMinute != key.RateLimit {
+		current = &limiter{perMinute: key.RateLimit, tokens: float64(key.RateLimit)}
+		k.limiters[key.ID] = current
+	}
+	return current
+}
+
+// limiter is a token bucket holding a minute of requests
+type limiter struct {
+	mu        sync.Mutex
+	perMinute int
+	tokens    float64
+	last      time.Time
+}
+
+// take consumes a token, or returns how long to wait for the next one
+func (l *limiter) take(now time.Time) time.Duration {
+	if l.perMinute <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate := float64(l.perMinute) / float64(time.Minute)
+	if !l.last.IsZero() {
+		l.tokens = math.Min(float64(l.perMinute), l.tokens+float64(now.Sub(l.last))*rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / rate)
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClock is the time of the API keys under test, moved by the tests
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestAPIKeys(store KeyStore) (*APIKeys, *testClock) {
+	clock := &testClock{now: testNow}
+	keys := NewAPIKeys(store)
+	keys.now = clock.Now
+	return keys, clock
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys, clock := newTestAPIKeys(NewMemoryKeyStore())
+	full, key, err := keys.Create("billing", KeyOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(full, APIKeyPrefix+key.ID+"_") {
+		t.Fatalf("unexpected key format %q", full)
+	}
+	if strings.Contains(key.Hash, strings.TrimPrefix(full, APIKeyPrefix+key.ID+"_")) {
+		t.Fatal("the store holds the secret")
+	}
+	other, _, err := keys.Create("reports", KeyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherSecret, _ := strings.Cut(strings.TrimPrefix(other, APIKeyPrefix), "_")
+
+	tests := []struct {
+		name string
+		full string
+		err  string
+	}{
+		{"valid", full, ""},
+		{"missing prefix", strings.TrimPrefix(full, APIKeyPrefix), "invalid API key: malformed"},
+		{"missing secret", APIKeyPrefix + key.ID, "invalid API key: malformed"},
+		{"unknown ID", APIKeyPrefix + "0000000000000000_secret", "invalid API key"},
+		{"wrong secret", full + "x", "invalid API key"},
+		{"secret of another key", APIKeyPrefix + key.ID + "_" + otherSecret, "invalid API key"},
+		{"empty", "", "invalid API key: malformed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := keys.Authenticate(test.full)
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("expected %q, got %v", test.err, err)
+			case test.err != "" && !errors.Is(err, ErrInvalidAPIKey):
+				t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+			}
+		})
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		clock.now = testNow.Add(time.Hour)
+		defer func() { clock.now = testNow }()
+		if _, err := keys.Authenticate(full); err == nil || err.Error() != "invalid API key: expired" {
+			t.Errorf("expected the key to expire after its TTL, got %v", err)
+		}
+	})
+
+	t.Run("rotated", func(t *testing.T) {
+		rotated, _, err := keys.Rotate(key.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := keys.Authenticate(full); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("expected the previous secret to stop working, got %v", err)
+		}
+		if _, err := keys.Authenticate(rotated); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		full = rotated
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		if _, err := keys.Revoke(key.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := keys.Authenticate(full); err == nil || err.Error() != "invalid API key: revoked" {
+			t.Errorf("expected the key to be revoked, got %v", err)
+		}
+		if _, _, err := keys.Rotate(key.ID); err == nil {
+			t.Error("expected a revoked key not to rotate")
+		}
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	l := &limiter{perMinute: 2, tokens: 2}
+	for i := 0; i < 2; i++ {
+		if wait := l.take(testNow); wait != 0 {
+			t.Fatalf("request %d: expected the burst to be allowed, got a wait of %v", i+1, wait)
+		}
+	}
+	if wait := l.take(testNow); wait != 30*time.Second {
+		t.Errorf("expected a wait of 30s for the next token, got %v", wait)
+	}
+	if wait := l.take(testNow.Add(30 * time.Second)); wait != 0 {
+		t.Errorf("expected a token after 30s, got a wait of %v", wait)
+	}
+	// The bucket holds a minute of requests at most
+	if wait := l.take(testNow.Add(time.Hour)); wait != 0 || l.tokens != 1 {
+		t.Errorf("expected a full bucket after an hour, got %v tokens and a wait of %v", l.tokens+1, wait)
+	}
+
+	unlimited := &limiter{}
+	for i := 0; i < 100; i++ {
+		if wait := unlimited.take(testNow); wait != 0 {
+			t.Fatalf("expected no limit, got a wait of %v", wait)
+		}
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	keys, clock := newTestAPIKeys(NewMemoryKeyStore())
+	limited, _, err := keys.Create("limited", KeyOptions{RateLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := server.New().
+		AddGlobalMiddleware("apikey", keys.WithQuery("api_key").Middleware()).
+		AddRoutes(RequireAPIKey(server.CreateGET("/orders", func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := PrincipalFromContext(r.Context())
+			fmt.Fprint(w, principal.Method)
+		})))
+	srv := goservetest.New(t, builder)
+
+	srv.GET("/orders").Expect(t).Status(http.StatusUnauthorized).Header("WWW-Authenticate", "ApiKey")
+	srv.GET("/orders").WithHeader("X-API-Key", limited+"x").Expect(t).Status(http.StatusUnauthorized)
+	srv.GET("/orders").WithHeader("X-API-Key", limited).Expect(t).Status(http.StatusOK).Body("apikey")
+	srv.GET("/orders").WithQuery("api_key", limited).Expect(t).
+		Status(http.StatusTooManyRequests).
+		Header("Retry-After", "60")
+
+	clock.now = clock.now.Add(time.Minute)
+	srv.GET("/orders").WithQuery("api_key", limited).Expect(t).Status(http.StatusOK)
+}
+
+func TestKeyAPI(t *testing.T) {
+	store, err := NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := newTestAPIKeys(store)
+	admins := AuthorizerFunc(func(r *http.Request, p *Principal) error {
+		if !slices.Contains(p.Roles, "admin") {
+			return fmt.Errorf("%w: administrators only", ErrForbidden)
+		}
+		return nil
+	})
+	verifier := newTestJWT(t, JWTConfig{Secret: "0123456789abcdef0123456789abcdef"})
+	builder := server.New().
+		AddGlobalMiddleware("jwt", verifier.Middleware()).
+		AddRoutes(RequireJWT(keys.Routes("/admin/api-keys/", admins)...))
+	srv := goservetest.New(t, builder)
+
+	admin := signToken(t, hs256, validClaims(map[string]interface{}{"roles": []string{"admin"}}), testSecret)
+	user := signToken(t, hs256, validClaims(nil), testSecret)
+
+	srv.GET("/admin/api-keys").Expect(t).Status(http.StatusUnauthorized)
+	if detail := srv.GET("/admin/api-keys").WithBearer(user).Expect(t).Status(http.StatusForbidden).Problem().Detail; detail != "administrators only" {
+		t.Errorf("unexpected detail %q", detail)
+	}
+	srv.POST("/admin/api-keys").WithBearer(user).WithJSON(map[string]string{"name": "ci"}).Expect(t).Status(http.StatusForbidden)
+
+	srv.POST("/admin/api-keys").WithBearer(admin).WithJSON(map[string]string{"name": " "}).Expect(t).Status(http.StatusBadRequest)
+	srv.POST("/admin/api-keys").WithBearer(admin).WithJSON(map[string]string{"name": "ci", "expiresIn": "soon"}).Expect(t).Status(http.StatusBadRequest)
+
+	var created KeyInfo
+	srv.POST("/admin/api-keys").WithBearer(admin).
+		WithJSON(map[string]interface{}{"name": "ci", "scopes": []string{"deploy"}, "expiresIn": "720h"}).
+		Expect(t).Status(http.StatusOK).JSON(&created)
+	if created.Key == "" || created.Status != "active" || !created.ExpiresAt.Equal(testNow.Add(720*time.Hour)) {
+		t.Fatalf("unexpected key %+v", created)
+	}
+	if _, err := keys.Authenticate(created.Key); err != nil {
+		t.Errorf("the created key does not authenticate: %v", err)
+	}
+
+	var listed []KeyInfo
+	srv.GET("/admin/api-keys").WithBearer(admin).Expect(t).Status(http.StatusOK).JSON(&listed)
+	if len(listed) != 1 || listed[0].ID != created.ID || listed[0].Key != "" {
+		t.Errorf("expected the key without its secret, got %+v", listed)
+	}
+
+	var rotated KeyInfo
+	srv.POST("/admin/api-keys/" + created.ID + "/rotate").WithBearer(admin).Expect(t).Status(http.StatusOK).JSON(&rotated)
+	if rotated.Key == "" || rotated.Key == created.Key || !rotated.RotatedAt.Equal(testNow) {
+		t.Errorf("unexpected rotation %+v", rotated)
+	}
+
+	var revoked KeyInfo
+	srv.DELETE("/admin/api-keys/" + created.ID).WithBearer(admin).Expect(t).Status(http.StatusOK).JSON(&revoked)
+	if revoked.Status != "revoked" {
+		t.Errorf("unexpected status %q", revoked.Status)
+	}
+	if _, err := keys.Authenticate(rotated.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected the revoked key to be refused, got %v", err)
+	}
+
+	srv.DELETE("/admin/api-keys/unknown").WithBearer(admin).Expect(t).Status(http.StatusNotFound)
+	srv.POST("/admin/api-keys/unknown/rotate").WithBearer(admin).Expect(t).Status(http.StatusNotFound)
+}
+
+func TestKeyAPIRequiresAnAuthorizer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Routes to refuse a nil authorizer")
+		}
+	}()
+	keys, _ := newTestAPIKeys(NewMemoryKeyStore())
+	keys.Routes("/admin/api-keys", nil)
+}
+
+func TestFileKeyStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "keys.json")
+	first, err := NewFileKeyStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := newTestAPIKeys(first)
+	full, key, err := keys.Create("ci", KeyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second store, such as the CLI, sees the key and its revocation
+	second, err := NewFileKeyStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := newTestAPIKeys(second)
+	if _, err := other.Authenticate(full); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := keys.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Authenticate(full); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected the revocation to be read from the file, got %v", err)
+	}
+}
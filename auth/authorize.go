@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"goserve/server"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Route meta declaring the requirements of a route, []string values:
+//
+//	route.WithMeta(auth.MetaScopes, []string{"orders:write"})
+const (
+	// Every scope is required
+	MetaScopes = "scopes"
+	// One of the roles is enough
+	MetaRoles = "roles"
+	// Every permission is required
+	MetaPermissions = "permissions"
+	// Names of the authorizers registered on the Policy, all must allow
+	MetaPolicies = "policies"
+)
+
+// The requirements fail Build when no middleware enforces them, rather than
+// leaving the routes unprotected
+func init() {
+	server.RequireEnforcement(MetaAuth, func(value interface{}) error {
+		if method, ok := value.(string); !ok || method == "" {
+			return fmt.Errorf("unsupported value %#v, expected the name of an authentication method", value)
+		}
+		return nil
+	})
+	for _, key := range []string{MetaScopes, MetaRoles, MetaPermissions, MetaPolicies} {
+		server.RequireEnforcement(key, func(value interface{}) error {
+			_, err := stringValues(value)
+			return err
+		})
+	}
+}
+
+// ErrForbidden is returned by authorizers denying a request; the message of
+// the error becomes the detail of the 403 problem
+var ErrForbidden = errors.New("forbidden")
+
+// Authorizer decides whether the principal may call the route of the
+// request, e.g. an ownership check on a path value. It returns nil to allow,
+// an error wrapping ErrForbidden or a *server.Problem to deny, and any other
+// error for failures (500).
+type Authorizer interface {
+	Authorize(r *http.Request, principal *Principal) error
+}
+
+type AuthorizerFunc func(r *http.Request, principal *Principal) error
+
+func (f AuthorizerFunc) Authorize(r *http.Request, principal *Principal) error {
+	return f(r, principal)
+}
+
+// Requirements are the authentication and authorization declared on a route
+type Requirements struct {
+	Authentication string   `json:"authentication,omitempty"`
+	Scopes         []string `json:"scopes,omitempty"`
+	Roles          []string `json:"roles,omitempty"`
+	Permissions    []string `json:"permissions,omitempty"`
+	Policies       []string `json:"policies,omitempty"`
+}
+
+// RequirementsOf reads the requirements from the meta of a route; values of
+// unsupported types are an error
+func RequirementsOf(route server.RouteInfo) (Requirements, error) {
+	var requirements Requirements
+	if authentication, ok := route.GetMeta(MetaAuth); ok {
+		method, isString := authentication.(string)
+		if !isString {
+			return requirements, fmt.Errorf("meta %s: unsupported type %T, expected a string", MetaAuth, authentication)
+		}
+		requirements.Authentication = method
+	}
+	for _, field := range []struct {
+		key    string
+		target *[]string
+	}{
+		{MetaScopes, &requirements.Scopes},
+		{MetaRoles, &requirements.Roles},
+		{MetaPermissions, &requirements.Permissions},
+		{MetaPolicies, &requirements.Policies},
+	} {
+		values, err := metaStrings(route, field.key)
+		if err != nil {
+			return requirements, err
+		}
+		*field.target = values
+	}
+	return requirements, nil
+}
+
+// Restricted tells whether the route requires more than authentication
+func (r Requirements) Restricted() bool {
+	return len(r.Scopes)+len(r.Roles)+len(r.Permissions)+len(r.Policies) > 0
+}
+
+func metaStrings(route server.RouteInfo, key string) ([]string, error) {
+	value, _ := route.GetMeta(key)
+	values, err := stringValues(value)
+	if err != nil {
+		return nil, fmt.Errorf("meta %s: %v", key, err)
+	}
+	return values, nil
+}
+
+// stringValues accepts a string or a []string, nil for none
+func stringValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T, expected a string or []string", value)
+}
+
+// addMeta appends values to the meta of the route in a new slice, since the
+// current one may be shared with the caller. An invalid current value is
+// kept for Build to report.
+func addMeta(route server.RouteInfo, key string, values []string) server.RouteInfo {
+	current, err := metaStrings(route, key)
+	if err != nil {
+		return route
+	}
+	return route.WithMeta(key, append(slices.Clone(current), values...))
+}
+
+// RequireScopes requires every scope on the route
+func RequireScopes(route server.RouteInfo, scopes ...string) server.RouteInfo {
+	return addMeta(route, MetaScopes, scopes)
+}
+
+// RequireRoles requires one of the roles on the route
+func RequireRoles(route server.RouteInfo, roles ...string) server.RouteInfo {
+	return addMeta(route, MetaRoles, roles)
+}
+
+// RequirePermissions requires every permission on the route
+func RequirePermissions(route server.RouteInfo, permissions ...string) server.RouteInfo {
+	return addMeta(route, MetaPermissions, permissions)
+}
+
+// RequirePolicies checks the route with authorizers registered on the Policy
+func RequirePolicies(route server.RouteInfo, names ...string) server.RouteInfo {
+	return addMeta(route, MetaPolicies, names)
+}
+
+// Policy enforces the requirements of the routes against the principal set
+// by the authentication middlewares, so it is registered after them:
+//
+//	policy := auth.NewPolicy().Register("order-owner", auth.AuthorizerFunc(isOwner))
+//	builder.
+//		AddGlobalMiddleware("jwt", verifier.Middleware()).
+//		AddGlobalMiddleware("policy", policy.Middleware())
+//
+// Restricted routes answer 401 without a principal and 403 when a
+// requirement is not met. Authorizers are registered before Build, which
+// fails on routes using a policy that is not registered.
+type Policy struct {
+	authorizers map[string]Authorizer
+}
+
+func NewPolicy() *Policy {
+	return &Policy{authorizers: make(map[string]Authorizer)}
+}
+
+// Register names an authorizer for RequirePolicies
+func (p *Policy) Register(name string, authorizer Authorizer) *Policy {
+	p.authorizers[name] = authorizer
+	return p
+}
+
+func (p *Policy) Middleware() server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := server.RouteFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			requirements, err := RequirementsOf(route)
+			if err != nil {
+				log.Printf("Authorizing %s %s: %v", r.Method, r.URL.Path, err)
+				server.WriteError(w, err)
+				return
+			}
+			if !requirements.Restricted() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, "Bearer", "authentication required")
+				return
+			}
+			if err := p.authorize(r, principal, requirements); err != nil {
+				var problem *server.Problem
+				switch {
+				case errors.As(err, &problem):
+					server.WriteProblem(w, problem)
+				case errors.Is(err, ErrForbidden):
+					detail := strings.TrimPrefix(err.Error(), ErrForbidden.Error()+": ")
+					server.WriteProblem(w, server.NewProblem(http.StatusForbidden, detail))
+				default:
+					log.Printf("Authorizing %s %s: %v", r.Method, r.URL.Path, err)
+					server.WriteError(w, err)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+		return server.EnforcedHandler{Handler: handler, Enforces: p.enforces}
+	}
+}
+
+// enforces tells Build which requirements the policy checks: all of them,
+// as long as the policies they name are registered
+func (p *Policy) enforces(key string, value interface{}) bool {
+	switch key {
+	case MetaScopes, MetaRoles, MetaPermissions:
+		return true
+	case MetaPolicies:
+		names, _ := stringValues(value)
+		for _, name := range names {
+			if _, ok := p.authorizers[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (p *Policy) authorize(r *http.Request, principal *Principal, requirements Requirements) error {
+	for _, scope := range requirements.Scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return fmt.Errorf("%w: missing scope %s", ErrForbidden, scope)
+		}
+	}
+	if len(requirements.Roles) > 0 && !slices.ContainsFunc(requirements.Roles, func(role string) bool {
+		return slices.Contains(principal.Roles, role)
+	}) {
+		return fmt.Errorf("%w: requires one of the roles %s", ErrForbidden, strings.Join(requirements.Roles, ", "))
+	}
+	for _, permission := range requirements.Permissions {
+		if !slices.Contains(principal.Permissions, permission) {
+			return fmt.Errorf("%w: missing permission %s", ErrForbidden, permission)
+		}
+	}
+	for _, name := range requirements.Policies {
+		authorizer, ok := p.authorizers[name]
+		if !ok {
+			return fmt.Errorf("unknown policy %q", name)
+		}
+		if err := authorizer.Authorize(r, principal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RouteReport is a line of Report
+type RouteReport struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Requirements
+	// Declarations that cannot be enforced as written
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Report lists the requirements of every route, sorted by path, for security
+// reviews. Routes restricted without authentication, or using a policy
+// that is not registered when policy is not nil, get a warning.
+func Report(routes []server.RouteInfo, policy *Policy) []RouteReport {
+	report := make([]RouteReport, 0, len(routes))
+	for _, route := range routes {
+		requirements, err := RequirementsOf(route)
+		line := RouteReport{
+			Method:       string(route.GetMethod()),
+			Path:         route.GetPath(),
+			Requirements: requirements,
+		}
+		if err != nil {
+			line.Warnings = append(line.Warnings, err.Error())
+		}
+		if line.Restricted() && line.Authentication == "" {
+			line.Warnings = append(line.Warnings, "restricted without authentication, always answers 401")
+		}
+		if policy != nil {
+			for _, name := range line.Policies {
+				if _, ok := policy.authorizers[name]; !ok {
+					line.Warnings = append(line.Warnings, fmt.Sprintf("policy %q is not registered", name))
+				}
+			}
+		}
+		report = append(report, line)
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Path != report[j].Path {
+			return report[i].Path < report[j].Path
+		}
+		return report[i].Method < report[j].Method
+	})
+	return report
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// buildPanic builds the server and returns what Build panicked with
+func buildPanic(builder server.ServerBuilder) (message string) {
+	defer func() {
+		if r := recover(); r != nil {
+			message = fmt.Sprint(r)
+		}
+	}()
+	builder.Build()
+	return ""
+}
+
+func TestBuildFailsOnUnenforcedMeta(t *testing.T) {
+	verifier := newTestJWT(t, JWTConfig{Secret: "0123456789abcdef0123456789abcdef"})
+	policy := NewPolicy().Register("owner", AuthorizerFunc(func(r *http.Request, p *Principal) error { return nil }))
+
+	tests := []struct {
+		name        string
+		routes      func() []server.RouteInfo
+		middlewares []server.MiddlewareInfo
+		// Expected panic, empty when Build succeeds
+		panic string
+	}{
+		{
+			name:   "jwt route without the middleware",
+			routes: func() []server.RouteInfo { return RequireJWT(server.CreateGET("/orders", ok)) },
+			panic:  "GET /orders: no middleware enforces meta auth=jwt",
+		},
+		{
+			name:        "jwt route with the middleware",
+			routes:      func() []server.RouteInfo { return RequireJWT(server.CreateGET("/orders", ok)) },
+			middlewares: []server.MiddlewareInfo{{Name: "jwt", Middleware: verifier.Middleware()}},
+		},
+		{
+			name: "jwt middleware on the route",
+			routes: func() []server.RouteInfo {
+				return RequireJWT(server.CreateGET("/orders", ok).WithMiddleware(verifier.Middleware()))
+			},
+		},
+		{
+			name:        "apikey route with the jwt middleware",
+			routes:      func() []server.RouteInfo { return RequireAPIKey(server.CreateGET("/orders", ok)) },
+			middlewares: []server.MiddlewareInfo{{Name: "jwt", Middleware: verifier.Middleware()}},
+			panic:       "GET /orders: no middleware enforces meta auth=apikey",
+		},
+		{
+			name: "scopes without the policy",
+			routes: func() []server.RouteInfo {
+				return RequireJWT(RequireScopes(server.CreateGET("/orders", ok), "orders:read"))
+			},
+			middlewares: []server.MiddlewareInfo{{Name: "jwt", Middleware: verifier.Middleware()}},
+			panic:       "GET /orders: no middleware enforces meta scopes=[orders:read]",
+		},
+		{
+			name: "unregistered policy",
+			routes: func() []server.RouteInfo {
+				return []server.RouteInfo{RequirePolicies(server.CreateDELETE("/orders/{id}", ok), "owner", "admin")}
+			},
+			middlewares: []server.MiddlewareInfo{
+				{Name: "policy", Middleware: policy.Middleware()},
+			},
+			panic: "DELETE /orders/{id}: no middleware enforces meta policies=[owner admin]",
+		},
+		{
+			name: "unsupported meta type",
+			routes: func() []server.RouteInfo {
+				return []server.RouteInfo{server.CreateGET("/orders", ok).WithMeta(MetaRoles, []interface{}{"admin"})}
+			},
+			middlewares: []server.MiddlewareInfo{{Name: "policy", Middleware: policy.Middleware()}},
+			panic:       "GET /orders: meta roles: unsupported type []interface {}, expected a string or []string",
+		},
+		{
+			name: "unsupported auth method type",
+			routes: func() []server.RouteInfo {
+				return []server.RouteInfo{server.CreateGET("/orders", ok).WithMeta(MetaAuth, true)}
+			},
+			panic: "GET /orders: meta auth: unsupported value true",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := server.New().AddRoutes(test.routes())
+			for _, mw := range test.middlewares {
+				builder.AddGlobalMiddleware(mw.Name, mw.Middleware)
+			}
+			message := buildPanic(builder)
+			switch {
+			case test.panic == "" && message != "":
+				t.Errorf("unexpected panic %s", message)
+			case test.panic != "" && !strings.Contains(message, test.panic):
+				t.Errorf("expected a panic with %q, got %q", test.panic, message)
+			}
+		})
+	}
+}
+
+func TestWithoutMiddlewareStandsIn(t *testing.T) {
+	verifier := newTestJWT(t, JWTConfig{Secret: "0123456789abcdef0123456789abcdef"})
+	builder := server.New().
+		AddGlobalMiddleware("jwt", verifier.Middleware()).
+		AddRoutes(RequireJWT(server.CreateGET("/orders", ok)))
+
+	srv := goservetest.New(t, builder, goservetest.WithoutMiddleware("jwt"))
+	srv.GET("/orders").Expect(t).Status(http.StatusOK)
+}
+
+func TestAddMetaCopies(t *testing.T) {
+	shared := make([]string, 1, 4)
+	shared[0] = "orders:read"
+	first := RequireScopes(server.CreateGET("/a", ok).WithMeta(MetaScopes, shared), "orders:write")
+	second := RequireScopes(server.CreateGET("/b", ok).WithMeta(MetaScopes, shared), "orders:delete")
+
+	a, _ := RequirementsOf(first)
+	b, _ := RequirementsOf(second)
+	if strings.Join(a.Scopes, " ") != "orders:read orders:write" || strings.Join(b.Scopes, " ") != "orders:read orders:delete" {
+		t.Errorf("routes share their scopes: %v and %v", a.Scopes, b.Scopes)
+	}
+	if len(shared) != 1 || shared[:2][1] != "" {
+		t.Errorf("the caller slice was changed: %v", shared[:2])
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	verifier := newTestJWT(t, JWTConfig{Secret: "0123456789abcdef0123456789abcdef"})
+	policy := NewPolicy().Register("owner", AuthorizerFunc(func(r *http.Request, p *Principal) error {
+		if r.PathValue("id") != p.Subject {
+			return fmt.Errorf("%w: not the owner", ErrForbidden)
+		}
+		return nil
+	})).Register("broken", AuthorizerFunc(func(r *http.Request, p *Principal) error {
+		return errors.New("database down")
+	}))
+
+	builder := server.New().
+		AddGlobalMiddleware("jwt", verifier.Middleware()).
+		AddGlobalMiddleware("policy", policy.Middleware()).
+		AddRoutes(RequireJWT(
+			RequireScopes(server.CreateGET("/orders", ok), "orders:read"),
+			RequireRoles(server.CreateGET("/admin", ok), "admin", "support"),
+			RequirePermissions(server.CreateGET("/reports", ok), "reports:read"),
+			RequirePolicies(server.CreateGET("/users/{id}", ok), "owner"),
+			RequirePolicies(server.CreateGET("/broken", ok), "broken"),
+		))
+	srv := goservetest.New(t, builder)
+
+	token := func(claims map[string]interface{}) string {
+		return signToken(t, hs256, validClaims(claims), testSecret)
+	}
+	tests := []struct {
+		name   string
+		path   string
+		claims map[string]interface{}
+		status int
+		detail string
+	}{
+		{"scope granted", "/orders", map[string]interface{}{"scope": "orders:read orders:write"}, http.StatusOK, ""},
+		{"scope missing", "/orders", map[string]interface{}{"scope": "orders:write"}, http.StatusForbidden, "missing scope orders:read"},
+		{"one of the roles", "/admin", map[string]interface{}{"roles": []string{"support"}}, http.StatusOK, ""},
+		{"no role", "/admin", map[string]interface{}{"roles": []string{"guest"}}, http.StatusForbidden, "requires one of the roles admin, support"},
+		{"permission", "/reports", map[string]interface{}{"permissions": []string{"reports:read"}}, http.StatusOK, ""},
+		{"permission missing", "/reports", nil, http.StatusForbidden, "missing permission reports:read"},
+		{"owner", "/users/alice", nil, http.StatusOK, ""},
+		{"not the owner", "/users/bob", nil, http.StatusForbidden, "not the owner"},
+		{"failing authorizer", "/broken", nil, http.StatusInternalServerError, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := srv.GET(test.path).WithBearer(token(test.claims)).Expect(t).Status(test.status)
+			if test.detail != "" && response.Problem().Detail != test.detail {
+				t.Errorf("expected %q, got %q", test.detail, response.Problem().Detail)
+			}
+		})
+	}
+
+	srv.GET("/orders").Expect(t).Status(http.StatusUnauthorized)
+}
@@ -0,0 +1,105 @@
+// Package auth authenticates and authorizes requests.
+//
+// JWT bearer tokens are verified with the standard crypto library against
+// keys from the configuration or a local JWKS file. Routes opt in with
+// WithMeta("auth", "jwt") or RequireJWT, and handlers read the verified
+// claims with ClaimsFromContext and the caller with PrincipalFromContext.
+//
+// Machine clients use API keys (APIKeys) checked against a KeyStore holding
+// salted hashes, with per-key scopes, expiry and rate limits. Routes opt in
+// with RequireAPIKey.
+//
+// Routes declare the scopes, roles, permissions and policies they require
+// (RequireScopes, RequireRoles, RequirePermissions, RequirePolicies), which
+// the Policy middleware enforces; Report lists them for security reviews.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Claims are the verified claims of a token, numbers decoded as float64
+type Claims map[string]interface{}
+
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// String returns a string claim, empty when missing or of another type
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Strings returns a claim holding a string or a list of strings
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Audience returns the aud claim, a string or a list in tokens
+func (c Claims) Audience() []string {
+	return c.Strings("aud")
+}
+
+// Scopes returns the space separated scope claim, or the scp list
+func (c Claims) Scopes() []string {
+	if scope := c.String("scope"); scope != "" {
+		return strings.Fields(scope)
+	}
+	return c.Strings("scp")
+}
+
+// maxNumericDate is the end of year 9999, later dates are rejected
+const maxNumericDate = 253402300799
+
+// Time returns a NumericDate claim such as exp, nbf or iat. Dates before
+// 1970 or after year 9999 are an error.
+func (c Claims) Time(name string) (time.Time, bool, error) {
+	value, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, true, fmt.Errorf("claim %s is not a number", name)
+	}
+	if math.IsNaN(seconds) || seconds < 0 || seconds > maxNumericDate {
+		return time.Time{}, true, fmt.Errorf("claim %s is out of range", name)
+	}
+	whole := int64(seconds)
+	return time.Unix(whole, int64((seconds-float64(whole))*float64(time.Second))), true, nil
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the token authenticating the
+// request
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// WithClaims stores claims in the context, e.g. in tests
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goserve/configuration"
+	"goserve/server"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetaAuth is the route meta selecting the authentication of a route:
+//
+//	server.CreateGET("/orders", listOrders).WithMeta(auth.MetaAuth, "jwt")
+const MetaAuth = "auth"
+
+// JWTConfig configures the verification of bearer tokens, usually bound from
+// the configuration:
+//
+//	builder.Section("app.jwt", &jwtConfig)
+type JWTConfig struct {
+	// Expected iss claim, not checked when empty
+	Issuer string `json:"issuer" env:"JWT_ISSUER" description:"expected iss claim"`
+	// Accepted aud claims, one is enough; not checked when empty
+	Audience []string `json:"audience" env:"JWT_AUDIENCE" description:"accepted aud claims"`
+	// Accepted algorithms, all of Algorithms when empty
+	Algorithms []string `json:"algorithms" description:"accepted signature algorithms"`
+	// Key of the HS256, HS384 and HS512 signatures, at least as long as
+	// their hash: 32 bytes for HS256, 48 for HS384 and 64 for HS512
+	Secret configuration.Secret `json:"secret" env:"JWT_SECRET" description:"HMAC key, 32 bytes at least"`
+	// PEM public keys or certificates by key ID
+	Keys map[string]string `json:"keys" description:"PEM public keys by key ID"`
+	// JWKS file, read again when it changes
+	JWKSFile string `json:"jwks-file" env:"JWT_JWKS_FILE" description:"local JWKS file"`
+	// How often the JWKS file is checked for changes
+	JWKSReload time.Duration `json:"jwks-reload" default:"1m" description:"interval between checks of the JWKS file"`
+	// Tolerance on exp and nbf for clocks out of sync
+	ClockSkew time.Duration `json:"clock-skew" default:"30s" description:"tolerance on exp and nbf"`
+	// Claim listing the roles of the caller
+	RolesClaim string `json:"roles-claim" default:"roles" description:"claim listing the roles of the caller"`
+	// Accepts tokens without an exp claim, which never expire
+	AllowMissingExpiry bool `json:"allow-missing-expiry" description:"accept tokens without an exp claim, which never expire"`
+}
+
+// JWT verifies bearer tokens
+type JWT struct {
+	config JWTConfig
+	// Keys from the configuration, then from the JWKS file
+	static []verificationKey
+	now    func() time.Time
+
+	mu       sync.Mutex
+	jwks     []verificationKey
+	jwksTime time.Time
+	checked  time.Time
+}
+
+// NewJWT loads the keys of config; the JWKS file must be readable
+func NewJWT(config JWTConfig) (*JWT, error) {
+	for _, alg := range config.Algorithms {
+		if !slices.Contains(Algorithms, alg) {
+			return nil, fmt.Errorf("jwt: unsupported algorithm %q, expected one of %v", alg, Algorithms)
+		}
+	}
+	if config.JWKSReload <= 0 {
+		config.JWKSReload = time.Minute
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+
+	j := &JWT{config: config, now: time.Now}
+	if config.Secret != "" {
+		if len(config.Secret.Reveal()) < minHMACKeySize {
+			return nil, fmt.Errorf("jwt: the secret must be %d bytes at least", minHMACKeySize)
+		}
+		j.static = append(j.static, verificationKey{value: []byte(config.Secret.Reveal())})
+	}
+	ids := make([]string, 0, len(config.Keys))
+	for id := range config.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		key, err := parsePEMKey(id, config.Keys[id])
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %v", err)
+		}
+		j.static = append(j.static, key)
+	}
+
+	if config.JWKSFile != "" {
+		if err := j.loadJWKS(); err != nil {
+			return nil, err
+		}
+	}
+	if len(j.static) == 0 && config.JWKSFile == "" {
+		return nil, errors.New("jwt: no key, set secret, keys or jwks-file")
+	}
+	return j, nil
+}
+
+func (j *JWT) loadJWKS() error {
+	info, err := os.Stat(j.config.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("jwt: %v", err)
+	}
+	data, err := os.ReadFile(j.config.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("jwt: %v", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("jwt: %s: %v", j.config.JWKSFile, err)
+	}
+
+	j.mu.Lock()
+	j.jwks = keys
+	j.jwksTime = info.ModTime()
+	j.checked = j.now()
+	j.mu.Unlock()
+	return nil
+}
+
+// keys returns the key set, reading the JWKS file again when it changed
+// since the last check. A file that cannot be read keeps the last keys.
+// refresh checks the file now, for tokens signed with an unknown key.
+func (j *JWT) keys(refresh bool) []verificationKey {
+	if j.config.JWKSFile == "" {
+		return j.static
+	}
+
+	j.mu.Lock()
+	due := refresh || j.now().Sub(j.checked) >= j.config.JWKSReload
+	if due {
+		j.checked = j.now()
+	}
+	modified := j.jwksTime
+	j.mu.Unlock()
+
+	if due {
+		if info, err := os.Stat(j.config.JWKSFile); err != nil {
+			log.Printf("Keeping the JWKS keys: %v", err)
+		} else if !info.ModTime().Equal(modified) {
+			if err := j.loadJWKS(); err != nil {
+				log.Printf("Keeping the JWKS keys: %v", err)
+			} else {
+				log.Printf("Reloaded the JWKS keys from %s", j.config.JWKSFile)
+			}
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append(append([]verificationKey{}, j.static...), j.jwks...)
+}
+
+// Verify checks the signature of a compact JWS token and its exp, nbf, iss
+// and aud claims, and returns the claims
+func (j *JWT) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJSONSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %v", err)
+	}
+	allowed := j.config.Algorithms
+	if len(allowed) == 0 {
+		allowed = Algorithms
+	}
+	if !slices.Contains(allowed, header.Alg) {
+		return nil, fmt.Errorf("algorithm %q not accepted", header.Alg)
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %v", err)
+	}
+	if !j.verifySignature(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, errors.New("invalid signature")
+	}
+
+	var claims Claims
+	if err := decodeJSONSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %v", err)
+	}
+	if err := j.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifySignature tries the keys with the kid of the token, or the keys
+// without ID when none matches
+func (j *JWT) verifySignature(alg, kid string, signed, signature []byte) bool {
+	candidates := candidateKeys(j.keys(false), alg, kid)
+	if len(candidates) == 0 {
+		// The key may have been added to the JWKS file since the last check
+		candidates = candidateKeys(j.keys(true), alg, kid)
+	}
+	for _, key := range candidates {
+		if key.verify(alg, signed, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+func candidateKeys(keys []verificationKey, alg, kid string) []verificationKey {
+	var candidates []verificationKey
+	for _, key := range keys {
+		if key.id == kid && key.supports(alg) {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, key := range keys {
+			if key.id == "" && key.supports(alg) {
+				candidates = append(candidates, key)
+			}
+		}
+	}
+	return candidates
+}
+
+func (j *JWT) validate(claims Claims) error {
+	now := j.now()
+	skew := j.config.ClockSkew
+
+	expires, ok, err := claims.Time("exp")
+	if err != nil {
+		return err
+	}
+	if !ok && !j.config.AllowMissingExpiry {
+		return errors.New("token has no exp claim")
+	}
+	if ok && now.After(expires.Add(skew)) {
+		return errors.New("token expired")
+	}
+	notBefore, ok, err := claims.Time("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(skew).Before(notBefore) {
+		return errors.New("token not valid yet")
+	}
+
+	if j.config.Issuer != "" && claims.Issuer() != j.config.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer())
+	}
+	if len(j.config.Audience) > 0 {
+		accepted := false
+		for _, audience := range claims.Audience() {
+			if slices.Contains(j.config.Audience, audience) {
+				accepted = true
+			}
+		}
+		if !accepted {
+			return fmt.Errorf("unexpected audience %v", claims.Audience())
+		}
+	}
+	return nil
+}
+
+// decodeJSONSegment decodes a base64url JSON segment
+func decodeJSONSegment(segment string, target interface{}) error {
+	data, err := decodeSegment(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Middleware authenticates the routes using JWT, selected with MetaAuth or
+// RequireJWT; the others are served unchanged. Register it once:
+//
+//	builder.AddGlobalMiddleware("jwt", verifier.Middleware())
+//
+// Requests without a valid bearer token get a 401 problem with a
+// WWW-Authenticate challenge.
+func (j *JWT) Middleware() server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := server.RouteFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if method, _ := route.GetMeta(MetaAuth); method != "jwt" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, `Bearer`, "missing bearer token")
+				return
+			}
+			claims, err := j.Verify(token)
+			if err != nil {
+				unauthorized(w, fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, err.Error()), err.Error())
+				return
+			}
+			ctx := WithClaims(r.Context(), claims)
+			ctx = WithPrincipal(ctx, principalFromClaims(claims, j.config.RolesClaim))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+		return server.EnforcedHandler{Handler: handler, Enforces: authMethod("jwt")}
+	}
+}
+
+// authMethod tells Build that a middleware enforces the routes of an
+// authentication method
+func authMethod(method string) func(key string, value interface{}) bool {
+	return func(key string, value interface{}) bool {
+		return key == MetaAuth && value == method
+	}
+}
+
+// RequireJWT marks a group of routes as authenticated with JWT
+func RequireJWT(routes ...server.RouteInfo) []server.RouteInfo {
+	for _, route := range routes {
+		route.WithMeta(MetaAuth, "jwt")
+	}
+	return routes
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(w http.ResponseWriter, challenge, detail string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	server.WriteProblem(w, server.NewProblem(http.StatusUnauthorized, detail))
+}
@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testNow    = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+)
+
+// signToken builds a compact JWS; key is []byte for the HS algorithms,
+// *rsa.PrivateKey for RS256 and nil for an unsigned token
+func signToken(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+	segment := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		newHash := sha256.New
+		if header["alg"] == "HS512" {
+			newHash = sha512.New
+		}
+		mac := hmac.New(newHash, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+var hs256 = map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+
+// validClaims expire in an hour and were issued a minute ago
+// A nil value in extra removes the claim
+func validClaims(extra map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://issuer.example.com",
+		"aud": "orders",
+		"iat": testNow.Add(-time.Minute).Unix(),
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func newTestJWT(t *testing.T, config JWTConfig) *JWT {
+	t.Helper()
+	if config.ClockSkew == 0 {
+		config.ClockSkew = 30 * time.Second
+	}
+	j, err := NewJWT(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.now = func() time.Time { return testNow }
+	return j
+}
+
+func rsaPEM(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := rsaPEM(t, rsaKey)
+
+	hmacOnly := JWTConfig{Secret: "0123456789abcdef0123456789abcdef", Issuer: "https://issuer.example.com", Audience: []string{"orders"}}
+	rsaOnly := JWTConfig{Keys: map[string]string{"rsa-1": publicPEM}}
+
+	tests := []struct {
+		name   string
+		config JWTConfig
+		token  string
+		// Expected error, empty when the token is valid
+		err string
+	}{
+		{
+			name:   "valid HS256",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(nil), testSecret),
+		},
+		{
+			name:   "valid RS256",
+			config: rsaOnly,
+			token:  signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa-1"}, validClaims(nil), rsaKey),
+		},
+		{
+			name:   "expired",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"exp": testNow.Add(-time.Minute).Unix()}), testSecret),
+			err:    "token expired",
+		},
+		{
+			name:   "expired within the clock skew",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"exp": testNow.Add(-10 * time.Second).Unix()}), testSecret),
+		},
+		{
+			name:   "missing exp",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"exp": nil}), testSecret),
+			err:    "token has no exp claim",
+		},
+		{
+			name:   "missing exp allowed",
+			config: JWTConfig{Secret: hmacOnly.Secret, AllowMissingExpiry: true},
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"exp": nil}), testSecret),
+		},
+		{
+			// Overflowed nanoseconds once, landing in the past
+			name:   "nbf after 2262",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"nbf": 1e10}), testSecret),
+			err:    "token not valid yet",
+		},
+		{
+			name:   "nbf out of range",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"nbf": 1e19}), testSecret),
+			err:    "claim nbf is out of range",
+		},
+		{
+			name:   "fractional exp",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"exp": float64(testNow.Unix()) + 0.5}), testSecret),
+		},
+		{
+			name:   "HS512 with a secret shorter than its hash",
+			config: hmacOnly,
+			token:  signToken(t, map[string]interface{}{"alg": "HS512"}, validClaims(nil), testSecret),
+			err:    "invalid signature",
+		},
+		{
+			name:   "not valid yet",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"nbf": testNow.Add(time.Minute).Unix()}), testSecret),
+			err:    "token not valid yet",
+		},
+		{
+			name:   "wrong audience",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"aud": []string{"billing", "shipping"}}), testSecret),
+			err:    "unexpected audience [billing shipping]",
+		},
+		{
+			name:   "one accepted audience among several",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"aud": []string{"billing", "orders"}}), testSecret),
+		},
+		{
+			name:   "wrong issuer",
+			config: hmacOnly,
+			token:  signToken(t, hs256, validClaims(map[string]interface{}{"iss": "https://evil.example.com"}), testSecret),
+			err:    `unexpected issuer "https://evil.example.com"`,
+		},
+		{
+			name:   "alg none",
+			config: hmacOnly,
+			token:  signToken(t, map[string]interface{}{"alg": "none"}, validClaims(nil), nil),
+			err:    `algorithm "none" not accepted`,
+		},
+		{
+			name:   "alg none without signature segment",
+			config: hmacOnly,
+			token:  strings.TrimSuffix(signToken(t, map[string]interface{}{"alg": "none"}, validClaims(nil), nil), "."),
+			err:    "malformed token",
+		},
+		{
+			name:   "RS256 token against an HMAC key",
+			config: hmacOnly,
+			token:  signToken(t, map[string]interface{}{"alg": "RS256"}, validClaims(nil), rsaKey),
+			err:    "invalid signature",
+		},
+		{
+			// The classic confusion: the public key used as an HMAC secret
+			name:   "HS256 token signed with the RSA public key",
+			config: rsaOnly,
+			token:  signToken(t, hs256, validClaims(nil), []byte(publicPEM)),
+			err:    "invalid signature",
+		},
+		{
+			name:   "algorithm not in the allowed list",
+			config: JWTConfig{Secret: hmacOnly.Secret, Keys: rsaOnly.Keys, Algorithms: []string{"RS256"}},
+			token:  signToken(t, hs256, validClaims(nil), testSecret),
+			err:    `algorithm "HS256" not accepted`,
+		},
+		{
+			name:   "tampered claims",
+			config: hmacOnly,
+			token:  tamper(signToken(t, hs256, validClaims(nil), testSecret), validClaims(map[string]interface{}{"sub": "admin"})),
+			err:    "invalid signature",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := newTestJWT(t, test.config).Verify(test.token)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("expected a valid token, got %v", err)
+			case test.err == "" && claims.Subject() != "alice":
+				t.Errorf("unexpected claims %v", claims)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+// tamper replaces the claims of a token, keeping its signature
+func tamper(token string, claims map[string]interface{}) string {
+	parts := strings.Split(token, ".")
+	data, _ := json.Marshal(claims)
+	parts[1] = base64.RawURLEncoding.EncodeToString(data)
+	return strings.Join(parts, ".")
+}
+
+func writeJWKS(t *testing.T, filename string, modified time.Time, keys map[string]*rsa.PrivateKey) {
+	t.Helper()
+	var jwks []map[string]string
+	for kid, key := range keys {
+		jwks = append(jwks, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{"keys": jwks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filename, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, filename, testNow.Add(-time.Hour), map[string]*rsa.PrivateKey{"old": oldKey})
+
+	j := newTestJWT(t, JWTConfig{JWKSFile: filename, JWKSReload: time.Minute})
+	now := testNow
+	j.now = func() time.Time { return now }
+	token := func(kid string, key *rsa.PrivateKey) string {
+		return signToken(t, map[string]interface{}{"alg": "RS256", "kid": kid}, validClaims(map[string]interface{}{"exp": now.Add(time.Hour).Unix()}), key)
+	}
+
+	oldToken := token("old", oldKey)
+	if _, err := j.Verify(oldToken); err != nil {
+		t.Fatalf("expected the token of the current key to verify: %v", err)
+	}
+
+	// The new key is published next to the old one, and found at once for
+	// its unknown kid
+	writeJWKS(t, filename, testNow.Add(-30*time.Minute), map[string]*rsa.PrivateKey{"old": oldKey, "new": newKey})
+	if _, err := j.Verify(token("new", newKey)); err != nil {
+		t.Fatalf("expected the token of the added key to verify: %v", err)
+	}
+
+	// The old key rotates out: its tokens fail once the file is checked again
+	writeJWKS(t, filename, testNow.Add(-10*time.Minute), map[string]*rsa.PrivateKey{"new": newKey})
+	if _, err := j.Verify(oldToken); err != nil {
+		t.Fatalf("expected the removed key to be kept until the next check, got %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := j.Verify(token("old", oldKey)); err == nil || err.Error() != "invalid signature" {
+		t.Errorf("expected the removed key to be rejected, got %v", err)
+	}
+	if _, err := j.Verify(token("new", newKey)); err != nil {
+		t.Errorf("expected the new key to verify: %v", err)
+	}
+
+	// A broken file keeps the last keys
+	if err := os.WriteFile(filename, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := j.Verify(token("new", newKey)); err != nil {
+		t.Errorf("expected the last keys to be kept, got %v", err)
+	}
+}
+
+func TestNewJWTErrors(t *testing.T) {
+	tests := []struct {
+		config JWTConfig
+		err    string
+	}{
+		{JWTConfig{}, "jwt: no key, set secret, keys or jwks-file"},
+		{JWTConfig{Secret: "s", Algorithms: []string{"none"}}, `jwt: unsupported algorithm "none"`},
+		{JWTConfig{Secret: "too short"}, "jwt: the secret must be 32 bytes at least"},
+		{JWTConfig{Keys: map[string]string{"k": "not a key"}}, "jwt: key k: no PEM block"},
+		{JWTConfig{JWKSFile: "missing.json"}, "jwt: stat missing.json"},
+	}
+	for _, test := range tests {
+		t.Run(test.err, func(t *testing.T) {
+			_, err := NewJWT(test.config)
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestSymmetricJWKS(t *testing.T) {
+	key := base64.RawURLEncoding.EncodeToString(testSecret)
+	tests := []struct {
+		name string
+		jwk  string
+		err  string
+	}{
+		{"valid", `{"kty":"oct","kid":"hs","k":"` + key + `"}`, ""},
+		{"valid for its algorithm", `{"kty":"oct","kid":"hs","alg":"HS256","k":"` + key + `"}`, ""},
+		{"empty", `{"kty":"oct","kid":"hs","k":""}`, "key hs: symmetric key of 0 bytes, expected 32 at least"},
+		{"missing", `{"kty":"oct","kid":"hs"}`, "key hs: symmetric key of 0 bytes, expected 32 at least"},
+		{"shorter than the hash", `{"kty":"oct","kid":"hs","alg":"HS512","k":"` + key + `"}`, "key hs: symmetric key of 32 bytes, expected 64 at least"},
+		{"asymmetric algorithm", `{"kty":"oct","kid":"hs","alg":"RS256","k":"` + key + `"}`, "key hs: algorithm RS256 does not use a symmetric key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := parseJWKS([]byte(`{"keys":[` + test.jwk + `]}`))
+			switch {
+			case test.err == "" && (err != nil || len(keys) != 1):
+				t.Errorf("expected one key, got %v and %v", keys, err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"goserve/configuration/utils"
+	"goserve/server"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KeyInfo describes a key without its hash. Key holds the full key in the
+// responses of create and rotate only.
+type KeyInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key,omitempty"`
+	Status    string    `json:"status"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	RateLimit int       `json:"rateLimit,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	RotatedAt time.Time `json:"rotatedAt,omitzero"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	RevokedAt time.Time `json:"revokedAt,omitzero"`
+}
+
+// Info describes the key at now
+func (k *APIKey) Info(now time.Time) KeyInfo {
+	return KeyInfo{
+		ID:        k.ID,
+		Name:      k.Name,
+		Status:    k.Status(now),
+		Scopes:    k.Scopes,
+		RateLimit: k.RateLimit,
+		CreatedAt: k.CreatedAt,
+		RotatedAt: k.RotatedAt,
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+type createKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Validity such as 720h, no expiry when empty
+	ExpiresIn string `json:"expiresIn"`
+	RateLimit int    `json:"rateLimit"`
+}
+
+type keyIDRequest struct {
+	ID string `path:"id"`
+}
+
+type listKeysRequest struct{}
+
+// Routes returns the management API of the keys under prefix, allowed to
+// the principals accepted by authorizer. Routes panics without one rather
+// than mounting the API unprotected; the routes answer 401 without a
+// principal, so they are also given an authentication method, e.g.
+//
+//	admins := auth.AuthorizerFunc(func(r *http.Request, p *auth.Principal) error {
+//		if !slices.Contains(p.Roles, "admin") {
+//			return fmt.Errorf("%w: administrators only", auth.ErrForbidden)
+//		}
+//		return nil
+//	})
+//	builder.AddRoutes(auth.RequireJWT(keys.Routes("/admin/api-keys", admins)...))
+func (k *APIKeys) Routes(prefix string, authorizer Authorizer) []server.RouteInfo {
+	if authorizer == nil {
+		panic("auth: the API key management routes require an authorizer")
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	routes := []server.RouteInfo{
+		server.CreateTyped(server.GET, prefix, k.listHandler),
+		server.CreateTyped(server.POST, prefix, k.createHandler),
+		server.CreateTyped(server.POST, prefix+"/{id}/rotate", k.rotateHandler),
+		server.CreateTyped(server.DELETE, prefix+"/{id}", k.revokeHandler),
+	}
+	for _, route := range routes {
+		route.WithTags("api-keys").WithMiddleware(authorized(authorizer))
+	}
+	return routes
+}
+
+type guardKey struct{}
+
+// guard keeps the request and the authorizer of the management routes for
+// their handlers: route middlewares run before the global ones, so the
+// principal is only known once the handler is reached
+type guard struct {
+	authorizer Authorizer
+	request    *http.Request
+}
+
+func authorized(authorizer Authorizer) server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), guardKey{}, guard{authorizer, r})))
+		})
+	}
+}
+
+// authorize returns the problem of a caller not allowed by the authorizer
+func authorize(ctx context.Context) error {
+	guard, ok := ctx.Value(guardKey{}).(guard)
+	if !ok {
+		return errors.New("API key management route without an authorizer")
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return server.NewProblem(http.StatusUnauthorized, "authentication required")
+	}
+	err := guard.authorizer.Authorize(guard.request, principal)
+	var problem *server.Problem
+	if err != nil && !errors.As(err, &problem) && errors.Is(err, ErrForbidden) {
+		return server.NewProblem(http.StatusForbidden, strings.TrimPrefix(err.Error(), ErrForbidden.Error()+": "))
+	}
+	return err
+}
+
+func (k *APIKeys) listHandler(ctx context.Context, req listKeysRequest) ([]KeyInfo, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	keys, err := k.List()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]KeyInfo, len(keys))
+	for i, key := range keys {
+		infos[i] = key.Info(k.now())
+	}
+	return infos, nil
+}
+
+func (k *APIKeys) createHandler(ctx context.Context, req createKeyRequest) (KeyInfo, error) {
+	if err := authorize(ctx); err != nil {
+		return KeyInfo{}, err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return KeyInfo{}, server.NewProblem(http.StatusBadRequest, "name is required")
+	}
+	options := KeyOptions{Scopes: req.Scopes, RateLimit: req.RateLimit}
+	if req.ExpiresIn != "" {
+		ttl, err := utils.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			return KeyInfo{}, server.NewProblem(http.StatusBadRequest, "expiresIn: "+err.Error())
+		}
+		options.TTL = ttl
+	}
+	full, key, err := k.Create(req.Name, options)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	info := key.Info(k.now())
+	info.Key = full
+	return info, nil
+}
+
+func (k *APIKeys) rotateHandler(ctx context.Context, req keyIDRequest) (KeyInfo, error) {
+	if err := authorize(ctx); err != nil {
+		return KeyInfo{}, err
+	}
+	full, key, err := k.Rotate(req.ID)
+	if err != nil {
+		return KeyInfo{}, keyError(err)
+	}
+	info := key.Info(k.now())
+	info.Key = full
+	return info, nil
+}
+
+func (k *APIKeys) revokeHandler(ctx context.Context, req keyIDRequest) (KeyInfo, error) {
+	if err := authorize(ctx); err != nil {
+		return KeyInfo{}, err
+	}
+	key, err := k.Revoke(req.ID)
+	if err != nil {
+		return KeyInfo{}, keyError(err)
+	}
+	return key.Info(k.now()), nil
+}
+
+func keyError(err error) error {
+	if errors.Is(err, ErrKeyNotFound) {
+		return server.NewProblem(http.StatusNotFound, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// Algorithms lists the supported signature algorithms
+var Algorithms = []string{"HS256", "HS384", "HS512", "RS256", "ES256", "EdDSA"}
+
+// verificationKey is a key of the key set: []byte for HMAC,
+// *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+type verificationKey struct {
+	id    string
+	alg   string
+	value interface{}
+}
+
+// supports tells whether the key can verify a signature made with alg
+// minHMACKeySize is the size of the smallest HMAC key accepted, the hash
+// size of HS256
+const minHMACKeySize = 32
+
+// hmacKeySize returns the minimum key size of an HMAC algorithm, its hash
+// size, or 0 for other algorithms
+func hmacKeySize(alg string) int {
+	switch alg {
+	case "HS256":
+		return 32
+	case "HS384":
+		return 48
+	case "HS512":
+		return 64
+	}
+	return 0
+}
+
+func (k verificationKey) supports(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	switch key := k.value.(type) {
+	case []byte:
+		size := hmacKeySize(alg)
+		return size > 0 && len(key) >= size
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256" && key.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+func (k verificationKey) verify(alg string, signed, signature []byte) bool {
+	switch key := k.value.(type) {
+	case []byte:
+		var newHash func() hash.Hash
+		switch alg {
+		case "HS256":
+			newHash = sha256.New
+		case "HS384":
+			newHash = sha512.New384
+		default:
+			newHash = sha512.New
+		}
+		mac := hmac.New(newHash, key)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// JWS signatures are r and s concatenated, not ASN.1
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signed, signature)
+	}
+	return false
+}
+
+// parsePEMKey reads a PEM public key (PKIX or PKCS #1) or certificate
+func parsePEMKey(id, data string) (verificationKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return verificationKey{}, fmt.Errorf("key %s: no PEM block", id)
+	}
+
+	var value interface{}
+	var err error
+	switch block.Type {
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		if certificate, err = x509.ParseCertificate(block.Bytes); err == nil {
+			value = certificate.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		value, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		value, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("key %s: %v", id, err)
+	}
+	return verificationKey{id: id, value: value}, nil
+}
+
+// jsonWebKey is a key of a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// Symmetric
+	K string `json:"k"`
+}
+
+// parseJWKS reads the signature keys of a JWKS document, skipping the
+// encryption keys
+func parseJWKS(data []byte) ([]verificationKey, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	keys := make([]verificationKey, 0, len(document.Keys))
+	for i, jwk := range document.Keys {
+		if jwk.Use == "enc" {
+			continue
+		}
+		value, err := jwk.publicKey()
+		if err != nil {
+			name := jwk.Kid
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return nil, fmt.Errorf("key %s: %v", name, err)
+		}
+		keys = append(keys, verificationKey{id: jwk.Kid, alg: jwk.Alg, value: value})
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		key, err := decodeSegment(k.K)
+		if err != nil {
+			return nil, err
+		}
+		size := minHMACKeySize
+		if k.Alg != "" {
+			size = hmacKeySize(k.Alg)
+		}
+		if size == 0 {
+			return nil, fmt.Errorf("algorithm %s does not use a symmetric key", k.Alg)
+		}
+		if len(key) < size {
+			return nil, fmt.Errorf("symmetric key of %d bytes, expected %d at least", len(key), size)
+		}
+		return key, nil
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		// Uncompressed point: 0x04 || x || y
+		point := append([]byte{4}, append(leftPad(x, 32), leftPad(y, 32)...)...)
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func leftPad(value []byte, size int) []byte {
+	if len(value) >= size {
+		return value
+	}
+	return append(make([]byte, size-len(value)), value...)
+}
+
+// decodeSegment decodes base64url without padding, as used by JOSE
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned by stores for unknown key IDs
+var ErrKeyNotFound = errors.New("API key not found")
+
+// KeyStore keeps API keys, only their salted hash for the secret part
+type KeyStore interface {
+	Get(id string) (*APIKey, error)
+	Save(key *APIKey) error
+	Delete(id string) error
+	// List returns the keys sorted by creation date
+	List() ([]*APIKey, error)
+}
+
+// MemoryKeyStore keeps the keys for the life of the process
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string]*APIKey)}
+}
+
+func (s *MemoryKeyStore) Get(id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (s *MemoryKeyStore) Save(key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *key
+	s.keys[key.ID] = &copied
+	return nil
+}
+
+func (s *MemoryKeyStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+	delete(s.keys, id)
+	return nil
+}
+
+func (s *MemoryKeyStore) List() ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedKeys(s.keys), nil
+}
+
+func sortedKeys(keys map[string]*APIKey) []*APIKey {
+	list := make([]*APIKey, 0, len(keys))
+	for _, key := range keys {
+		copied := *key
+		list = append(list, &copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// FileKeyStore keeps the keys in a JSON file, readable by the owner only.
+// The file is read again when its modification time or size changes, so
+// keys managed with the CLI are picked up by running servers.
+type FileKeyStore struct {
+	filename string
+
+	mu       sync.Mutex
+	keys     map[string]*APIKey
+	modified time.Time
+	size     int64
+}
+
+// NewFileKeyStore opens filename, created on the first Save when missing
+func NewFileKeyStore(filename string) (*FileKeyStore, error) {
+	s := &FileKeyStore{filename: filename, keys: make(map[string]*APIKey)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// refresh reads the file when it changed since the last read
+func (s *FileKeyStore) refresh() error {
+	info, err := os.Stat(s.filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.keys = make(map[string]*APIKey)
+		s.modified = time.Time{}
+		s.size = 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modified) && info.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.filename)
+	if err != nil {
+		return err
+	}
+	var document struct {
+		Keys []*APIKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("%s: %v", s.filename, err)
+	}
+	keys := make(map[string]*APIKey, len(document.Keys))
+	for _, key := range document.Keys {
+		keys[key.ID] = key
+	}
+	s.keys = keys
+	s.modified, s.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// write replaces the file atomically
+func (s *FileKeyStore) write() error {
+	data, err := json.MarshalIndent(map[string]interface{}{"keys": sortedKeys(s.keys)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(append(data, '\n')); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temporary.Name(), s.filename); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.filename); err == nil {
+		s.modified, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+func (s *FileKeyStore) Get(id string) (*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (s *FileKeyStore) Save(key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	copied := *key
+	s.keys[key.ID] = &copied
+	return s.write()
+}
+
+func (s *FileKeyStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	if _, ok := s.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+	delete(s.keys, id)
+	return s.write()
+}
+
+func (s *FileKeyStore) List() ([]*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return sortedKeys(s.keys), nil
+}
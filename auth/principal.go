@@ -0,0 +1,41 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated caller, whatever the authentication method
+type Principal struct {
+	Subject string
+	// Method that authenticated the caller, e.g. "jwt"
+	Method      string
+	Scopes      []string
+	Roles       []string
+	Permissions []string
+	// Claims of the token, nil for other methods
+	Claims Claims
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the caller authenticated for the request
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// WithPrincipal stores the authenticated caller in the context, for
+// authentication middlewares and tests
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// principalFromClaims describes the caller of a verified token
+func principalFromClaims(claims Claims, rolesClaim string) *Principal {
+	return &Principal{
+		Subject:     claims.Subject(),
+		Method:      "jwt",
+		Scopes:      claims.Scopes(),
+		Roles:       claims.Strings(rolesClaim),
+		Permissions: claims.Strings("permissions"),
+		Claims:      claims,
+	}
+}
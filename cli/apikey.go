@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"goserve/auth"
+	"goserve/configuration/utils"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// apiKeyFlags opens the key file given with --file
+func (a *App) apiKeyFlags(command string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(a.name+" "+command, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	return flags, flags.String("file", "apikeys.json", "JSON file of the keys")
+}
+
+func openAPIKeys(filename string) (*auth.APIKeys, error) {
+	store, err := auth.NewFileKeyStore(filename)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewAPIKeys(store), nil
+}
+
+func (a *App) apiKeyCreate(args []string) error {
+	flags, file := a.apiKeyFlags("apikey create")
+	scopes := flags.String("scopes", "", "comma separated scopes")
+	expires := flags.String("expires", "", "validity, e.g. 720h (default: no expiry)")
+	rateLimit := flags.Int("rate-limit", 0, "requests per minute (default: unlimited)")
+	name, err := parseNamed(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	options := auth.KeyOptions{RateLimit: *rateLimit}
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			options.Scopes = append(options.Scopes, scope)
+		}
+	}
+	if *expires != "" {
+		if options.TTL, err = utils.ParseDuration(*expires); err != nil {
+			return usagef("--expires: %v", err)
+		}
+	}
+
+	keys, err := openAPIKeys(*file)
+	if err != nil {
+		return err
+	}
+	full, key, err := keys.Create(name, options)
+	if err != nil {
+		return err
+	}
+	a.printCreatedKey(full, key)
+	return nil
+}
+
+func (a *App) apiKeyRotate(args []string) error {
+	flags, file := a.apiKeyFlags("apikey rotate")
+	id, err := parseNamed(flags, args, "key ID")
+	if err != nil {
+		return err
+	}
+	keys, err := openAPIKeys(*file)
+	if err != nil {
+		return err
+	}
+	full, key, err := keys.Rotate(id)
+	if err != nil {
+		return err
+	}
+	a.printCreatedKey(full, key)
+	return nil
+}
+
+func (a *App) printCreatedKey(full string, key *auth.APIKey) {
+	fmt.Fprintf(a.stdout, "%s\n\nKey %s (%s). It is not stored and cannot be shown again.\n", full, key.ID, key.Name)
+}
+
+func (a *App) apiKeyRevoke(args []string) error {
+	flags, file := a.apiKeyFlags("apikey revoke")
+	id, err := parseNamed(flags, args, "key ID")
+	if err != nil {
+		return err
+	}
+	keys, err := openAPIKeys(*file)
+	if err != nil {
+		return err
+	}
+	if _, err := keys.Revoke(id); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "revoked %s\n", id)
+	return nil
+}
+
+func (a *App) apiKeyList(args []string) error {
+	flags, file := a.apiKeyFlags("apikey list")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	keys, err := openAPIKeys(*file)
+	if err != nil {
+		return err
+	}
+	list, err := keys.List()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	infos := make([]auth.KeyInfo, len(list))
+	for i, key := range list {
+		infos[i] = key.Info(now)
+	}
+	if *asJSON {
+		return a.printJSON(infos)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tSCOPES\tRATE LIMIT\tEXPIRES")
+	for _, info := range infos {
+		rate, expires := "-", "-"
+		if info.RateLimit > 0 {
+			rate = fmt.Sprintf("%d/min", info.RateLimit)
+		}
+		if !info.ExpiresAt.IsZero() {
+			expires = info.ExpiresAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", info.ID, info.Name, info.Status, dash(strings.Join(info.Scopes, ", ")), rate, expires)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,237 @@
+// Package cli provides the goserve command line around an application, so
+// mains only declare their configuration and routes:
+//
+//	func main() {
+//		cli.New("orders").
+//			WithConfiguration(func(b configuration.ConfigurationBuilder) {
+//				b.Section("app.database", &database)
+//			}).
+//			WithServer(func(s server.ServerBuilder, cfg configuration.Configuration) {
+//				s.WithLogging(true, true).AddRoutes(routes)
+//			}).
+//			Main()
+//	}
+//
+// Subcommands: serve, the default when no command is given, routes, security, config print, config validate,
+// config schema, config reference, config keygen, config encrypt (see
+// package configuration/secrets), openapi, generate client (see package
+// client/generator), apikey create|list|rotate|revoke (keys of
+// auth.FileKeyStore), and the generators new and generate
+// handler|middleware|group (see package scaffold).
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"goserve/configuration"
+	"goserve/server"
+	"io"
+	"os"
+	"strings"
+)
+
+type App struct {
+	name      string
+	version   string
+	configure func(configuration.ConfigurationBuilder)
+	setup     func(server.ServerBuilder, configuration.Configuration)
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(a *App, args []string) error
+}
+
+func New(name string) *App {
+	return &App{
+		name:    name,
+		version: "1.0.0",
+		stdin:   os.Stdin,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+	}
+}
+
+// WithVersion sets the version shown in the OpenAPI document
+func (a *App) WithVersion(version string) *App {
+	a.version = version
+	return a
+}
+
+// WithConfiguration registers sections and sources on the loader before
+// every command loads or validates the configuration
+func (a *App) WithConfiguration(configure func(configuration.ConfigurationBuilder)) *App {
+	a.configure = configure
+	return a
+}
+
+// WithServer adds the routes and middlewares of the application to the
+// server builder
+func (a *App) WithServer(setup func(server.ServerBuilder, configuration.Configuration)) *App {
+	a.setup = setup
+	return a
+}
+
+// SetOutput changes where results and errors are written (default: the
+// standard output and error)
+func (a *App) SetOutput(stdout, stderr io.Writer) *App {
+	a.stdout = stdout
+	a.stderr = stderr
+	return a
+}
+
+// Main runs the command of os.Args and exits with its status
+func (a *App) Main() {
+	os.Exit(a.Run(os.Args[1:]))
+}
+
+// Run executes a command and returns the exit status: 0 on success, 1 when
+// the command fails and 2 for usage errors. Without a command, or with
+// flags only, the server is started.
+func (a *App) Run(args []string) int {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help" || args[0] == "help") {
+		a.usage()
+		return 0
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
+	}
+
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != cmd.name {
+			continue
+		}
+		err := cmd.run(a, args[len(words):])
+		var usage usageError
+		switch {
+		case err == nil || errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.As(err, &usage):
+			fmt.Fprintf(a.stderr, "%s %s: %v\n", a.name, cmd.name, err)
+			return 2
+		default:
+			fmt.Fprintf(a.stderr, "%s %s: %v\n", a.name, cmd.name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(a.stderr, "%s: unknown command %q\n", a.name, strings.Join(args[:min(len(args), 2)], " "))
+	a.usage()
+	return 2
+}
+
+func (a *App) usage() {
+	fmt.Fprintf(a.stderr, "Usage: %s [command] [arguments]\n\nCommands (serve when none is given):\n", a.name)
+	for _, cmd := range commands {
+		fmt.Fprintf(a.stderr, "  %-20s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(a.stderr, "\nRun %s <command> --help for the flags of a command.\n", a.name)
+}
+
+// usageError reports invalid arguments, exiting with status 2
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// Flags shared by the commands loading the configuration: --config and
+// --watch are read here, the others are configuration flags
+type loadOptions struct {
+	file  string
+	watch bool
+	rest  []string
+}
+
+func splitLoadFlags(args []string) (loadOptions, error) {
+	options := loadOptions{watch: true}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") {
+			options.rest = append(options.rest, args[i])
+			continue
+		}
+		switch name {
+		case "config", "c":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return options, usagef("flag --config needs a file")
+				}
+				i++
+				value = args[i]
+			}
+			options.file = value
+		case "watch":
+			options.watch = value == "" || value == "true"
+		case "no-watch":
+			options.watch = false
+		default:
+			options.rest = append(options.rest, args[i])
+		}
+	}
+	return options, nil
+}
+
+// load reads the configuration from --config or the default files, with the
+// remaining arguments as configuration flags
+func (a *App) load(args []string) (configuration.ConfigurationBuilder, configuration.Configuration, loadOptions, error) {
+	options, err := splitLoadFlags(args)
+	if err != nil {
+		return nil, nil, options, err
+	}
+
+	flags := configuration.NewFlags(a.name).SetOutput(a.stderr)
+	if err := flags.Parse(options.rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(a.stderr, "  %-28s %s\n", "-c, --config file", "configuration file (default: config.* in the current directory)")
+			fmt.Fprintf(a.stderr, "  %-28s %s\n", "--no-watch", "do not reload the configuration on changes (serve)")
+			return nil, nil, options, err
+		}
+		return nil, nil, options, usagef("%v", err)
+	}
+	if len(flags.Args()) > 0 {
+		return nil, nil, options, usagef("unexpected arguments %v", flags.Args())
+	}
+
+	builder := a.builder().AddSource(flags.Source())
+	var cfg configuration.Configuration
+	if options.file != "" {
+		// The loader skips missing files, an explicit one must exist
+		if _, err := os.Stat(options.file); err != nil {
+			return nil, nil, options, err
+		}
+		cfg, err = builder.LoadConfigFromFile(options.file)
+	} else {
+		cfg, err = builder.LoadConfig()
+	}
+	return builder, cfg, options, err
+}
+
+func (a *App) builder() configuration.ConfigurationBuilder {
+	builder := configuration.New()
+	if a.configure != nil {
+		a.configure(builder)
+	}
+	return builder
+}
+
+// server creates the server builder with the routes of the application
+func (a *App) server(cfg configuration.Configuration) server.ServerBuilder {
+	builder := server.New().WithConfiguration(cfg)
+	if a.setup != nil {
+		a.setup(builder, cfg)
+	}
+	return builder
+}
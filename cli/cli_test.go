@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"goserve/configuration"
+	"goserve/server"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newTestApp() (*App, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	app := New("orders").
+		WithServer(func(s server.ServerBuilder, _ configuration.Configuration) {
+			s.GET("/orders", func(w http.ResponseWriter, r *http.Request) {})
+		}).
+		SetOutput(&stdout, &stderr)
+	return app, &stdout, &stderr
+}
+
+func TestRunDefaultsToServe(t *testing.T) {
+	app, _, stderr := newTestApp()
+	// A missing --config file stops serve before it listens
+	if status := app.Run([]string{"--config", "missing.yaml"}); status != 1 {
+		t.Errorf("expected status 1, got %d", status)
+	}
+	if !strings.HasPrefix(stderr.String(), "orders serve: ") {
+		t.Errorf("expected flags without a command to run serve, got %q", stderr)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		args   []string
+		status int
+		output string
+	}{
+		{[]string{"help"}, 0, "Commands (serve when none is given):"},
+		{[]string{"--help"}, 0, "config print"},
+		{[]string{"unknown"}, 2, `unknown command "unknown"`},
+		{[]string{"config", "validate"}, 2, "missing configuration files"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			app, _, stderr := newTestApp()
+			if status := app.Run(test.args); status != test.status {
+				t.Errorf("expected status %d, got %d", test.status, status)
+			}
+			if !strings.Contains(stderr.String(), test.output) {
+				t.Errorf("expected %q in %q", test.output, stderr)
+			}
+		})
+	}
+}
+
+func TestQuietOnlyForJSON(t *testing.T) {
+	var logs bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(output) })
+	t.Chdir(t.TempDir())
+
+	app, stdout, _ := newTestApp()
+	if status := app.Run([]string{"routes", "--json"}); status != 0 {
+		t.Fatalf("routes --json failed with status %d", status)
+	}
+	var routes []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &routes); err != nil || len(routes) != 1 {
+		t.Errorf("expected one route as JSON, got %q (%v)", stdout, err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no logs with --json, got %q", logs.String())
+	}
+	if log.Writer() != &logs {
+		t.Error("expected the logger to be restored")
+	}
+
+	app, stdout, _ = newTestApp()
+	if status := app.Run([]string{"routes"}); status != 0 {
+		t.Fatalf("routes failed with status %d", status)
+	}
+	if !strings.Contains(stdout.String(), "/orders") || logs.Len() == 0 {
+		t.Errorf("expected the table and the loading logs, got %q and %q", stdout, logs.String())
+	}
+}
@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"goserve/auth"
+	"goserve/client/generator"
+	"goserve/configuration"
+	"goserve/openapi"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Commands are matched on their words: "config print" runs with the
+// arguments following print
+var commands = []command{
+	{"serve", "start the server (--config file, --no-watch, configuration flags)", (*App).serve},
+	{"routes", "list the routes with their middlewares and tags (--json)", (*App).routes},
+	{"security", "list the authentication and authorization required by each route (--json)", (*App).security},
+	{"config print", "print the effective configuration, redacted, with the source of each value (--json)", (*App).configPrint},
+	{"config validate", "check configuration files against the schema", (*App).configValidate},
+	{"config schema", "print the JSON Schema of the configuration", (*App).configSchema},
+	{"config reference", "print the markdown reference of the settings", (*App).configReference},
+	{"config keygen", "create a key file for encrypted values: config keygen <file>", (*App).configKeygen},
+	{"config encrypt", "print the enc: value of the argument or of the standard input (--key file)", (*App).configEncrypt},
+	{"openapi", "print the OpenAPI document of the routes (--title)", (*App).openapi},
+	{"generate client", "print a client of the typed routes: generate client --lang go|ts [--package name] [--out file]", (*App).generateClient},
+	{"apikey create", "create an API key: apikey create <name> [--scopes a,b] [--expires 720h] [--rate-limit n]", (*App).apiKeyCreate},
+	{"apikey list", "list the API keys without their secret (--json)", (*App).apiKeyList},
+	{"apikey rotate", "replace the secret of an API key: apikey rotate <id>", (*App).apiKeyRotate},
+	{"apikey revoke", "disable an API key: apikey revoke <id>", (*App).apiKeyRevoke},
+	{"new", "create a project: new <name> [--module path] [--goserve dir]", (*App).newProject},
+	{"generate handler", "add a typed handler: generate handler <name> --group <package>", (*App).generateHandler},
+	{"generate middleware", "add a middleware: generate middleware <name>", (*App).generateMiddleware},
+	{"generate group", "add a route group: generate group <name>", (*App).generateGroup},
+}
+
+// quiet silences the loading logs while a command prints JSON, so its
+// output can be piped even when the logs share the terminal. The logger is
+// global: only the JSON outputs use it, the other commands keep their logs.
+func quiet(enabled bool) func() {
+	if !enabled {
+		return func() {}
+	}
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	return func() { log.SetOutput(output) }
+}
+
+func (a *App) serve(args []string) error {
+	loader, cfg, options, err := a.load(args)
+	if err != nil {
+		return err
+	}
+
+	srv := a.server(cfg).Build()
+	if options.watch {
+		loader.Watch(func(_, config configuration.Configuration) {
+			srv.Reconfigure(config)
+		})
+		stop := loader.StartWatching(0)
+		defer stop()
+	}
+	return srv.Start()
+}
+
+func (a *App) routes(args []string) error {
+	asJSON, args := boolFlag(args, "json")
+	defer quiet(asJSON)()
+	_, cfg, _, err := a.load(args)
+	if err != nil {
+		return err
+	}
+
+	builder := a.server(cfg)
+	var global []string
+	for _, middleware := range builder.GetMiddlewares() {
+		global = append(global, middleware.Name)
+	}
+
+	type routeRow struct {
+		Method      string   `json:"method"`
+		Path        string   `json:"path"`
+		Name        string   `json:"name,omitempty"`
+		Middlewares []string `json:"middlewares"`
+		Tags        []string `json:"tags"`
+	}
+	var rows []routeRow
+	for _, route := range builder.GetRoutes() {
+		middlewares := append([]string{}, global...)
+		if count := len(route.GetHandler().GetMiddlewares()); count > 0 {
+			middlewares = append(middlewares, fmt.Sprintf("%d route middleware(s)", count))
+		}
+		name := ""
+		if id, ok := route.GetMeta("operationId"); ok {
+			name, _ = id.(string)
+		}
+		rows = append(rows, routeRow{
+			Method:      string(route.GetMethod()),
+			Path:        route.GetPath(),
+			Name:        name,
+			Middlewares: middlewares,
+			Tags:        append([]string{}, route.GetTags()...),
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Path != rows[j].Path {
+			return rows[i].Path < rows[j].Path
+		}
+		return rows[i].Method < rows[j].Method
+	})
+
+	if asJSON {
+		return a.printJSON(rows)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tNAME\tMIDDLEWARES\tTAGS")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.Method, row.Path, dash(row.Name), dash(strings.Join(row.Middlewares, ", ")), dash(strings.Join(row.Tags, ", ")))
+	}
+	return w.Flush()
+}
+
+func (a *App) security(args []string) error {
+	asJSON, args := boolFlag(args, "json")
+	defer quiet(asJSON)()
+	_, cfg, _, err := a.load(args)
+	if err != nil {
+		return err
+	}
+
+	report := auth.Report(a.server(cfg).GetRoutes(), nil)
+	if asJSON {
+		return a.printJSON(report)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tAUTH\tSCOPES\tROLES\tPERMISSIONS\tPOLICIES\tWARNINGS")
+	for _, line := range report {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", line.Method, line.Path, dash(line.Authentication),
+			dash(strings.Join(line.Scopes, ", ")), dash(strings.Join(line.Roles, " | ")),
+			dash(strings.Join(line.Permissions, ", ")), dash(strings.Join(line.Policies, ", ")),
+			strings.Join(line.Warnings, "; "))
+	}
+	return w.Flush()
+}
+
+func (a *App) configSchema(args []string) error {
+	return a.printJSON(a.builder().Schema())
+}
+
+func (a *App) configReference(args []string) error {
+	_, err := io.WriteString(a.stdout, a.builder().Reference())
+	return err
+}
+
+func (a *App) configPrint(args []string) error {
+	asJSON, args := boolFlag(args, "json")
+	defer quiet(asJSON)()
+	_, cfg, _, err := a.load(args)
+	if err != nil {
+		return err
+	}
+
+	type setting struct {
+		Key    string      `json:"key"`
+		Value  interface{} `json:"value"`
+		Origin string      `json:"origin,omitempty"`
+	}
+	var settings []setting
+	flattenDump(cfg.Dump(), "", func(key string, value interface{}) {
+		origin, _ := cfg.Origin(key)
+		settings = append(settings, setting{Key: key, Value: value, Origin: origin})
+	})
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+
+	if asJSON {
+		return a.printJSON(settings)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	for _, s := range settings {
+		value, _ := json.Marshal(s.Value)
+		fmt.Fprintf(w, "%s\t= %s\t%s\n", s.Key, value, dash(s.Origin))
+	}
+	return w.Flush()
+}
+
+func flattenDump(values map[string]interface{}, path string, visit func(key string, value interface{})) {
+	for key, value := range values {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			flattenDump(child, keyPath, visit)
+			continue
+		}
+		visit(keyPath, value)
+	}
+}
+
+func (a *App) configValidate(args []string) error {
+	if len(args) == 0 {
+		return usagef("missing configuration files")
+	}
+	loader := a.builder()
+	failed := 0
+	for _, filename := range args {
+		if err := loader.Validate(filename); err != nil {
+			fmt.Fprintln(a.stdout, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(a.stdout, "%s: ok\n", filename)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files invalid", failed, len(args))
+	}
+	return nil
+}
+
+func (a *App) openapi(args []string) error {
+	defer quiet(true)()
+	title, args := valueFlag(args, "title")
+	if title == "" {
+		title = a.name
+	}
+	_, cfg, _, err := a.load(args)
+	if err != nil {
+		return err
+	}
+	doc := openapi.FromRoutes(openapi.Info{Title: title, Version: a.version}, a.server(cfg).GetRoutes())
+	return a.printJSON(doc)
+}
+
+func (a *App) generateClient(args []string) error {
+	defer quiet(true)()
+	lang, args := valueFlag(args, "lang")
+	pkg, args := valueFlag(args, "package")
+	out, args := valueFlag(args, "out")
+	if lang != "go" && lang != "ts" {
+		return usagef("--lang must be go or ts")
+	}
+	_, cfg, _, err := a.load(args)
+	if err != nil {
+		return err
+	}
+
+	routes := a.server(cfg).GetRoutes()
+	var code []byte
+	if lang == "go" {
+		code, err = generator.GenerateGo(routes, generator.Options{Package: pkg})
+	} else {
+		code, err = generator.GenerateTypeScript(routes)
+	}
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		return os.WriteFile(out, code, 0o644)
+	}
+	_, err = a.stdout.Write(code)
+	return err
+}
+
+func (a *App) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// boolFlag removes --name from args and tells whether it was there
+func boolFlag(args []string, name string) (bool, []string) {
+	found := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--"+name || arg == "-"+name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return found, rest
+}
+
+// valueFlag removes --name value or --name=value from args and returns the
+// value
+func valueFlag(args []string, name string) (string, []string) {
+	value := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		flagName, flagValue, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || flagName != name {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			flagValue = args[i]
+		}
+		value = flagValue
+	}
+	return value, rest
+}
+
+func dash(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return text
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"goserve/scaffold"
+	"path/filepath"
+	"strings"
+)
+
+// generateFlags are the flags of new and generate
+type generateFlags struct {
+	flags     *flag.FlagSet
+	dir       *string
+	templates *string
+}
+
+func (a *App) generateFlags(command, dirUsage string) generateFlags {
+	flags := flag.NewFlagSet(a.name+" "+command, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	return generateFlags{
+		flags:     flags,
+		dir:       flags.String("dir", "", dirUsage),
+		templates: flags.String("templates", "", "directory of templates overriding the built-in ones (env "+scaffold.TemplatesEnv+")"),
+	}
+}
+
+// parse reads the flags and the name, given before or after the flags
+func (g generateFlags) parse(args []string) (string, error) {
+	return parseNamed(g.flags, args, "name")
+}
+
+// parseNamed reads flags and a single argument, given before, after or
+// between them
+func parseNamed(flags *flag.FlagSet, args []string, label string) (string, error) {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	rest := flags.Args()
+	if name == "" && len(rest) > 0 {
+		// Parsing stops at the name, the flags following it are read too
+		name = rest[0]
+		if err := flags.Parse(rest[1:]); err != nil {
+			return "", err
+		}
+		rest = flags.Args()
+	}
+	switch {
+	case name == "":
+		return "", usagef("missing %s", label)
+	case len(rest) > 0:
+		return "", usagef("unexpected arguments %v", rest)
+	}
+	return name, nil
+}
+
+func (g generateFlags) generator() *scaffold.Generator {
+	generator := scaffold.New()
+	if *g.templates != "" {
+		generator.WithTemplates(*g.templates)
+	}
+	return generator
+}
+
+func (a *App) newProject(args []string) error {
+	options := a.generateFlags("new", "directory of the project (default: the name)")
+	module := options.flags.String("module", "", "module path (default: the name)")
+	goserve := options.flags.String("goserve", "", "local goserve directory, added as a replace directive to go.mod")
+	name, err := options.parse(args)
+	if err != nil {
+		return err
+	}
+
+	data := scaffold.Data{Name: filepath.Base(name), Module: *module, GoServe: *goserve}
+	if data.Module == "" {
+		data.Module = data.Name
+	}
+	if data.GoServe != "" {
+		if data.GoServe, err = filepath.Abs(data.GoServe); err != nil {
+			return err
+		}
+	}
+	dir := *options.dir
+	if dir == "" {
+		dir = name
+	}
+
+	if err := a.generate(options.generator(), "project", dir, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "\nNext: cd %s && go mod tidy && go test ./... && go run . serve\n", dir)
+	return nil
+}
+
+func (a *App) generateHandler(args []string) error {
+	options := a.generateFlags("generate handler", "project directory (default: the current directory)")
+	group := options.flags.String("group", "", "package of the handler (required)")
+	name, err := options.parse(args)
+	if err != nil {
+		return err
+	}
+	if *group == "" {
+		return usagef("missing --group")
+	}
+
+	data := scaffold.Data{Name: name, Group: *group}
+	if err := a.generateInProject(options, "handler", data); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "\nNext: add %sRoute() to Routes in internal/%s\n", data.GoName(), data.Group)
+	return nil
+}
+
+func (a *App) generateMiddleware(args []string) error {
+	options := a.generateFlags("generate middleware", "project directory (default: the current directory)")
+	name, err := options.parse(args)
+	if err != nil {
+		return err
+	}
+
+	data := scaffold.Data{Name: name}
+	if err := a.generateInProject(options, "middleware", data); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "\nNext: builder.AddGlobalMiddleware(%q, middleware.%s)\n", data.Path(), data.GoName())
+	return nil
+}
+
+func (a *App) generateGroup(args []string) error {
+	options := a.generateFlags("generate group", "project directory (default: the current directory)")
+	name, err := options.parse(args)
+	if err != nil {
+		return err
+	}
+
+	data := scaffold.Data{Name: name}
+	if err := a.generateInProject(options, "group", data); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "\nNext: builder.AddRoutes(%s.Routes())\n", data.Package())
+	return nil
+}
+
+// generateInProject generates into an existing project, whose module path
+// is read from go.mod
+func (a *App) generateInProject(options generateFlags, kind string, data scaffold.Data) error {
+	dir := *options.dir
+	if dir == "" {
+		dir = "."
+	}
+	module, err := scaffold.ModulePath(dir)
+	if err != nil {
+		return err
+	}
+	data.Module = module
+	return a.generate(options.generator(), kind, dir, data)
+}
+
+func (a *App) generate(generator *scaffold.Generator, kind, dir string, data scaffold.Data) error {
+	files, err := generator.Generate(kind, dir, data)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Fprintf(a.stdout, "created %s\n", file)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"goserve/configuration"
+	"goserve/server"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAndGenerate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+
+	app, stdout, stderr := newTestApp()
+	if status := app.Run([]string{"new", "shop", "--dir", dir, "--module", "example.com/shop"}); status != 0 {
+		t.Fatalf("new failed with status %d: %s", status, stderr)
+	}
+	if !strings.Contains(stdout.String(), "created "+filepath.Join(dir, "go.mod")) || !strings.Contains(stdout.String(), "Next: cd "+dir) {
+		t.Errorf("unexpected output %q", stdout)
+	}
+	if module, _ := os.ReadFile(filepath.Join(dir, "go.mod")); !strings.HasPrefix(string(module), "module example.com/shop\n") {
+		t.Errorf("unexpected go.mod %q", module)
+	}
+
+	tests := []struct {
+		args []string
+		file string
+		pkg  string
+		next string
+	}{
+		{[]string{"generate", "group", "billing"}, "internal/billing/routes.go", "billing", "builder.AddRoutes(billing.Routes())"},
+		{[]string{"generate", "handler", "--group", "billing", "invoice-status"}, "internal/billing/invoice_status.go", "billing", "add InvoiceStatusRoute() to Routes in internal/billing"},
+		{[]string{"generate", "middleware", "request-id"}, "internal/middleware/request_id.go", "middleware", `builder.AddGlobalMiddleware("request-id", middleware.RequestID)`},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.args[:2], " "), func(t *testing.T) {
+			app, stdout, stderr := newTestApp()
+			args := append(test.args, "--dir", dir)
+			if status := app.Run(args); status != 0 {
+				t.Fatalf("failed with status %d: %s", status, stderr)
+			}
+			content, err := os.ReadFile(filepath.Join(dir, test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains("\n"+string(content), "\npackage "+test.pkg+"\n") {
+				t.Errorf("unexpected %s: %s", test.file, content)
+			}
+			if !strings.Contains(stdout.String(), test.next) {
+				t.Errorf("expected %q in %q", test.next, stdout)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "go.mod"), []byte("module example.com/shop\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		output string
+	}{
+		{"missing name", []string{"new"}, 2, "missing name"},
+		{"missing group", []string{"generate", "handler", "status", "--dir", project}, 2, "missing --group"},
+		{"extra arguments", []string{"generate", "middleware", "--dir", project, "a", "b"}, 2, "unexpected arguments [b]"},
+		{"outside of a module", []string{"generate", "group", "billing", "--dir", t.TempDir()}, 1, "go.mod not found"},
+		{"existing files", []string{"new", "shop", "--dir", project}, 1, "go.mod already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, _, stderr := newTestApp()
+			if status := app.Run(test.args); status != test.status {
+				t.Errorf("expected status %d, got %d", test.status, status)
+			}
+			if !strings.Contains(stderr.String(), test.output) {
+				t.Errorf("expected %q in %q", test.output, stderr)
+			}
+		})
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	app := New("orders").
+		WithServer(func(s server.ServerBuilder, _ configuration.Configuration) {
+			s.AddRoutes([]server.RouteInfo{
+				server.CreateTyped(server.GET, "/orders/{id}", func(ctx context.Context, req struct {
+					ID int `path:"id"`
+				}) (map[string]int, error) {
+					return nil, nil
+				}).WithMeta("operationId", "getOrder"),
+			})
+		}).
+		SetOutput(&stdout, &stderr)
+
+	if status := app.Run([]string{"generate", "client", "--lang", "go", "--package", "orders"}); status != 0 {
+		t.Fatalf("failed with status %d: %s", status, stderr.String())
+	}
+	for _, expected := range []string{"package orders\n", "func (c *Client) GetOrder(ctx context.Context, req struct {"} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("expected %q in %q", expected, stdout.String())
+		}
+	}
+
+	out := filepath.Join(t.TempDir(), "client.ts")
+	if status := app.Run([]string{"generate", "client", "--lang=ts", "--out", out}); status != 0 {
+		t.Fatalf("failed with status %d: %s", status, stderr.String())
+	}
+	if code, _ := os.ReadFile(out); !strings.Contains(string(code), "getOrder(req: {\n    id: number;\n  }, signal?: AbortSignal)") {
+		t.Errorf("unexpected TypeScript client %s", code)
+	}
+
+	stderr.Reset()
+	if status := app.Run([]string{"generate", "client", "--lang", "java"}); status != 2 || !strings.Contains(stderr.String(), "--lang must be go or ts") {
+		t.Errorf("expected a usage error, got %d %q", status, stderr.String())
+	}
+}
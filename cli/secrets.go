@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"goserve/configuration"
+	"goserve/configuration/secrets"
+	"os"
+	"strings"
+)
+
+func (a *App) configKeygen(args []string) error {
+	flags := flag.NewFlagSet(a.name+" config keygen", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	filename, err := parseNamed(flags, args, "key file")
+	if err != nil {
+		return err
+	}
+
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := secrets.WriteKeyFile(filename, key); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "created %s\n\nKeep it out of the repository and load it with %s=%s\n", filename, configuration.SecretKeyFileEnv, filename)
+	return nil
+}
+
+func (a *App) configEncrypt(args []string) error {
+	flags := flag.NewFlagSet(a.name+" config encrypt", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	keyFile := flags.String("key", os.Getenv(configuration.SecretKeyFileEnv), "key file (env "+configuration.SecretKeyFileEnv+")")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return usagef("missing --key")
+	}
+
+	var plaintext string
+	switch rest := flags.Args(); len(rest) {
+	case 0:
+		// Read from the standard input, keeping the value out of the shell
+		// history
+		line, err := bufio.NewReader(a.stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("reading the value: %v", err)
+		}
+		plaintext = strings.TrimRight(line, "\r\n")
+	case 1:
+		plaintext = rest[0]
+	default:
+		return usagef("unexpected arguments %v", rest[1:])
+	}
+
+	key, err := secrets.ReadKeyFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	value, err := secrets.Encrypt(key, plaintext)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, value)
+	return nil
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"goserve/configuration"
+	"goserve/configuration/secrets"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigKeygenAndEncrypt(t *testing.T) {
+	t.Setenv(configuration.SecretKeyFileEnv, "")
+	keyFile := filepath.Join(t.TempDir(), "secret.key")
+
+	app, stdout, stderr := newTestApp()
+	if status := app.Run([]string{"config", "keygen", keyFile}); status != 0 {
+		t.Fatalf("keygen failed with status %d: %s", status, stderr)
+	}
+	if !strings.Contains(stdout.String(), "created "+keyFile) {
+		t.Errorf("unexpected output %q", stdout)
+	}
+	key, err := secrets.ReadKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, _, stderr = newTestApp()
+	if status := app.Run([]string{"config", "keygen", keyFile}); status != 1 || !strings.Contains(stderr.String(), "exists") {
+		t.Errorf("expected keygen to keep the existing key, got %d %q", status, stderr)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		env   string
+	}{
+		{"argument", []string{"--key", keyFile, "s3cret"}, "", ""},
+		{"standard input", []string{"--key", keyFile}, "s3cret\n", ""},
+		{"environment", []string{"s3cret"}, "", keyFile},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(configuration.SecretKeyFileEnv, test.env)
+			app, stdout, stderr := newTestApp()
+			app.stdin = strings.NewReader(test.stdin)
+			if status := app.Run(append([]string{"config", "encrypt"}, test.args...)); status != 0 {
+				t.Fatalf("encrypt failed with status %d: %s", status, stderr)
+			}
+			plaintext, err := secrets.Decrypt(key, strings.TrimSpace(stdout.String()))
+			if err != nil || plaintext != "s3cret" {
+				t.Errorf("expected the encrypted value, got %q, %v", plaintext, err)
+			}
+		})
+	}
+
+	raw := filepath.Join(t.TempDir(), "raw.key")
+	os.WriteFile(raw, []byte("0123456789abcdef0123456789abcdef"), 0o600)
+	errors := []struct {
+		args   []string
+		status int
+		output string
+	}{
+		{[]string{"config", "encrypt", "s3cret"}, 2, "missing --key"},
+		{[]string{"config", "encrypt", "--key", keyFile, "a", "b"}, 2, "unexpected arguments [b]"},
+		{[]string{"config", "encrypt", "--key", raw, "s3cret"}, 1, "expected base64: followed by"},
+		{[]string{"config", "keygen"}, 2, "missing key file"},
+	}
+	for _, test := range errors {
+		app, _, stderr := newTestApp()
+		if status := app.Run(test.args); status != test.status || !strings.Contains(stderr.String(), test.output) {
+			t.Errorf("%v: expected %d and %q, got %d %q", test.args, test.status, test.output, status, stderr)
+		}
+	}
+}
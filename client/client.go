@@ -0,0 +1,231 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"goserve/server"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Client is the runtime used by generated clients
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+}
+
+type Option func(*Client)
+
+// Error is returned for every non-2xx response. Problem is set when the
+// server answered with an application/problem+json document.
+type Error struct {
+	StatusCode int
+	Problem    *server.Problem
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Problem.Error())
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader adds a header sent with every request
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// Call sends a request built from req and decodes the JSON response into out.
+// Fields of req tagged `path`, `query` or `header` are encoded in the URL and
+// headers, the remaining ones form the JSON body of POST, PUT and PATCH
+// requests. out may be nil when no response body is expected.
+func (c *Client) Call(ctx context.Context, method, pattern string, req interface{}, out interface{}) error {
+	path := pattern
+	query := url.Values{}
+	header := c.header.Clone()
+
+	if req != nil {
+		if err := encodeParams(reflect.ValueOf(req), &path, query, header); err != nil {
+			return err
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if req != nil && hasBody(method) {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("encode request: %v", err)
+		}
+		body = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp, data)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %v", err)
+	}
+	return nil
+}
+
+func decodeError(resp *http.Response, data []byte) error {
+	apiErr := &Error{StatusCode: resp.StatusCode, Body: data}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == server.ProblemContentType {
+		var problem server.Problem
+		if json.Unmarshal(data, &problem) == nil {
+			apiErr.Problem = &problem
+		}
+	}
+	return apiErr
+}
+
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+func encodeParams(value reflect.Value, path *string, query url.Values, header http.Header) error {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		in, name := server.ParamTag(fieldType)
+		if in == "" {
+			if fieldType.Anonymous {
+				if err := encodeParams(value.Field(i), path, query, header); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		values := formatValues(value.Field(i))
+		switch in {
+		case "path":
+			if len(values) == 0 {
+				return fmt.Errorf("missing path parameter %s", name)
+			}
+			*path = strings.ReplaceAll(*path, "{"+name+"...}", escapeSegments(values[0]))
+			*path = strings.ReplaceAll(*path, "{"+name+"}", url.PathEscape(values[0]))
+		case "query":
+			for _, v := range values {
+				query.Add(name, v)
+			}
+		case "header":
+			for _, v := range values {
+				header.Add(name, v)
+			}
+		}
+	}
+	return nil
+}
+
+func escapeSegments(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// formatValues renders a field as strings; nil pointers and empty slices
+// produce no value
+func formatValues(field reflect.Value) []string {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		return formatValues(field.Elem())
+	}
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]string, 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			values = append(values, formatValue(field.Index(i)))
+		}
+		return values
+	}
+	return []string{formatValue(field)}
+}
+
+func formatValue(field reflect.Value) string {
+	switch v := field.Interface().(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(field.Interface())
+}
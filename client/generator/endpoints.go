@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"fmt"
+	"goserve/internal/naming"
+	"goserve/server"
+	"reflect"
+	"strings"
+)
+
+type Options struct {
+	// Package name of the generated Go client
+	Package string
+}
+
+// Endpoint is a typed route of the server route table
+type Endpoint struct {
+	Name     string
+	Method   string
+	Path     string
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// Endpoints extracts the routes created with server.CreateTyped. The method
+// name comes from the "operationId" meta or is derived from method and path.
+func Endpoints(routes []server.RouteInfo) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	names := make(map[string]string)
+
+	for _, route := range routes {
+		types, ok := server.GetRouteTypes(route)
+		if !ok {
+			continue
+		}
+
+		name := naming.GoName(strings.ToLower(string(route.GetMethod())) + " " + route.GetPath())
+		if operationID, ok := route.GetMeta("operationId"); ok {
+			if id, ok := operationID.(string); ok && id != "" {
+				name = naming.GoName(id)
+			}
+		}
+
+		signature := fmt.Sprintf("%s %s", route.GetMethod(), route.GetPath())
+		if previous, ok := names[name]; ok {
+			return nil, fmt.Errorf("routes %s and %s both map to %s", previous, signature, name)
+		}
+		names[name] = signature
+
+		endpoints = append(endpoints, Endpoint{
+			Name:     name,
+			Method:   string(route.GetMethod()),
+			Path:     route.GetPath(),
+			Request:  types.Request,
+			Response: types.Response,
+		})
+	}
+	return endpoints, nil
+}
+
+// hasRequest is false for empty request structs, which need no argument
+func (e Endpoint) hasRequest() bool {
+	return !(e.Request.Kind() == reflect.Struct && e.Request.NumField() == 0)
+}
+
+func (e Endpoint) noContent() bool {
+	return e.Response == reflect.TypeOf(server.NoContent{})
+}
+
+// field describes an exported struct field as seen on the wire
+type field struct {
+	goField   reflect.StructField
+	in        string
+	name      string
+	optional  bool
+	omitted   bool
+	flattened bool
+}
+
+func fieldsOf(typ reflect.Type) []field {
+	var fields []field
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		f := field{goField: structField, name: structField.Name}
+		f.in, f.name = server.ParamTag(structField)
+		if f.in == "" {
+			f.name = structField.Name
+			tag := structField.Tag.Get("json")
+			if tag == "-" {
+				f.omitted = true
+			} else {
+				parts := strings.Split(tag, ",")
+				if parts[0] != "" {
+					f.name = parts[0]
+				} else if structField.Anonymous && structField.Type.Kind() == reflect.Struct {
+					f.flattened = true
+				}
+				for _, option := range parts[1:] {
+					if option == "omitempty" || option == "omitzero" {
+						f.optional = true
+					}
+				}
+			}
+		}
+		if structField.Type.Kind() == reflect.Ptr {
+			f.optional = true
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
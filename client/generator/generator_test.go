@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"goserve/server"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+type Status string
+
+type Line struct {
+	SKU      string  `json:"sku"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price,omitempty"`
+}
+
+type Order struct {
+	ID       int               `json:"id"`
+	Status   Status            `json:"status"`
+	Lines    []Line            `json:"lines"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Note     *string           `json:"note"`
+	Created  time.Time         `json:"created"`
+	Metadata json.RawMessage   `json:"metadata,omitempty"`
+	Shipping struct {
+		City    string `json:"city"`
+		Express bool   `json:"express,omitempty"`
+	} `json:"shipping"`
+	internal string
+}
+
+type Paging struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+type ListOrders struct {
+	Paging
+	Status []Status `query:"status"`
+	Tenant string   `header:"X-Tenant"`
+}
+
+type CreateOrder struct {
+	Tenant string `header:"X-Tenant"`
+	Lines  []Line `json:"lines"`
+	Note   string `json:"note,omitempty"`
+}
+
+type OrderID struct {
+	ID int `path:"id"`
+}
+
+type DownloadFile struct {
+	Path string `path:"path"`
+}
+
+func sampleRoutes() []server.RouteInfo {
+	return []server.RouteInfo{
+		server.CreateTyped(server.GET, "/orders", func(ctx context.Context, req ListOrders) ([]Order, error) {
+			return nil, nil
+		}),
+		server.CreateTyped(server.POST, "/orders", func(ctx context.Context, req CreateOrder) (Order, error) {
+			return Order{}, nil
+		}).WithMeta("operationId", "placeOrder"),
+		server.CreateTyped(server.GET, "/orders/{id}", func(ctx context.Context, req OrderID) (*Order, error) {
+			return nil, nil
+		}),
+		server.CreateTyped(server.DELETE, "/orders/{id}", func(ctx context.Context, req OrderID) (server.NoContent, error) {
+			return server.NoContent{}, nil
+		}),
+		server.CreateTyped(server.GET, "/files/{path...}", func(ctx context.Context, req DownloadFile) (string, error) {
+			return "", nil
+		}),
+		server.CreateTyped(server.GET, "/stats", func(ctx context.Context, req struct{}) (map[string]int, error) {
+			return nil, nil
+		}),
+		// Untyped routes are skipped
+		server.CreateGET("/health", nil),
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	endpoints, err := Endpoints(sampleRoutes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, endpoint := range endpoints {
+		names = append(names, endpoint.Name)
+	}
+	expected := "GetOrders PlaceOrder GetOrdersID DeleteOrdersID GetFilesPath GetStats"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected %s, got %v", expected, names)
+	}
+
+	duplicate := append(sampleRoutes(), server.CreateTyped(server.PUT, "/order", func(ctx context.Context, req struct{}) (server.NoContent, error) {
+		return server.NoContent{}, nil
+	}).WithMeta("operationId", "placeOrder"))
+	if _, err := Endpoints(duplicate); err == nil || !strings.Contains(err.Error(), "both map to PlaceOrder") {
+		t.Errorf("expected a name conflict, got %v", err)
+	}
+}
+
+// TestGenerateGo builds the generated client in a module using this
+// repository
+func TestGenerateGo(t *testing.T) {
+	code, err := GenerateGo(sampleRoutes(), Options{Package: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"package shop\n",
+		"func (c *Client) PlaceOrder(ctx context.Context, req CreateOrder) (*Order, error) {",
+		"type CreateOrder struct {\n\tTenant string `header:\"X-Tenant\" json:\"-\"`",
+		"func (c *Client) GetOrdersID(ctx context.Context, req OrderID) (*Order, error) {",
+		"func (c *Client) DeleteOrdersID(ctx context.Context, req OrderID) error {",
+		"func (c *Client) GetStats(ctx context.Context) (map[string]int, error) {",
+		"type Status string\n",
+	} {
+		if !strings.Contains(string(code), expected) {
+			t.Errorf("expected %q in\n%s", expected, code)
+		}
+	}
+	if strings.Contains(string(code), "internal") {
+		t.Errorf("unexported fields must not be generated\n%s", code)
+	}
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	_, file, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(file), "..", "..")
+
+	dir := t.TempDir()
+	module := "module example.com/shop\n\ngo 1.25\n\nrequire goserve v0.0.0\n\nreplace goserve => " + root + "\n"
+	files := map[string]string{
+		"go.mod":       module,
+		"shop/shop.go": string(code),
+		"main.go":      "package main\n\nimport (\n\t\"context\"\n\t\"example.com/shop/shop\"\n)\n\nfunc main() {\n\tc := shop.New(\"http://localhost:8080\")\n\torder, _ := c.GetOrdersID(context.Background(), shop.OrderID{ID: 1})\n\t_ = order.Lines[0].SKU\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("generated client does not build: %v\n%s\n%s", err, output, code)
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	code, err := GenerateTypeScript(sampleRoutes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "client.ts.golden")
+	if *update {
+		if err := os.WriteFile(golden, code, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(code, expected) {
+		t.Errorf("generated client differs from %s, run go test -update and review the diff\n%s", golden, code)
+	}
+}
+
+func TestUnsupportedTypes(t *testing.T) {
+	routes := []server.RouteInfo{
+		server.CreateTyped(server.GET, "/events", func(ctx context.Context, req struct{}) (chan int, error) {
+			return nil, nil
+		}),
+	}
+	if _, err := GenerateGo(routes, Options{}); err == nil || !strings.Contains(err.Error(), "cannot be sent over HTTP") {
+		t.Errorf("expected an error for a channel, got %v", err)
+	}
+	if _, err := GenerateTypeScript(routes); err == nil {
+		t.Error("expected an error for a channel")
+	}
+}
@@ -0,0 +1,220 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"goserve/server"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+)
+
+type goGenerator struct {
+	imports  map[string]bool
+	named    map[string]reflect.Type
+	pending  []reflect.Type
+	declared bytes.Buffer
+}
+
+// GenerateGo produces a Go client package with one method per typed route
+func GenerateGo(routes []server.RouteInfo, opts Options) ([]byte, error) {
+	endpoints, err := Endpoints(routes)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Package == "" {
+		opts.Package = "client"
+	}
+
+	g := &goGenerator{
+		imports: map[string]bool{"context": true, "goserve/client": true},
+		named:   make(map[string]reflect.Type),
+	}
+
+	var methods bytes.Buffer
+	for _, endpoint := range endpoints {
+		if err := g.writeMethod(&methods, endpoint); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.flushTypes(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by goserve client generator. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", opts.Package)
+	out.WriteString("import (\n")
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n\n")
+	out.WriteString("type Client struct {\n\t*client.Client\n}\n\n")
+	out.WriteString("func New(baseURL string, options ...client.Option) *Client {\n\treturn &Client{Client: client.New(baseURL, options...)}\n}\n\n")
+	out.Write(g.declared.Bytes())
+	out.Write(methods.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %v\n%s", err, out.String())
+	}
+	return formatted, nil
+}
+
+func (g *goGenerator) writeMethod(buf *bytes.Buffer, e Endpoint) error {
+	params := "ctx context.Context"
+	request := "nil"
+	if e.hasRequest() {
+		requestType, err := g.typeExpr(e.Request)
+		if err != nil {
+			return err
+		}
+		params += ", req " + requestType
+		request = "req"
+	}
+
+	responseType := ""
+	if !e.noContent() {
+		var err error
+		if responseType, err = g.typeExpr(e.Response); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(buf, "// %s calls %s %s\n", e.Name, e.Method, e.Path)
+	switch {
+	case e.noContent():
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) error {\n", e.Name, params)
+		fmt.Fprintf(buf, "\treturn c.Call(ctx, %q, %q, %s, nil)\n}\n\n", e.Method, e.Path, request)
+	case e.Response.Kind() == reflect.Struct && e.Response != timeType:
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) (*%s, error) {\n", e.Name, params, responseType)
+		fmt.Fprintf(buf, "\tvar out %s\n", responseType)
+		fmt.Fprintf(buf, "\tif err := c.Call(ctx, %q, %q, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", e.Method, e.Path, request)
+		buf.WriteString("\treturn &out, nil\n}\n\n")
+	default:
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) (%s, error) {\n", e.Name, params, responseType)
+		fmt.Fprintf(buf, "\tvar out %s\n", responseType)
+		fmt.Fprintf(buf, "\terr := c.Call(ctx, %q, %q, %s, &out)\n", e.Method, e.Path, request)
+		buf.WriteString("\treturn out, err\n}\n\n")
+	}
+	return nil
+}
+
+// typeExpr returns the Go expression for typ in the generated package, queuing
+// named types for declaration
+func (g *goGenerator) typeExpr(typ reflect.Type) (string, error) {
+	switch typ {
+	case timeType:
+		g.imports["time"] = true
+		return "time.Time", nil
+	case durationType:
+		g.imports["time"] = true
+		return "time.Duration", nil
+	case rawJSONType:
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", nil
+	}
+
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		if existing, ok := g.named[typ.Name()]; ok {
+			if existing != typ {
+				return "", fmt.Errorf("types %s and %s share the name %s", existing, typ, typ.Name())
+			}
+			return typ.Name(), nil
+		}
+		g.named[typ.Name()] = typ
+		g.pending = append(g.pending, typ)
+		return typ.Name(), nil
+	}
+
+	return g.underlyingExpr(typ)
+}
+
+func (g *goGenerator) underlyingExpr(typ reflect.Type) (string, error) {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elem, err := g.typeExpr(typ.Elem())
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := g.typeExpr(typ.Elem())
+		return "[]" + elem, err
+	case reflect.Array:
+		elem, err := g.typeExpr(typ.Elem())
+		return fmt.Sprintf("[%d]%s", typ.Len(), elem), err
+	case reflect.Map:
+		key, err := g.typeExpr(typ.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := g.typeExpr(typ.Elem())
+		return fmt.Sprintf("map[%s]%s", key, elem), err
+	case reflect.Interface:
+		return "interface{}", nil
+	case reflect.Struct:
+		return g.structExpr(typ)
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return "", fmt.Errorf("type %s cannot be sent over HTTP", typ)
+	}
+	return typ.Kind().String(), nil
+}
+
+func (g *goGenerator) structExpr(typ reflect.Type) (string, error) {
+	var buf strings.Builder
+	buf.WriteString("struct {\n")
+	for _, f := range fieldsOf(typ) {
+		expr, err := g.typeExpr(f.goField.Type)
+		if err != nil {
+			return "", err
+		}
+
+		tag := string(f.goField.Tag)
+		if f.in != "" {
+			// Parameters must not leak into the JSON body
+			if _, ok := f.goField.Tag.Lookup("json"); !ok {
+				tag = strings.TrimSpace(tag + ` json:"-"`)
+			}
+		}
+		if strings.Contains(tag, "`") {
+			tag = " " + strconv.Quote(tag)
+		} else if tag != "" {
+			tag = " `" + tag + "`"
+		}
+
+		if f.goField.Anonymous {
+			fmt.Fprintf(&buf, "\t%s%s\n", expr, tag)
+		} else {
+			fmt.Fprintf(&buf, "\t%s %s%s\n", f.goField.Name, expr, tag)
+		}
+	}
+	buf.WriteString("}")
+	return buf.String(), nil
+}
+
+func (g *goGenerator) flushTypes() error {
+	for len(g.pending) > 0 {
+		typ := g.pending[0]
+		g.pending = g.pending[1:]
+
+		expr, err := g.underlyingExpr(typ)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.declared, "type %s %s\n\n", typ.Name(), expr)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"goserve/internal/naming"
+	"goserve/server"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type tsGenerator struct {
+	named    map[string]reflect.Type
+	pending  []reflect.Type
+	declared bytes.Buffer
+}
+
+const tsRuntime = `export interface Problem {
+  type?: string;
+  title: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  [key: string]: unknown;
+}
+
+export class ApiError extends Error {
+  constructor(
+    readonly status: number,
+    readonly problem?: Problem,
+    readonly body?: string,
+  ) {
+    super(problem ? ` + "`${status} ${problem.title}`" + ` : ` + "`${status}`" + `);
+  }
+}
+
+type Params = Record<string, unknown>;
+
+function fillPath(pattern: string, params: Params): string {
+  return pattern.replace(/\{([^}.]+)(\.\.\.)?\}/g, (_, name: string, rest?: string) => {
+    const value = String(params[name]);
+    return rest ? value.split("/").map(encodeURIComponent).join("/") : encodeURIComponent(value);
+  });
+}
+
+function appendValues(target: (key: string, value: string) => void, name: string, value: unknown): void {
+  if (value === undefined || value === null) return;
+  for (const item of Array.isArray(value) ? value : [value]) target(name, String(item));
+}
+
+function omit(source: Params, keys: string[]): Params {
+  const copy: Params = { ...source };
+  for (const key of keys) delete copy[key];
+  return copy;
+}
+
+export class Client {
+  constructor(
+    private readonly baseUrl: string,
+    private readonly init: RequestInit = {},
+  ) {}
+
+  protected async call<T>(
+    method: string,
+    pattern: string,
+    req: Params,
+    params: { path: string[]; query: string[]; header: string[] },
+    withBody: boolean,
+    signal?: AbortSignal,
+  ): Promise<T> {
+    const query = new URLSearchParams();
+    const headers = new Headers(this.init.headers);
+    headers.set("Accept", "application/json");
+    for (const name of params.query) appendValues((k, v) => query.append(k, v), name, req[name]);
+    for (const name of params.header) appendValues((k, v) => headers.append(k, v), name, req[name]);
+
+    let body: string | undefined;
+    if (withBody) {
+      headers.set("Content-Type", "application/json");
+      body = JSON.stringify(omit(req, [...params.path, ...params.query, ...params.header]));
+    }
+
+    const search = query.toString();
+    const url = this.baseUrl.replace(/\/$/, "") + fillPath(pattern, req) + (search ? "?" + search : "");
+    const response = await fetch(url, { ...this.init, method, headers, body, signal });
+    const text = await response.text();
+
+    if (!response.ok) {
+      const isProblem = (response.headers.get("Content-Type") ?? "").startsWith("application/problem+json");
+      throw new ApiError(response.status, isProblem && text ? (JSON.parse(text) as Problem) : undefined, text);
+    }
+    return (text ? JSON.parse(text) : undefined) as T;
+  }
+`
+
+// GenerateTypeScript produces a fetch based TypeScript client with one method
+// per typed route
+func GenerateTypeScript(routes []server.RouteInfo) ([]byte, error) {
+	endpoints, err := Endpoints(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &tsGenerator{named: make(map[string]reflect.Type)}
+
+	var methods bytes.Buffer
+	for _, endpoint := range endpoints {
+		if err := g.writeMethod(&methods, endpoint); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.flushTypes(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by goserve client generator. DO NOT EDIT.\n\n")
+	out.Write(g.declared.Bytes())
+	out.WriteString(tsRuntime)
+	out.Write(methods.Bytes())
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+func (g *tsGenerator) writeMethod(buf *bytes.Buffer, e Endpoint) error {
+	responseType := "void"
+	if !e.noContent() {
+		var err error
+		if responseType, err = g.typeExpr(e.Response); err != nil {
+			return err
+		}
+	}
+
+	requestType := "Params"
+	params := map[string][]string{}
+	if e.hasRequest() {
+		var err error
+		if requestType, err = g.typeExpr(e.Request); err != nil {
+			return err
+		}
+		if e.Request.Kind() == reflect.Struct {
+			for _, f := range flatFields(e.Request) {
+				if f.in != "" {
+					params[f.in] = append(params[f.in], strconv.Quote(f.name))
+				}
+			}
+		}
+	}
+
+	// Inline object types continue at the indentation of the method
+	requestType = strings.ReplaceAll(requestType, "\n", "\n  ")
+	responseType = strings.ReplaceAll(responseType, "\n", "\n  ")
+
+	name := naming.LowerCamel(e.Name)
+	fmt.Fprintf(buf, "\n  /** %s %s */\n", e.Method, e.Path)
+	if e.hasRequest() {
+		fmt.Fprintf(buf, "  %s(req: %s, signal?: AbortSignal): Promise<%s> {\n", name, requestType, responseType)
+	} else {
+		fmt.Fprintf(buf, "  %s(signal?: AbortSignal): Promise<%s> {\n", name, responseType)
+	}
+
+	request := "{}"
+	if e.hasRequest() {
+		request = "req as unknown as Params"
+	}
+	withBody := e.hasRequest() && (e.Method == "POST" || e.Method == "PUT" || e.Method == "PATCH")
+	fmt.Fprintf(buf, "    return this.call<%s>(%q, %q, %s, {\n", responseType, e.Method, e.Path, request)
+	for _, in := range []string{"path", "query", "header"} {
+		fmt.Fprintf(buf, "      %s: [%s],\n", in, strings.Join(params[in], ", "))
+	}
+	fmt.Fprintf(buf, "    }, %t, signal);\n  }\n", withBody)
+	return nil
+}
+
+// flatFields lists the fields of a struct with embedded structs flattened,
+// as encoding/json does
+func flatFields(typ reflect.Type) []field {
+	var fields []field
+	for _, f := range fieldsOf(typ) {
+		if f.omitted && f.in == "" {
+			continue
+		}
+		if f.flattened {
+			fields = append(fields, flatFields(f.goField.Type)...)
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func (g *tsGenerator) typeExpr(typ reflect.Type) (string, error) {
+	switch typ {
+	case timeType:
+		return "string", nil
+	case rawJSONType:
+		return "unknown", nil
+	}
+
+	if typ.Name() != "" && typ.PkgPath() != "" && typ != durationType {
+		if existing, ok := g.named[typ.Name()]; ok {
+			if existing != typ {
+				return "", fmt.Errorf("types %s and %s share the name %s", existing, typ, typ.Name())
+			}
+			return typ.Name(), nil
+		}
+		g.named[typ.Name()] = typ
+		g.pending = append(g.pending, typ)
+		return typ.Name(), nil
+	}
+
+	return g.underlyingExpr(typ)
+}
+
+func (g *tsGenerator) underlyingExpr(typ reflect.Type) (string, error) {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elem, err := g.typeExpr(typ.Elem())
+		return elem + " | null", err
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// base64 encoded by encoding/json
+			return "string", nil
+		}
+		elem, err := g.typeExpr(typ.Elem())
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]", err
+	case reflect.Map:
+		elem, err := g.typeExpr(typ.Elem())
+		return "Record<string, " + elem + ">", err
+	case reflect.Interface:
+		return "unknown", nil
+	case reflect.Struct:
+		return g.structExpr(typ)
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number", nil
+	}
+	return "", fmt.Errorf("type %s cannot be sent over HTTP", typ)
+}
+
+// structExpr returns an object type, nested ones indented under their field
+func (g *tsGenerator) structExpr(typ reflect.Type) (string, error) {
+	var buf strings.Builder
+	buf.WriteString("{\n")
+	for _, f := range flatFields(typ) {
+		expr, err := g.typeExpr(f.goField.Type)
+		if err != nil {
+			return "", err
+		}
+		optional := ""
+		if f.optional || f.in == "query" || f.in == "header" {
+			optional = "?"
+		}
+		fmt.Fprintf(&buf, "  %s%s: %s;\n", tsKey(f.name), optional, strings.ReplaceAll(expr, "\n", "\n  "))
+	}
+	buf.WriteString("}")
+	return buf.String(), nil
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func (g *tsGenerator) flushTypes() error {
+	for len(g.pending) > 0 {
+		typ := g.pending[0]
+		g.pending = g.pending[1:]
+
+		if typ.Kind() == reflect.Struct {
+			expr, err := g.structExpr(typ)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&g.declared, "export interface %s %s\n\n", typ.Name(), expr)
+			continue
+		}
+
+		expr, err := g.underlyingExpr(typ)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.declared, "export type %s = %s;\n\n", typ.Name(), expr)
+	}
+	return nil
+}
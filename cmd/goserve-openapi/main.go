@@ -0,0 +1,57 @@
+// Command goserve-openapi generates route stubs and typed handlers from an
+// OpenAPI 3 JSON document.
+//
+// Usage with go generate:
+//
+//	//go:generate go run goserve/cmd/goserve-openapi -in openapi.json -out api.gen.go -pkg api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"goserve/openapi/generator"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	in := flag.String("in", "openapi.json", "OpenAPI 3 JSON document")
+	out := flag.String("out", "", "output file (default: stdout)")
+	pkg := flag.String("pkg", "", "package name (default: name of the output directory)")
+	flag.Parse()
+
+	if err := run(*in, *out, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "goserve-openapi: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, pkg string) error {
+	spec, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	if pkg == "" {
+		pkg = "api"
+		if out != "" {
+			if abs, err := filepath.Abs(out); err == nil {
+				pkg = filepath.Base(filepath.Dir(abs))
+			}
+		}
+	}
+
+	code, err := generator.Generate(spec, generator.Options{
+		Package: pkg,
+		Source:  filepath.Base(in),
+	})
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(out, code, 0644)
+}
@@ -0,0 +1,65 @@
+// Command goserve-replay sends recorded traffic to a running server and
+// reports the responses that differ from the recording.
+//
+//	goserve-replay -file traffic.jsonl -target http://localhost:8080 -ignore id -ignore items[*].createdAt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"goserve/traffic"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	file := flag.String("file", traffic.DefaultFilename, "recorded JSONL file")
+	target := flag.String("target", "http://localhost:8080", "base URL of the server")
+	var ignore, headers, compare listFlag
+	flag.Var(&ignore, "ignore", "JSON path excluded from the comparison (repeatable)")
+	flag.Var(&headers, "header", "header sent with every request, as Name: value (repeatable)")
+	flag.Var(&compare, "compare-header", "response header compared (repeatable, default Content-Type)")
+	flag.Parse()
+
+	entries, err := traffic.ReadFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goserve-replay: %v\n", err)
+		os.Exit(1)
+	}
+
+	extra := make(http.Header)
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "goserve-replay: invalid header %q\n", header)
+			os.Exit(2)
+		}
+		extra.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	results := traffic.Replay(entries, traffic.ReplayOptions{
+		BaseURL:        *target,
+		Headers:        extra,
+		CompareHeaders: compare,
+		IgnorePaths:    ignore,
+	})
+	fmt.Print(traffic.Report(results))
+
+	for _, result := range results {
+		if !result.OK() {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Command goserve runs the goserve command line without application routes:
+// it prints, validates and documents the configuration.
+//
+//	goserve config print --config config.yaml
+//	goserve config validate config.yaml config.production.yaml
+//
+// Applications embed the same commands with their routes through package cli.
+package main
+
+import "goserve/cli"
+
+func main() {
+	cli.New("goserve").Main()
+}
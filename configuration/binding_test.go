@@ -0,0 +1,118 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvBinding(t *testing.T) {
+	t.Setenv("APP_PORT", "08080")
+	t.Setenv("READ_TIMEOUT", "010")
+	t.Setenv("WRITE_TIMEOUT", "20")
+
+	config, err := New().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetPort() != 8080 {
+		t.Errorf("APP_PORT=08080: expected port 8080, got %d", config.GetPort())
+	}
+	if config.GetReadTimeout() != 10 {
+		t.Errorf("READ_TIMEOUT=010: expected 10 seconds, got %d", config.GetReadTimeout())
+	}
+	if config.GetWriteTimeout() != 20 {
+		t.Errorf("expected a write timeout of 20 seconds, got %d", config.GetWriteTimeout())
+	}
+	if origin, _ := config.Origin("server.port"); origin != "env APP_PORT" {
+		t.Errorf("unexpected origin %q", origin)
+	}
+}
+
+func TestEnvBindingErrors(t *testing.T) {
+	t.Setenv("APP_PORT", "0x1F90")
+	t.Setenv("IDLE_TIMEOUT", "sixty")
+
+	_, err := New().Load()
+	if err == nil {
+		t.Fatal("expected invalid variables to fail Load")
+	}
+	for _, expected := range []string{`APP_PORT (Server.Port): invalid integer "0x1F90"`, `IDLE_TIMEOUT (Server.IdleTimeout): invalid integer "sixty"`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err)
+		}
+	}
+}
+
+type limits struct {
+	Burst    int           `json:"burst"`
+	Interval time.Duration `json:"interval" default:"1s"`
+	Window   time.Duration `json:"window" env:"LIMITS_WINDOW"`
+}
+
+func TestSectionBinding(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	content := "server:\n  port: 09090\napp:\n  limits:\n    burst: 010\n"
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var section limits
+	config, err := New().Section("app.limits", &section).LoadConfigFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetPort() != 9090 {
+		t.Errorf("expected port 9090 from the file, got %d", config.GetPort())
+	}
+	if section.Burst != 10 || section.Interval != time.Second {
+		t.Errorf("unexpected section %+v", section)
+	}
+
+	t.Setenv("LIMITS_WINDOW", "NaN")
+	_, err = New().Section("app.limits", &limits{}).LoadConfigFromFile(filename)
+	if err == nil || !strings.Contains(err.Error(), `invalid duration "NaN"`) {
+		t.Errorf("expected a NaN duration to fail Load, got %v", err)
+	}
+}
+
+func TestPprofIsOptIn(t *testing.T) {
+	config, err := New().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.IsDevelopment() || config.GetProfile().Pprof {
+		t.Errorf("expected pprof to be off in the default environment, got %+v", config.GetProfile())
+	}
+
+	t.Setenv("APP_PPROF", "true")
+	config, err = New().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.GetProfile().Pprof {
+		t.Error("expected APP_PPROF=true to enable pprof")
+	}
+	if origin, _ := config.Origin("server.pprof"); origin != "env APP_PPROF" {
+		t.Errorf("unexpected origin %q", origin)
+	}
+}
+
+func TestUnregisteredEnvironment(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeFile(t, ".env", "APP_ENV=preprod\n")
+
+	_, err := New().LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), `invalid environment "preprod", expected one of development, testing, staging, production`) {
+		t.Errorf("expected an unregistered APP_ENV to fail validation, got %v", err)
+	}
+
+	t.Setenv("APP_ENV", "Nowhere")
+	_, err = New().LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), `invalid environment "nowhere"`) {
+		t.Errorf("expected an unregistered APP_ENV to fail validation, got %v", err)
+	}
+}
@@ -1,29 +1,73 @@
 package configuration
 
 import (
+	"errors"
 	"fmt"
+	"goserve/configuration/secrets"
 	"goserve/configuration/utils"
 	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 )
 
+// ErrSourceNotFound is returned by a source whose file does not exist. It
+// only fails Load when the source is Required.
+var ErrSourceNotFound = errors.New("configuration source not found")
+
+// SourceError is returned by a source failing on one of its files or URLs.
+// Its message names the file, so Load reports it without the source name.
+type SourceError struct {
+	// File name or URL
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
 type ConfigurationSource struct {
 	Filename string
+	// Sources are applied by ascending priority, so higher priorities
+	// override lower ones; sources sharing a priority keep the order they
+	// were added in
 	Priority int
 	Load     func(*Config) error
+	// A required source fails Load when it is missing
+	Required bool
+	// Changed, when set, reports whether the source changed since it was
+	// loaded; StartWatching polls it along with the files
+	Changed func() bool
 }
 
 type ConfigLoader struct {
-	sources []ConfigurationSource
-	config  *Config
+	sources        []ConfigurationSource
+	sections       []section
+	listStrategies map[string]ListStrategy
+	strict         bool
+	secretKeyFile  string
+
+	// Options of the last Load, applied again by Reload
+	options []ConfigOption
+	// Last valid configuration, swapped by Reload
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	watchers    []func(old, new Configuration)
+	keyWatchers []keyWatcher
 }
 
 func New() ConfigurationBuilder {
 	return &ConfigLoader{
 		sources: make([]ConfigurationSource, 0),
-		config: &Config{
-			App:    make(map[string]interface{}),
-			Custom: make(map[string]interface{}),
-		},
 	}
 }
 
@@ -32,30 +76,148 @@ func (cl *ConfigLoader) AddSource(source ConfigurationSource) ConfigurationBuild
 	return cl
 }
 
+func (cl *ConfigLoader) Section(path string, target interface{}) ConfigurationBuilder {
+	cl.sections = append(cl.sections, section{path: path, target: target})
+	return cl
+}
+
+func (cl *ConfigLoader) WithListStrategy(path string, strategy ListStrategy) ConfigurationBuilder {
+	if cl.listStrategies == nil {
+		cl.listStrategies = make(map[string]ListStrategy)
+	}
+	cl.listStrategies[normalizePath(path)] = strategy
+	return cl
+}
+
+func (cl *ConfigLoader) WithStrictMode(strict bool) ConfigurationBuilder {
+	cl.strict = strict
+	return cl
+}
+
+func (cl *ConfigLoader) WithSecretKeyFile(filename string) ConfigurationBuilder {
+	cl.secretKeyFile = filename
+	return cl
+}
+
+// Load applies the sources by ascending priority. Any source error, a
+// missing required source or an invalid setting fails Load with every
+// problem found; in strict mode unknown keys do as well.
 func (cl *ConfigLoader) Load(options ...ConfigOption) (Configuration, error) {
-	cl.config.Server.setDefaults()
-	cl.config.Server.loadFromEnv()
-
-	for i := 0; i <= 10; i++ { // Priorité de 0 à 10
-		for _, source := range cl.sources {
-			if source.Priority == i {
-				if err := source.Load(cl.config); err != nil {
-					log.Printf("Error on loading %s: %v", source.Filename, err)
-				} else {
-					log.Printf("Loading configuration from: %s", source.Filename)
-				}
-			}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.options = options
+	config, err := cl.build(true)
+	if err != nil {
+		return nil, err
+	}
+	cl.current.Store(config)
+	config.LogConfiguration()
+
+	return config, nil
+}
+
+// build runs the load pipeline into a new Config. Registered sections are
+// filled on the first load only; reloads validate them into fresh values.
+func (cl *ConfigLoader) build(fillSections bool) (*Config, error) {
+	var bindErrors utils.EnvErrors
+	var failures []error
+
+	config := &Config{
+		App:            make(map[string]interface{}),
+		Custom:         make(map[string]interface{}),
+		listStrategies: cl.listStrategies,
+	}
+	config.Server.setDefaults()
+	config.recordDefaults()
+	collectEnvErrors(&bindErrors, loadFromEnvVars(config))
+
+	keyFile := cl.secretKeyFile
+	if keyFile == "" {
+		keyFile = os.Getenv(SecretKeyFileEnv)
+	}
+	if keyFile != "" {
+		key, err := secrets.ReadKeyFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: secret key: %w", err)
 		}
+		config.secretKey = key
 	}
 
-	for _, option := range options {
-		option(cl.config)
+	sources := append([]ConfigurationSource{}, cl.sources...)
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority < sources[j].Priority
+	})
+	for _, source := range sources {
+		err := source.Load(config)
+		switch {
+		case err == nil:
+			log.Printf("Loading configuration from: %s", source.Filename)
+		case errors.Is(err, ErrSourceNotFound) && !source.Required:
+			log.Printf("Skipping missing configuration source: %s", source.Filename)
+		case errors.Is(err, ErrSourceNotFound):
+			failures = append(failures, fmt.Errorf("required source %s: %w", source.Filename, err))
+		case collectEnvErrors(&bindErrors, err):
+		case errors.As(err, new(*SourceError)):
+			failures = append(failures, err)
+		default:
+			failures = append(failures, fmt.Errorf("%s: %w", source.Filename, err))
+		}
 	}
 
-	cl.config.Server.validate()
-	cl.config.Server.LogConfiguration()
+	for _, option := range cl.options {
+		option(config)
+	}
 
-	return cl.config, nil
+	collectEnvErrors(&bindErrors, utils.CheckRequired(config))
+	for _, section := range cl.sections {
+		target := section.target
+		if !fillSections {
+			target = reflect.New(reflect.TypeOf(target).Elem()).Interface()
+		}
+		if err := BindTo(config, section.path, target); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(bindErrors) > 0 {
+		failures = append(failures, bindErrors)
+	}
+	if err := config.Server.validate(); err != nil {
+		failures = append(failures, err)
+	}
+	for _, key := range config.unknownKeys {
+		if cl.strict {
+			failures = append(failures, fmt.Errorf("unknown key %s", key))
+		} else {
+			log.Printf("Unknown configuration key ignored: %s", key)
+		}
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(failures...))
+	}
+	return config, nil
+}
+
+// collectEnvErrors appends the binding errors of err, skipping the ones
+// already reported since the environment is read more than once
+func collectEnvErrors(errs *utils.EnvErrors, err error) bool {
+	var bindErrors utils.EnvErrors
+	if !errors.As(err, &bindErrors) {
+		return false
+	}
+	for _, bindError := range bindErrors {
+		duplicate := false
+		for _, existing := range *errs {
+			if existing.Variable != "" && existing.Variable == bindError.Variable {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			*errs = append(*errs, bindError)
+		}
+	}
+	return true
 }
 
 func (cl *ConfigLoader) LoadConfig(options ...ConfigOption) (Configuration, error) {
@@ -66,6 +228,8 @@ func (cl *ConfigLoader) LoadConfig(options ...ConfigOption) (Configuration, erro
 		Load:     func(c *Config) error { return nil }, // Déjà fait dans Load()
 	})
 
+	cl.AddSource(DotEnvSource("."))
+
 	cl.AddSource(ConfigurationSource{
 		Filename: "config.json",
 		Priority: 1,
@@ -73,14 +237,26 @@ func (cl *ConfigLoader) LoadConfig(options ...ConfigOption) (Configuration, erro
 	})
 
 	cl.AddSource(ConfigurationSource{
-		Filename: "config.{env}.json",
+		Filename: "config.yaml",
+		Priority: 1,
+		Load:     loadFromYAMLFile("config.yaml"),
+	})
+
+	cl.AddSource(ConfigurationSource{
+		Filename: "config.toml",
+		Priority: 1,
+		Load:     loadFromTOMLFile("config.toml"),
+	})
+
+	cl.AddSource(ConfigurationSource{
+		Filename: "config.{env}.{json,yaml,yml,toml}",
 		Priority: 2,
-		Load:     loadFromEnvSpecificJSONFile(),
+		Load:     loadFromEnvSpecificFiles(),
 	})
 
 	cl.AddSource(ConfigurationSource{
 		Filename: "environment variables",
-		Priority: 3,
+		Priority: 4,
 		Load:     loadFromEnvVars,
 	})
 
@@ -95,15 +271,17 @@ func (cl *ConfigLoader) LoadConfigFromFile(filename string, options ...ConfigOpt
 		Load:     func(c *Config) error { return nil },
 	})
 
+	cl.AddSource(DotEnvSource(filepath.Dir(filename)))
+
 	cl.AddSource(ConfigurationSource{
 		Filename: fmt.Sprintf("file: %s", filename),
 		Priority: 1,
-		Load:     loadFromJSONFile(filename),
+		Load:     loadFromFile(filename),
 	})
 
 	cl.AddSource(ConfigurationSource{
 		Filename: "environment variables",
-		Priority: 2,
+		Priority: 4,
 		Load:     loadFromEnvVars,
 	})
 
@@ -111,5 +289,7 @@ func (cl *ConfigLoader) LoadConfigFromFile(filename string, options ...ConfigOpt
 }
 
 func loadFromEnvVars(config *Config) error {
-	return utils.LoadStructFromEnv(config)
+	return utils.BindEnvWith(config, "", config.resolveEnv, func(field, variable string) {
+		config.setOrigin(goPath(field), "env "+variable)
+	})
 }
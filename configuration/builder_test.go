@@ -0,0 +1,105 @@
+package configuration
+
+import (
+	"errors"
+	"goserve/configuration/secrets"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSourceErrors(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(filename, []byte("server: port: 80\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New().LoadConfigFromFile(filename)
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) {
+		t.Fatalf("expected a SourceError, got %v", err)
+	}
+	if sourceErr.Source != filename {
+		t.Errorf("expected the source %s, got %s", filename, sourceErr.Source)
+	}
+	// The error names the file once, without the source name
+	if strings.Contains(err.Error(), "file: ") || strings.Count(err.Error(), filename) != 1 {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestDotEnvReload(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(filename, []byte("app:\n  greeting: ${GREETING:-none}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dotenv := filepath.Join(dir, ".env")
+	if err := os.WriteFile(dotenv, []byte("GREETING=hello\nAPP_PORT=9090\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := New()
+	config, err := loader.LoadConfigFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetString("app.greeting") != "hello" || config.GetPort() != 9090 {
+		t.Errorf("expected the .env values, got %q and port %d", config.GetString("app.greeting"), config.GetPort())
+	}
+	if value, ok := config.LookupEnv("GREETING"); !ok || value != "hello" {
+		t.Errorf("expected LookupEnv to return the .env value, got %q", value)
+	}
+	if _, ok := os.LookupEnv("GREETING"); ok {
+		t.Error("the .env values must not be exported to the process")
+	}
+
+	if err := os.WriteFile(dotenv, []byte("APP_PORT=9191\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	config = loader.Current()
+	if config.GetString("app.greeting") != "none" || config.GetPort() != 9191 {
+		t.Errorf("expected the removed entry to be dropped on reload, got %q and port %d", config.GetString("app.greeting"), config.GetPort())
+	}
+}
+
+func TestEncryptedValues(t *testing.T) {
+	t.Setenv(SecretKeyFileEnv, "")
+	dir := t.TempDir()
+	key, _ := secrets.GenerateKey()
+	keyFile := filepath.Join(dir, "secret.key")
+	if err := secrets.WriteKeyFile(keyFile, key); err != nil {
+		t.Fatal(err)
+	}
+	value, err := secrets.Encrypt(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(filename, []byte("app:\n  password: "+value+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := New().WithSecretKeyFile(keyFile).LoadConfigFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password := config.GetString("app.password"); password != "s3cret" {
+		t.Errorf("expected the decrypted value, got %q", password)
+	}
+
+	other := filepath.Join(dir, "other.key")
+	otherKey, _ := secrets.GenerateKey()
+	secrets.WriteKeyFile(other, otherKey)
+	if _, err := New().WithSecretKeyFile(other).LoadConfigFromFile(filename); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("expected a wrong key error, got %v", err)
+	}
+	if _, err := New().LoadConfigFromFile(filename); err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+}
@@ -1,15 +1,48 @@
 package configuration
 
-import "goserve/configuration/env"
+import (
+	"goserve/configuration/env"
+	"goserve/configuration/utils"
+	"os"
+)
 
 type Config struct {
 	Server ServeurConfiguration
-	App    map[string]interface{} `jon:"app" yaml:"app"`
+	App    map[string]interface{} `json:"app" yaml:"app"`
 	Custom map[string]interface{} `json:"custom" yaml:"custom"`
+
+	// Source of every key set, see Origin
+	origins        map[string]string
+	listStrategies map[string]ListStrategy
+	// Keys of the last load that match no setting, with their source
+	unknownKeys []string
+	// Files read or looked for by the sources, watched for changes
+	files []string
+	// Key decrypting "enc:" values, and the keys holding secrets
+	secretKey []byte
+	secrets   map[string]bool
+	// Variables of the .env files, kept here rather than exported to the
+	// process so a reload can drop them
+	dotenv map[string]string
 }
 
 type ConfigOption func(*Config)
 
+// LookupEnv returns a variable of the process environment, or else of the
+// .env files loaded by DotEnvSource
+func (c *Config) LookupEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := c.dotenv[name]
+	return value, ok
+}
+
+// resolveEnv resolves variables, and their _FILE indirection, with LookupEnv
+func (c *Config) resolveEnv(name string) (string, bool, error) {
+	return utils.LookupFunc(c.LookupEnv)(name)
+}
+
 func (c *Config) GetAddress() string {
 	return c.Server.Host
 }
@@ -34,18 +67,26 @@ func (c *Config) GetWriteTimeout() int {
 	return c.Server.WriteTimeout
 }
 
+func (c *Config) GetProfile() env.Profile {
+	profile := c.Server.Environment.Profile()
+	if c.Server.Pprof {
+		profile.Pprof = true
+	}
+	return profile
+}
+
 func (c *Config) IsDevelopment() bool {
-	return c.Server.Environment == env.Development
+	return c.Server.Environment.Is(env.Development)
 }
 
 func (c *Config) IsStaging() bool {
-	return c.Server.Environment == env.Staging
+	return c.Server.Environment.Is(env.Staging)
 }
 
 func (c *Config) IsProduction() bool {
-	return c.Server.Environment == env.Production
+	return c.Server.Environment.Is(env.Production)
 }
 
 func (c *Config) IsTesting() bool {
-	return c.Server.Environment == env.Testing
+	return c.Server.Environment.Is(env.Testing)
 }
@@ -0,0 +1,231 @@
+// Package dotenv parses .env files with the rules shared by the common
+// dotenv tools:
+//
+//	# comment
+//	export KEY=value          # trailing comment
+//	QUOTED="line1\nline2"     # escapes, may span lines, interpolated
+//	LITERAL='${NOT_EXPANDED}' # single quotes and backticks are literal
+package dotenv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Entry is one KEY=VALUE assignment
+type Entry struct {
+	Key   string
+	Value string
+	// Unquoted and double quoted values are subject to ${VAR} interpolation;
+	// escaped dollars are written $$
+	Expand bool
+	Line   int
+}
+
+func ParseFile(filename string) ([]Entry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return entries, nil
+}
+
+// Parse decodes the assignments of a .env file in order
+func Parse(data []byte) ([]Entry, error) {
+	text := strings.TrimPrefix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\ufeff")
+	p := &parser{text: text, line: 1}
+
+	var entries []Entry
+	for {
+		p.blank()
+		if p.eof() {
+			return entries, nil
+		}
+		entry, err := p.assignment()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", p.line, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+type parser struct {
+	text string
+	pos  int
+	line int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *parser) next() byte {
+	c := p.text[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *parser) space() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// blank skips empty lines and comment lines
+func (p *parser) blank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n':
+			p.next()
+		case '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// endOfLine accepts trailing spaces and a comment
+func (p *parser) endOfLine() error {
+	p.space()
+	if p.peek() == '#' {
+		p.skipLine()
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return fmt.Errorf("unexpected %q after value", p.rest())
+	}
+	return nil
+}
+
+func (p *parser) rest() string {
+	end := strings.IndexByte(p.text[p.pos:], '\n')
+	if end < 0 {
+		return p.text[p.pos:]
+	}
+	return p.text[p.pos : p.pos+end]
+}
+
+func (p *parser) assignment() (Entry, error) {
+	entry := Entry{Line: p.line}
+	if strings.HasPrefix(p.text[p.pos:], "export ") {
+		p.pos += len("export ")
+		p.space()
+	}
+
+	start := p.pos
+	for !p.eof() && isKey(p.peek()) {
+		p.pos++
+	}
+	entry.Key = p.text[start:p.pos]
+	if entry.Key == "" || (entry.Key[0] >= '0' && entry.Key[0] <= '9') {
+		return entry, fmt.Errorf("invalid variable name in %q", p.rest())
+	}
+	p.space()
+	if p.peek() != '=' {
+		return entry, fmt.Errorf("expected '=' after %s", entry.Key)
+	}
+	p.pos++
+	p.space()
+
+	var err error
+	switch p.peek() {
+	case '\'', '`':
+		entry.Value, err = p.literal(p.peek())
+	case '"':
+		entry.Value, err = p.double()
+		entry.Expand = true
+	default:
+		entry.Value = p.unquoted()
+		entry.Expand = true
+	}
+	if err != nil {
+		return entry, err
+	}
+	return entry, p.endOfLine()
+}
+
+func isKey(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// unquoted reads up to the end of line or a " #" comment
+func (p *parser) unquoted() string {
+	value := p.rest()
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.Index(value, "\t#"); i >= 0 {
+		value = value[:i]
+	}
+	p.pos += len(value)
+	return strings.TrimSpace(value)
+}
+
+func (p *parser) literal(quote byte) (string, error) {
+	p.next()
+	end := strings.IndexByte(p.text[p.pos:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated %c quoted value", quote)
+	}
+	value := p.text[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 1
+	return value, nil
+}
+
+func (p *parser) double() (string, error) {
+	startLine := p.line
+	p.next()
+	var buf strings.Builder
+	for !p.eof() {
+		c := p.next()
+		switch c {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			if p.eof() {
+				break
+			}
+			escaped := p.next()
+			switch escaped {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case '$':
+				buf.WriteString("$$")
+			case '"', '\\', '\'', '`':
+				buf.WriteByte(escaped)
+			default:
+				buf.WriteByte('\\')
+				buf.WriteByte(escaped)
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated double quoted value starting on line %d", startLine)
+}
@@ -0,0 +1,97 @@
+package dotenv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Entry
+	}{
+		{
+			name:     "unquoted",
+			input:    "KEY=value\nSPACED =  some value  \nEMPTY=\n",
+			expected: []Entry{{"KEY", "value", true, 1}, {"SPACED", "some value", true, 2}, {"EMPTY", "", true, 3}},
+		},
+		{
+			name:     "comments",
+			input:    "# header\n\n  # indented\nKEY=value # trailing\nHASH=a#b\nTAB=c\t# tab\n",
+			expected: []Entry{{"KEY", "value", true, 4}, {"HASH", "a#b", true, 5}, {"TAB", "c", true, 6}},
+		},
+		{
+			name:     "export prefix",
+			input:    "export KEY=value\nexport  SPACED=1\nexported=2\n",
+			expected: []Entry{{"KEY", "value", true, 1}, {"SPACED", "1", true, 2}, {"exported", "2", true, 3}},
+		},
+		{
+			name:     "double quotes",
+			input:    `QUOTED="a # not a comment" # comment` + "\n" + `ESCAPES="tab\tnew\nline \"q\" back\\slash \$HOME \x"`,
+			expected: []Entry{{"QUOTED", "a # not a comment", true, 1}, {"ESCAPES", "tab\tnew\nline \"q\" back\\slash $$HOME \\x", true, 2}},
+		},
+		{
+			name:     "single quotes and backticks",
+			input:    "SINGLE='${HOME} \\n \"x\"'\nBACKTICK=`it's`\n",
+			expected: []Entry{{"SINGLE", `${HOME} \n "x"`, false, 1}, {"BACKTICK", "it's", false, 2}},
+		},
+		{
+			name:  "multi-line values",
+			input: "CERT=\"-----BEGIN-----\nabc\n-----END-----\"\nKEY='one\ntwo'\nNEXT=1\n",
+			expected: []Entry{
+				{"CERT", "-----BEGIN-----\nabc\n-----END-----", true, 1},
+				{"KEY", "one\ntwo", false, 4},
+				{"NEXT", "1", true, 6},
+			},
+		},
+		{
+			name:     "windows line endings and BOM",
+			input:    "\ufeffKEY=value\r\nOTHER=\"x\"\r\n",
+			expected: []Entry{{"KEY", "value", true, 1}, {"OTHER", "x", true, 2}},
+		},
+		{
+			name:     "dotted and dashed names",
+			input:    "app.name=shop\nmy-key=1\n",
+			expected: []Entry{{"app.name", "shop", true, 1}, {"my-key", "1", true, 2}},
+		},
+		{
+			name:     "duplicates are kept in order",
+			input:    "KEY=1\nKEY=2\n",
+			expected: []Entry{{"KEY", "1", true, 1}, {"KEY", "2", true, 2}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := Parse([]byte(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, entries)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"KEY value\n", "line 1: expected '=' after KEY"},
+		{"OK=1\n=value\n", `line 2: invalid variable name in "=value"`},
+		{"1KEY=value\n", "line 1: invalid variable name"},
+		{"KEY=\"open\nstill open\n", "unterminated double quoted value starting on line 1"},
+		{"KEY='open\n", "unterminated ' quoted value"},
+		{"KEY=\"value\" extra\n", `line 1: unexpected "extra" after value`},
+		{"KEY='a' 'b'\n", `unexpected "'b'" after value`},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected %q, got %v", test.input, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package configuration
+
+import (
+	"fmt"
+	"goserve/configuration/dotenv"
+	"goserve/configuration/env"
+	"goserve/configuration/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DotEnvSource loads .env files from dir before the environment variables
+// are bound. From lowest to highest precedence: .env, .env.{environment},
+// .env.local and .env.{environment}.local; variables already set in the
+// process always win. The values stay in the Config, read with LookupEnv,
+// and are never exported to the process, so a reload drops the removed ones.
+func DotEnvSource(dir string) ConfigurationSource {
+	return ConfigurationSource{
+		Filename: filepath.Join(dir, ".env"),
+		Priority: 0,
+		Load:     loadFromDotEnvFiles(dir),
+	}
+}
+
+type dotEnvValue struct {
+	dotenv.Entry
+	filename string
+}
+
+func loadFromDotEnvFiles(dir string) func(*Config) error {
+	return func(config *Config) error {
+		base, err := readDotEnv(config, dir, ".env")
+		if err != nil {
+			return err
+		}
+		local, err := readDotEnv(config, dir, ".env.local")
+		if err != nil {
+			return err
+		}
+
+		environment := string(config.Server.Environment)
+		if value, ok := os.LookupEnv(env.APP_ENVIRONMENT_KEY); ok && value != "" {
+			environment = value
+		} else if value, ok := local[env.APP_ENVIRONMENT_KEY]; ok && value.Value != "" {
+			environment = value.Value
+		} else if value, ok := base[env.APP_ENVIRONMENT_KEY]; ok && value.Value != "" {
+			environment = value.Value
+		}
+		environment = strings.ToLower(environment)
+
+		specific, err := readDotEnv(config, dir, ".env."+environment)
+		if err != nil {
+			return err
+		}
+		specificLocal, err := readDotEnv(config, dir, ".env."+environment+".local")
+		if err != nil {
+			return err
+		}
+
+		if base == nil && local == nil && specific == nil && specificLocal == nil {
+			return fmt.Errorf("%s: %w", filepath.Join(dir, ".env"), ErrSourceNotFound)
+		}
+
+		values := make(map[string]dotEnvValue)
+		for _, layer := range []map[string]dotEnvValue{base, specific, local, specificLocal} {
+			for key, value := range layer {
+				values[key] = value
+			}
+		}
+		resolved, err := resolveDotEnv(values)
+		if err != nil {
+			return err
+		}
+		config.dotenv = resolved
+		return nil
+	}
+}
+
+func readDotEnv(config *Config, dir, name string) (map[string]dotEnvValue, error) {
+	filename := filepath.Join(dir, name)
+	config.watchFile(filename)
+	entries, err := dotenv.ParseFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil // Fichier optionnel
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]dotEnvValue, len(entries))
+	for _, entry := range entries {
+		values[entry.Key] = dotEnvValue{Entry: entry, filename: filename}
+	}
+	return values, nil
+}
+
+// resolveDotEnv interpolates the values, which may reference each other and
+// the process environment, and returns the ones the process does not set
+func resolveDotEnv(values map[string]dotEnvValue) (map[string]string, error) {
+	resolved := make(map[string]string)
+	var stack []string
+
+	var resolve utils.Resolver
+	resolve = func(name string) (string, bool, error) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true, nil
+		}
+		entry, ok := values[name]
+		if !ok {
+			return "", false, nil
+		}
+		if value, ok := resolved[name]; ok {
+			return value, true, nil
+		}
+		if !entry.Expand {
+			resolved[name] = entry.Value
+			return entry.Value, true, nil
+		}
+		for i, pending := range stack {
+			if pending == name {
+				cycle := append(append([]string{}, stack[i:]...), name)
+				return "", false, &utils.InterpolationError{Variable: name, Cycle: cycle}
+			}
+		}
+
+		stack = append(stack, name)
+		value, err := utils.Interpolate(entry.Value, resolve)
+		stack = stack[:len(stack)-1]
+		if err != nil {
+			return "", false, err
+		}
+		resolved[name] = value
+		return value, true, nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make(map[string]string)
+	for _, key := range keys {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		value, _, err := resolve(key)
+		if err != nil {
+			entry := values[key]
+			return nil, fmt.Errorf("%s:%d: %s: %w", entry.filename, entry.Line, key, err)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
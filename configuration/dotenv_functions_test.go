@@ -0,0 +1,143 @@
+package configuration
+
+import (
+	"errors"
+	"goserve/configuration/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadDotEnv(t *testing.T, files map[string]string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := &Config{}
+	config.Server.setDefaults()
+	return config, loadFromDotEnvFiles(dir)(config)
+}
+
+// unsetenv removes name from the process for the duration of the test
+func unsetenv(t *testing.T, name string) {
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+func TestDotEnvFiles(t *testing.T) {
+	t.Setenv("GOSERVE_TEST_HOME", "/home/test")
+	t.Setenv("GOSERVE_TEST_SET", "from process")
+	unsetenv(t, "APP_ENV")
+
+	config, err := loadDotEnv(t, map[string]string{
+		".env": `APP_ENV=staging
+NAME=base
+URL=https://${HOST}:${PORT:-443}/${NAME}
+HOST=example.com
+LITERAL='${HOST}'
+USES_LITERAL=${LITERAL}
+ESCAPED="\${HOST} and $$HOST"
+HOME_DIR=${GOSERVE_TEST_HOME}/app
+GOSERVE_TEST_SET=from file
+`,
+		".env.staging":       "NAME=staging\nPORT=8443\n",
+		".env.local":         "NAME=local\n",
+		".env.staging.local": "PORT=9443\n",
+		".env.production":    "NAME=production\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"APP_ENV":      "staging",
+		"NAME":         "local",
+		"PORT":         "9443",
+		"HOST":         "example.com",
+		"URL":          "https://example.com:9443/local",
+		"LITERAL":      "${HOST}",
+		"USES_LITERAL": "${HOST}",
+		"ESCAPED":      "${HOST} and $HOST",
+		"HOME_DIR":     "/home/test/app",
+	}
+	// .env.production is not read, .env.local overrides .env.staging
+	if !reflect.DeepEqual(config.dotenv, expected) {
+		t.Errorf("expected %v, got %v", expected, config.dotenv)
+	}
+}
+
+func TestDotEnvErrors(t *testing.T) {
+	unsetenv(t, "APP_ENV")
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+		cycle []string
+	}{
+		{
+			name:  "self reference",
+			files: map[string]string{".env": "A=${A}\n"},
+			err:   ".env:1: A: cycle in variable references: A -> A",
+			cycle: []string{"A", "A"},
+		},
+		{
+			name:  "cycle",
+			files: map[string]string{".env": "A=x${B}\nB=${C:-default}\nC=${A}\n"},
+			err:   ".env:1: A: cycle in variable references: A -> B -> C -> A",
+			cycle: []string{"A", "B", "C", "A"},
+		},
+		{
+			name:  "cycle across files",
+			files: map[string]string{".env": "A=${B}\n", ".env.local": "\nB=${A}\n"},
+			err:   "cycle in variable references: A -> B -> A",
+			cycle: []string{"A", "B", "A"},
+		},
+		{
+			name:  "undefined",
+			files: map[string]string{".env": "OK=1\nURL=${GOSERVE_TEST_UNDEFINED}\n"},
+			err:   ".env:2: URL: undefined variable ${GOSERVE_TEST_UNDEFINED}",
+		},
+		{
+			name:  "syntax",
+			files: map[string]string{".env": "OK=1\nBROKEN\n"},
+			err:   ".env: line 2: expected '=' after BROKEN",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadDotEnv(t, test.files)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected %q, got %v", test.err, err)
+			}
+			var interpolation *utils.InterpolationError
+			if test.cycle != nil && (!errors.As(err, &interpolation) || !reflect.DeepEqual(interpolation.Cycle, test.cycle)) {
+				t.Errorf("expected the cycle %v, got %v", test.cycle, err)
+			}
+		})
+	}
+
+	if _, err := loadDotEnv(t, nil); !errors.Is(err, ErrSourceNotFound) {
+		t.Errorf("expected ErrSourceNotFound without files, got %v", err)
+	}
+}
+
+// A cycle broken by a variable of the process is not an error
+func TestDotEnvProcessBreaksCycle(t *testing.T) {
+	unsetenv(t, "APP_ENV")
+	t.Setenv("GOSERVE_TEST_B", "process")
+	config, err := loadDotEnv(t, map[string]string{".env": "GOSERVE_TEST_A=${GOSERVE_TEST_B}\nGOSERVE_TEST_B=${GOSERVE_TEST_A}\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.dotenv["GOSERVE_TEST_A"] != "process" {
+		t.Errorf("unexpected values %v", config.dotenv)
+	}
+	if _, ok := config.dotenv["GOSERVE_TEST_B"]; ok {
+		t.Error("variables of the process must not be overridden")
+	}
+}
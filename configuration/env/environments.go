@@ -1,5 +1,11 @@
 package env
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
 type Environment string
 
 const (
@@ -12,3 +18,126 @@ const (
 var APP_ENVIRONMENT_KEY = "APP_ENV"
 var APP_PORT_KEY = "APP_PORT"
 var APP_HOST_KEY = "APP_HOST"
+
+// Profile holds the framework defaults that depend on the environment, so
+// they are switched in one place instead of by IsDevelopment checks
+type Profile struct {
+	// Error responses include the message of unexpected errors
+	VerboseErrors bool
+	// Logs are written for humans rather than as key=value pairs
+	PrettyLogs bool
+	// The net/http/pprof handlers are served under /debug/pprof/. Off in
+	// every built-in environment, since they expose the process: enabled
+	// with APP_PPROF or by a registered environment.
+	Pprof bool
+	// The server listens with TLS on a generated self-signed certificate
+	DevTLS bool
+}
+
+// ProfileOption changes the profile of a registered environment
+type ProfileOption func(*Profile)
+
+type definition struct {
+	parent  Environment
+	profile Profile
+}
+
+var (
+	mu       sync.RWMutex
+	order    []Environment
+	registry = make(map[Environment]definition)
+)
+
+func init() {
+	define(Development, "", Profile{VerboseErrors: true, PrettyLogs: true})
+	define(Testing, "", Profile{VerboseErrors: true, PrettyLogs: true})
+	define(Staging, "", Profile{})
+	define(Production, "", Profile{})
+}
+
+func define(name, parent Environment, profile Profile) {
+	registry[name] = definition{parent: parent, profile: profile}
+	order = append(order, name)
+}
+
+// Register adds an environment extending parent: it starts with the profile
+// of parent, changed by options, and Is(parent) is true for it.
+//
+//	env.Register("preprod", env.Production)
+//	env.Register("qa", env.Staging, func(p *env.Profile) { p.VerboseErrors = true })
+func Register(name, parent Environment, options ...ProfileOption) error {
+	name = Environment(strings.ToLower(strings.TrimSpace(string(name))))
+	if name == "" {
+		return fmt.Errorf("environment name is empty")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := registry[name]; exists {
+		return fmt.Errorf("environment %q is already registered", name)
+	}
+	parentDefinition, ok := registry[parent]
+	if !ok {
+		return fmt.Errorf("environment %q extends unknown environment %q", name, parent)
+	}
+
+	profile := parentDefinition.profile
+	for _, option := range options {
+		option(&profile)
+	}
+	define(name, parent, profile)
+	return nil
+}
+
+// Registered returns the known environments in registration order
+func Registered() []Environment {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Environment{}, order...)
+}
+
+// IsRegistered tells whether e is a built-in or registered environment
+func (e Environment) IsRegistered() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := registry[e]
+	return ok
+}
+
+// Is tells whether e is target or extends it, directly or not
+func (e Environment) Is(target Environment) bool {
+	for _, current := range e.Chain() {
+		if current == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain returns the environments e extends, from the root one to e itself:
+// [production preprod] for preprod extending production
+func (e Environment) Chain() []Environment {
+	mu.RLock()
+	defer mu.RUnlock()
+	var chain []Environment
+	for current := e; current != ""; current = registry[current].parent {
+		chain = append([]Environment{current}, chain...)
+		if _, ok := registry[current]; !ok {
+			break
+		}
+	}
+	return chain
+}
+
+// Profile returns the profile of e, the zero one when e is unknown
+func (e Environment) Profile() Profile {
+	mu.RLock()
+	defer mu.RUnlock()
+	return registry[e].profile
+}
+
+// UnmarshalText lets the environment be bound from APP_ENV case-insensitively
+func (e *Environment) UnmarshalText(text []byte) error {
+	*e = Environment(strings.ToLower(strings.TrimSpace(string(text))))
+	return nil
+}
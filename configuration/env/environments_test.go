@@ -0,0 +1,109 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The registry is global: every test registers names of its own
+
+func TestRegister(t *testing.T) {
+	if err := Register(" PreProd ", Production); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("preprod-eu", "preprod", func(p *Profile) { p.Pprof = true }); err != nil {
+		t.Fatal(err)
+	}
+
+	preprod := Environment("preprod-eu")
+	if !preprod.IsRegistered() || !Environment("preprod").IsRegistered() {
+		t.Errorf("expected preprod and preprod-eu in %v", Registered())
+	}
+	expected := []Environment{Production, "preprod", "preprod-eu"}
+	if chain := preprod.Chain(); !reflect.DeepEqual(chain, expected) {
+		t.Errorf("expected %v, got %v", expected, chain)
+	}
+	if !preprod.Is(Production) || !preprod.Is("preprod") || preprod.Is(Staging) {
+		t.Errorf("unexpected ancestry of %s", preprod)
+	}
+	if registered := Registered(); registered[len(registered)-1] != preprod {
+		t.Errorf("expected the registration order, got %v", registered)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	if err := Register("duplicated", Staging); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, parent Environment
+		expected     string
+	}{
+		{" ", Production, "environment name is empty"},
+		{"duplicated", Staging, `environment "duplicated" is already registered`},
+		{"Production", Staging, `environment "production" is already registered`},
+		{"orphan", "nowhere", `environment "orphan" extends unknown environment "nowhere"`},
+		{"rootless", "", `environment "rootless" extends unknown environment ""`},
+	}
+	for _, test := range tests {
+		t.Run(string(test.name), func(t *testing.T) {
+			err := Register(test.name, test.parent)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected %q, got %v", test.expected, err)
+			}
+		})
+	}
+	if Environment("orphan").IsRegistered() {
+		t.Error("expected a failed registration to leave the registry unchanged")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	if err := Register("qa", Staging, func(p *Profile) { p.VerboseErrors = true }); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("qa-local", "qa", func(p *Profile) { p.DevTLS = true }, func(p *Profile) { p.PrettyLogs = true }); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		environment Environment
+		expected    Profile
+	}{
+		{Development, Profile{VerboseErrors: true, PrettyLogs: true}},
+		{Testing, Profile{VerboseErrors: true, PrettyLogs: true}},
+		{Staging, Profile{}},
+		{Production, Profile{}},
+		{"qa", Profile{VerboseErrors: true}},
+		{"qa-local", Profile{VerboseErrors: true, PrettyLogs: true, DevTLS: true}},
+		{"unknown", Profile{}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.environment), func(t *testing.T) {
+			if profile := test.environment.Profile(); profile != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, profile)
+			}
+		})
+	}
+	if Staging.Profile().VerboseErrors {
+		t.Error("expected the options to leave the parent profile unchanged")
+	}
+}
+
+func TestUnknownEnvironment(t *testing.T) {
+	unknown := Environment("nowhere")
+	if unknown.IsRegistered() || unknown.Is(Production) {
+		t.Errorf("unexpected registration of %s", unknown)
+	}
+	if chain := unknown.Chain(); !reflect.DeepEqual(chain, []Environment{unknown}) {
+		t.Errorf("expected [nowhere], got %v", chain)
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	var e Environment
+	if err := e.UnmarshalText([]byte(" Production\n")); err != nil || e != Production {
+		t.Errorf("expected production, got %q (%v)", e, err)
+	}
+}
@@ -0,0 +1,101 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"goserve/configuration/env"
+	"goserve/configuration/toml"
+	"goserve/configuration/yaml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions tried, in order, for config.{env}.* files
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// loadFromFile picks the format from the file extension, JSON by default
+func loadFromFile(filename string) func(*Config) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return loadFromYAMLFile(filename)
+	case ".toml":
+		return loadFromTOMLFile(filename)
+	default:
+		return loadFromJSONFile(filename)
+	}
+}
+
+func loadFromYAMLFile(filename string) func(*Config) error {
+	return loadFromDocumentFile(filename, "YAML", yaml.ParseMap)
+}
+
+func loadFromTOMLFile(filename string) func(*Config) error {
+	return loadFromDocumentFile(filename, "TOML", toml.Parse)
+}
+
+// loadFromDocumentFile parses a file into a generic document, expands its
+// ${VAR} references and merges it into the Config, so every format shares
+// the same keys and merge rules
+func loadFromDocumentFile(filename, format string, parse func([]byte) (map[string]interface{}, error)) func(*Config) error {
+	return func(config *Config) error {
+		config.watchFile(filename)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", filename, ErrSourceNotFound)
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return &SourceError{Source: filename, Err: fmt.Errorf("impossible de lire le fichier: %v", err)}
+		}
+
+		document, err := parse(data)
+		if err != nil {
+			return &SourceError{Source: filename, Err: fmt.Errorf("%s invalide: %v", format, err)}
+		}
+		expanded, err := interpolateDocument(config, document, "")
+		if err != nil {
+			return &SourceError{Source: filename, Err: fmt.Errorf("variable invalide: %w", err)}
+		}
+		decrypted, err := decryptDocument(config, expanded, "")
+		if err != nil {
+			return &SourceError{Source: filename, Err: fmt.Errorf("secret invalide: %w", err)}
+		}
+		if err := mergeDocument(config, decrypted.(map[string]interface{}), "file "+filename); err != nil {
+			return &SourceError{Source: filename, Err: fmt.Errorf("%s invalide: %v", format, err)}
+		}
+		return nil
+	}
+}
+
+// loadFromEnvSpecificFiles loads config.{env}.json, .yaml, .yml and .toml,
+// in that order, when they exist. An environment extending another one
+// loads the files of its parents first: config.production.* then
+// config.preprod.* for preprod.
+func loadFromEnvSpecificFiles() func(*Config) error {
+	return func(config *Config) error {
+		envValue := string(config.Server.Environment)
+		if envVar, _ := config.LookupEnv(env.APP_ENVIRONMENT_KEY); envVar != "" {
+			envValue = strings.ToLower(envVar)
+		}
+
+		found := false
+		for _, environment := range env.Environment(envValue).Chain() {
+			for _, extension := range configExtensions {
+				filename := fmt.Sprintf("config.%s%s", environment, extension)
+				err := loadFromFile(filename)(config)
+				if errors.Is(err, ErrSourceNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("config.%s.*: %w", envValue, ErrSourceNotFound)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,366 @@
+package configuration
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"goserve/configuration/utils"
+	"goserve/internal/naming"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Flags derives command-line flags from the server configuration and from
+// registered struct sections, and overrides every other source:
+//
+//	flags := configuration.NewFlags("myapp").Section("app.database", &DatabaseConfig{})
+//	if err := flags.Parse(os.Args[1:]); err != nil { ... }
+//	cfg, err := configuration.New().AddSource(flags.Source()).LoadConfig()
+//
+// Server fields become --server.port, --server.read-timeout, ... Keys under
+// app. and custom. are accepted even when no section declares them:
+// --app.feature-x alone sets true.
+type Flags struct {
+	name    string
+	output  io.Writer
+	options []*flagOption
+	byName  map[string]*flagOption
+
+	parsed bool
+	set    []flagValue
+	args   []string
+}
+
+type flagOption struct {
+	name     string
+	root     string
+	keys     []string
+	index    []int
+	typ      reflect.Type
+	defValue string
+	envVar   string
+	usage    string
+}
+
+type flagValue struct {
+	option *flagOption
+	// Root and keys for undeclared app. and custom. flags
+	root  string
+	keys  []string
+	raw   string
+	value interface{}
+}
+
+// FlagsPriority is the priority of the flags source, above the files and
+// the environment variables
+const FlagsPriority = 10
+
+func NewFlags(name string) *Flags {
+	f := &Flags{
+		name:   name,
+		output: os.Stderr,
+		byName: make(map[string]*flagOption),
+	}
+	f.declare("server", nil, nil, nil, reflect.TypeOf(ServeurConfiguration{}), reflect.Value{})
+	return f
+}
+
+// SetOutput changes where the help is printed (default: os.Stderr)
+func (f *Flags) SetOutput(w io.Writer) *Flags {
+	f.output = w
+	return f
+}
+
+// Section declares the flags of a struct stored under prefix, which starts
+// with app or custom. The current field values are shown as defaults.
+func (f *Flags) Section(prefix string, section interface{}) *Flags {
+	value := reflect.ValueOf(section)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("configuration: section %s must be a pointer to a struct", prefix))
+	}
+	parts := strings.Split(prefix, ".")
+	if parts[0] != "app" && parts[0] != "custom" {
+		panic(fmt.Sprintf("configuration: section %s must be under app or custom", prefix))
+	}
+	f.declare(parts[0], parts[1:], parts[1:], nil, value.Elem().Type(), value.Elem())
+	return f
+}
+
+func (f *Flags) declare(root string, names, keys []string, index []int, typ reflect.Type, defaults reflect.Value) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _ := field.Tag.Lookup("flag")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = naming.Kebab(field.Name)
+		}
+
+		// Section values are stored in maps, under their JSON name
+		key := name
+		if jsonName := strings.Split(field.Tag.Get("json"), ",")[0]; jsonName != "" && jsonName != "-" && root != "server" {
+			key = jsonName
+		}
+		fieldNames := append(append([]string{}, names...), name)
+		fieldKeys := append(append([]string{}, keys...), key)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		var fieldDefault reflect.Value
+		if defaults.IsValid() {
+			fieldDefault = defaults.Field(i)
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Struct && !reflect.PointerTo(fieldType).Implements(textUnmarshalerType) {
+			f.declare(root, fieldNames, fieldKeys, fieldIndex, fieldType, fieldDefault)
+			continue
+		}
+
+		option := &flagOption{
+			name:   root + "." + strings.Join(fieldNames, "."),
+			root:   root,
+			keys:   fieldKeys,
+			index:  fieldIndex,
+			typ:    fieldType,
+			envVar: field.Tag.Get("env"),
+			usage:  field.Tag.Get("usage"),
+		}
+		def, hasDefault := field.Tag.Lookup("default")
+		switch {
+		case isSecretField(field):
+			// Secret defaults are never shown in the help
+		case hasDefault:
+			option.defValue = def
+		case fieldDefault.IsValid() && !fieldDefault.IsZero():
+			option.defValue = fmt.Sprint(fieldDefault.Interface())
+		}
+		if _, exists := f.byName[option.name]; exists {
+			panic(fmt.Sprintf("configuration: flag --%s declared twice", option.name))
+		}
+		f.options = append(f.options, option)
+		f.byName[option.name] = option
+	}
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Parse reads the flags from args. --help prints the usage and returns
+// flag.ErrHelp. Arguments after -- or that are not flags are kept in Args.
+func (f *Flags) Parse(args []string) error {
+	f.parsed = true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			f.args = append(f.args, args[i+1:]...)
+			return nil
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			f.args = append(f.args, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		raw, hasValue := "", false
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name, raw, hasValue = name[:eq], name[eq+1:], true
+		}
+		if name == "h" || name == "help" {
+			f.Usage()
+			return flag.ErrHelp
+		}
+
+		option, declared := f.byName[name]
+		if !declared {
+			root, rest, _ := strings.Cut(name, ".")
+			if (root != "app" && root != "custom") || rest == "" {
+				return fmt.Errorf("unknown flag --%s", name)
+			}
+			if !hasValue {
+				raw = "true"
+			}
+			f.set = append(f.set, flagValue{root: root, keys: strings.Split(rest, "."), raw: raw, value: typedScalar(raw)})
+			continue
+		}
+
+		if !hasValue {
+			if option.typ.Kind() == reflect.Bool {
+				raw = "true"
+			} else if i+1 < len(args) {
+				i++
+				raw = args[i]
+			} else {
+				return fmt.Errorf("flag --%s needs a value", name)
+			}
+		}
+
+		value := reflect.New(option.typ).Elem()
+		if err := utils.SetField(value, raw); err != nil {
+			return fmt.Errorf("invalid value %q for flag --%s: %v", raw, name, err)
+		}
+		f.set = append(f.set, flagValue{option: option, raw: raw, value: sectionValue(value, raw)})
+	}
+	return nil
+}
+
+// sectionValue keeps plain kinds typed in the maps and the raw text for the
+// others (durations, text unmarshalers, ...) so sections decode them later
+func sectionValue(value reflect.Value, raw string) interface{} {
+	if value.Type().PkgPath() != "" {
+		return raw
+	}
+	switch value.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Interface()
+	case reflect.Slice:
+		// Stored like the lists of a document
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = value.Index(i).Interface()
+		}
+		return items
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return raw
+		}
+		values := make(map[string]interface{}, value.Len())
+		for _, key := range value.MapKeys() {
+			values[key.String()] = value.MapIndex(key).Interface()
+		}
+		return values
+	}
+	return raw
+}
+
+func (v flagValue) name() string {
+	if v.option != nil {
+		return v.option.name
+	}
+	return v.root + "." + strings.Join(v.keys, ".")
+}
+
+// Args returns the arguments that are not flags
+func (f *Flags) Args() []string {
+	return f.args
+}
+
+// Usage prints every flag with its default and environment variable
+func (f *Flags) Usage() {
+	fmt.Fprintf(f.output, "Usage of %s:\n", f.name)
+	w := tabwriter.NewWriter(f.output, 0, 0, 2, ' ', 0)
+
+	options := append([]*flagOption{}, f.options...)
+	sort.SliceStable(options, func(i, j int) bool {
+		return rootOrder(options[i].root) < rootOrder(options[j].root)
+	})
+	for _, option := range options {
+		var details []string
+		if option.defValue != "" {
+			details = append(details, fmt.Sprintf("default %q", option.defValue))
+		}
+		if option.envVar != "" {
+			details = append(details, "env "+option.envVar)
+		}
+		description := option.usage
+		if len(details) > 0 {
+			description = strings.TrimSpace(fmt.Sprintf("%s (%s)", description, strings.Join(details, ", ")))
+		}
+		fmt.Fprintf(w, "  --%s %s\t%s\n", option.name, typeName(option.typ), description)
+	}
+	fmt.Fprintf(w, "  --app.<key>[=value]\tany application setting, true when the value is omitted\n")
+	fmt.Fprintf(w, "  --custom.<key>[=value]\tany custom setting, true when the value is omitted\n")
+	fmt.Fprintf(w, "  -h, --help\tshow this help\n")
+	w.Flush()
+}
+
+func rootOrder(root string) int {
+	return map[string]int{"server": 0, "app": 1, "custom": 2}[root]
+}
+
+func typeName(typ reflect.Type) string {
+	switch {
+	case typ == durationType:
+		return "duration"
+	case typ.Kind() == reflect.Bool:
+		return ""
+	case typ.Kind() == reflect.Slice:
+		return "list"
+	case typ.Kind() == reflect.Map:
+		return "key=value,..."
+	}
+	return typ.Kind().String()
+}
+
+// Source returns the configuration source applying the parsed flags
+func (f *Flags) Source() ConfigurationSource {
+	return ConfigurationSource{
+		Filename: "command-line flags",
+		Priority: FlagsPriority,
+		Load:     f.load,
+	}
+}
+
+func (f *Flags) load(config *Config) error {
+	if !f.parsed {
+		return fmt.Errorf("command-line flags were not parsed")
+	}
+	for _, set := range f.set {
+		root, keys := set.root, set.keys
+		if set.option != nil {
+			root, keys = set.option.root, set.option.keys
+		}
+
+		switch root {
+		case "server":
+			field := reflect.ValueOf(&config.Server).Elem().FieldByIndex(set.option.index)
+			if err := utils.SetField(field, set.raw); err != nil {
+				return fmt.Errorf("--%s: %v", set.option.name, err)
+			}
+		case "app":
+			setPath(config.App, keys, set.value)
+		case "custom":
+			setPath(config.Custom, keys, set.value)
+		}
+		config.setOrigin(root+"."+strings.Join(keys, "."), "flag --"+set.name())
+	}
+	return nil
+}
+
+// flagged returns the values below path set by flags, relative to path
+func (c *Config) flagged(path string) map[string]interface{} {
+	prefix := normalizePath(path) + "."
+	depth := strings.Count(path, ".") + 1
+	values := make(map[string]interface{})
+	for key, origin := range c.origins {
+		if !strings.HasPrefix(origin, "flag ") || !strings.HasPrefix(normalizePath(key), prefix) {
+			continue
+		}
+		if value, ok := c.Get(key); ok {
+			setPath(values, strings.Split(key, ".")[depth:], value)
+		}
+	}
+	return values
+}
+
+// setPath stores value in nested maps, creating the missing levels
+func setPath(target map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := target[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			target[key] = child
+		}
+		target = child
+	}
+	target[keys[len(keys)-1]] = value
+}
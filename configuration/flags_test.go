@@ -0,0 +1,222 @@
+package configuration
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type database struct {
+	Host     string            `json:"host" usage:"database host"`
+	Port     int               `json:"port" default:"5432" env:"DB_PORT"`
+	Timeout  time.Duration     `json:"timeout"`
+	Debug    bool              `json:"debug"`
+	Replicas []string          `json:"replicas"`
+	Options  map[string]string `json:"options"`
+	Bind     netip.Addr        `json:"bind"`
+	Password Secret            `json:"password" default:"hunter2"`
+	Pool     struct {
+		MaxOpen int `json:"max_open" flag:"max-open"`
+	} `json:"pool"`
+}
+
+func TestTypedFlags(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	writeFile(t, filename, "app:\n  database:\n    host: file.internal\n    port: 6543\n")
+
+	var db database
+	flags := NewFlags("orders").Section("app.database", &db)
+	err := flags.Parse([]string{
+		"--server.port", "9090",
+		"--server.read-timeout=30",
+		"--app.database.host", "db.internal",
+		"--app.database.timeout=5s",
+		"--app.database.debug",
+		"--app.database.replicas", "a,b",
+		"--app.database.options", "sslmode=disable",
+		"--app.database.bind", "10.0.0.1",
+		"--app.database.pool.max-open=20",
+		"--app.feature-x",
+		"--custom.limit=3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := New().Section("app.database", &db).AddSource(flags.Source()).LoadConfigFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetPort() != 9090 || config.GetReadTimeout() != 30 {
+		t.Errorf("expected port 9090 and read timeout 30, got %d and %d", config.GetPort(), config.GetReadTimeout())
+	}
+	if db.Host != "db.internal" || db.Port != 6543 || db.Timeout != 5*time.Second || !db.Debug {
+		t.Errorf("unexpected section %+v", db)
+	}
+	if !reflect.DeepEqual(db.Replicas, []string{"a", "b"}) || db.Options["sslmode"] != "disable" {
+		t.Errorf("unexpected replicas %v or options %v", db.Replicas, db.Options)
+	}
+	if db.Bind != netip.MustParseAddr("10.0.0.1") || db.Pool.MaxOpen != 20 {
+		t.Errorf("unexpected bind %v or pool %+v", db.Bind, db.Pool)
+	}
+	if !config.GetBool("app.feature-x") || config.GetInt("custom.limit") != 3 {
+		t.Errorf("expected the undeclared flags in app and custom, got %v", config.Dump())
+	}
+
+	for key, expected := range map[string]string{
+		"server.port":          "flag --server.port",
+		"server.read-timeout":  "flag --server.read-timeout",
+		"app.database.timeout": "flag --app.database.timeout",
+		"app.database.port":    "file " + filename,
+		"app.feature-x":        "flag --app.feature-x",
+	} {
+		if origin, _ := config.Origin(key); origin != expected {
+			t.Errorf("expected origin %q for %s, got %q", expected, key, origin)
+		}
+	}
+}
+
+func TestFlagsOverrideEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	writeFile(t, filename, "server:\n  port: 7000\napp:\n  database:\n    port: 6543\n")
+	t.Setenv("APP_PORT", "7070")
+	t.Setenv("DB_PORT", "7432")
+
+	// Env tags of sections are applied to the section only, not to the map
+	load := func(args ...string) (Configuration, database) {
+		t.Helper()
+		var db database
+		flags := NewFlags("orders").Section("app.database", &db)
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		config, err := New().Section("app.database", &db).AddSource(flags.Source()).LoadConfigFromFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return config, db
+	}
+
+	config, db := load()
+	if config.GetPort() != 7070 || db.Port != 7432 {
+		t.Errorf("expected the environment to override the file, got %d and %d", config.GetPort(), db.Port)
+	}
+
+	config, db = load("--server.port=9090", "--app.database.port=9432")
+	if config.GetPort() != 9090 || db.Port != 9432 {
+		t.Errorf("expected the flags to override the environment, got %d and %d", config.GetPort(), db.Port)
+	}
+	if origin, _ := config.Origin("server.port"); origin != "flag --server.port" {
+		t.Errorf("unexpected origin %q", origin)
+	}
+}
+
+func TestFlagErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--port=80"}, "unknown flag --port"},
+		{[]string{"--server.unknown=1"}, "unknown flag --server.unknown"},
+		{[]string{"--app"}, "unknown flag --app"},
+		{[]string{"--server.port"}, "flag --server.port needs a value"},
+		{[]string{"--server.port", "http"}, `invalid value "http" for flag --server.port`},
+		{[]string{"--app.database.timeout=soon"}, `invalid value "soon" for flag --app.database.timeout`},
+		{[]string{"--app.database.bind=localhost"}, `invalid value "localhost" for flag --app.database.bind`},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			err := NewFlags("orders").Section("app.database", &database{}).Parse(test.args)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected %q, got %v", test.expected, err)
+			}
+		})
+	}
+
+	_, err := New().AddSource(NewFlags("orders").Source()).Load()
+	if err == nil || !strings.Contains(err.Error(), "command-line flags were not parsed") {
+		t.Errorf("expected unparsed flags to fail Load, got %v", err)
+	}
+}
+
+func TestFlagArgs(t *testing.T) {
+	flags := NewFlags("orders")
+	if err := flags.Parse([]string{"input.json", "--server.port=9090", "-", "--", "--server.host", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"input.json", "-", "--server.host", "x"}
+	if !reflect.DeepEqual(flags.Args(), expected) {
+		t.Errorf("expected %q, got %q", expected, flags.Args())
+	}
+}
+
+func TestFlagsHelp(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewFlags("orders").SetOutput(&buf).Section("app.database", &database{Host: "localhost"}).Parse([]string{"--server.port=1", "--help"})
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("expected flag.ErrHelp, got %v", err)
+	}
+
+	help := buf.String()
+	for _, expected := range []string{
+		"Usage of orders:",
+		"--server.port int",
+		`HTTP port (default "8080", env APP_PORT)`,
+		"--server.read-timeout int",
+		"--server.pprof ",
+		"--app.database.host string",
+		`database host (default "localhost")`,
+		`(default "5432", env DB_PORT)`,
+		"--app.database.timeout duration",
+		"--app.database.replicas list",
+		"--app.database.options key=value,...",
+		"--app.database.pool.max-open int",
+		"--app.database.password string",
+		"--app.<key>[=value]",
+		"--custom.<key>[=value]",
+		"-h, --help",
+	} {
+		if !strings.Contains(help, expected) {
+			t.Errorf("expected %q in %q", expected, help)
+		}
+	}
+	if strings.Contains(help, "hunter2") {
+		t.Errorf("expected the secret default to be hidden, got %q", help)
+	}
+	if strings.Index(help, "--server.port") > strings.Index(help, "--app.database.host") {
+		t.Errorf("expected the server flags first, got %q", help)
+	}
+}
+
+func TestFlagSectionPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		declare  func()
+		expected string
+	}{
+		{"not a pointer", func() { NewFlags("orders").Section("app.database", database{}) }, "must be a pointer to a struct"},
+		{"outside app and custom", func() { NewFlags("orders").Section("server.database", &database{}) }, "must be under app or custom"},
+		{"declared twice", func() {
+			NewFlags("orders").Section("app.database", &database{}).Section("app.database", &database{})
+		}, "flag --app.database.host declared twice"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				recovered := recover()
+				if message, _ := recovered.(string); !strings.Contains(message, test.expected) {
+					t.Errorf("expected a panic with %q, got %v", test.expected, recovered)
+				}
+			}()
+			test.declare()
+		})
+	}
+}
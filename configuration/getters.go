@@ -0,0 +1,97 @@
+package configuration
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Get returns the value at a dot path: "server.port", "app.database.host" or
+// "custom.feature". Keys match ignoring case, dashes and underscores.
+func (c *Config) Get(path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	var current interface{}
+	switch normalizeKey(keys[0]) {
+	case "server":
+		current = serverValues(&c.Server)
+	case "app":
+		current = c.App
+	case "custom":
+		current = c.Custom
+	default:
+		return nil, false
+	}
+
+	for _, key := range keys[1:] {
+		values, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if _, current, ok = lookupKey(values, key); !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// serverValues exposes the server settings as a section
+func serverValues(server *ServeurConfiguration) map[string]interface{} {
+	value := reflect.ValueOf(server).Elem()
+	values := make(map[string]interface{}, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		values[value.Type().Field(i).Name] = value.Field(i).Interface()
+	}
+	return values
+}
+
+// getAs decodes the value at path into target, leaving it unchanged when the
+// path is missing or has an incompatible type
+func (c *Config) getAs(path string, target interface{}) {
+	raw, ok := c.Get(path)
+	if !ok {
+		return
+	}
+	value := reflect.ValueOf(target).Elem()
+	decoded := reflect.New(value.Type()).Elem()
+	if decodeValue(raw, decoded, path) == nil {
+		value.Set(decoded)
+	}
+}
+
+func (c *Config) GetString(path string) string {
+	var value string
+	c.getAs(path, &value)
+	return value
+}
+
+func (c *Config) GetInt(path string) int {
+	var value int
+	c.getAs(path, &value)
+	return value
+}
+
+func (c *Config) GetFloat(path string) float64 {
+	var value float64
+	c.getAs(path, &value)
+	return value
+}
+
+func (c *Config) GetBool(path string) bool {
+	var value bool
+	c.getAs(path, &value)
+	return value
+}
+
+// GetDuration reads Go durations ("1m30s") and numbers of seconds
+func (c *Config) GetDuration(path string) time.Duration {
+	var value time.Duration
+	c.getAs(path, &value)
+	return value
+}
+
+// GetStringSlice reads lists and comma separated strings
+func (c *Config) GetStringSlice(path string) []string {
+	var value []string
+	c.getAs(path, &value)
+	return value
+}
@@ -1,17 +1,52 @@
 package configuration
 
-import "goserve/configuration/env"
+import (
+	"goserve/configuration/env"
+	"time"
+)
 
 type ConfigurationBuilder interface {
 	// Add source configuration
-	// File source could be JSON, YAML, etc.
-	// The priority determines the order of loading (0 = highest, 10 = lowest)
+	// File source could be JSON, YAML, TOML, .env, flags, etc.
+	// Sources are applied by ascending priority (any integer): a higher
+	// priority overrides a lower one and ties keep their insertion order
 	AddSource(source ConfigurationSource) ConfigurationBuilder
 	// Load and return the final configuration
 	Load(options ...ConfigOption) (Configuration, error)
+	// Register a struct bound from the section at path, e.g. "app.database",
+	// when loading; a section that fails to decode or validate fails Load
+	Section(path string, target interface{}) ConfigurationBuilder
+	// Fail Load on unknown keys as well as on source errors
+	WithStrictMode(strict bool) ConfigurationBuilder
+	// Read the AES-GCM key decrypting "enc:" values from filename (default:
+	// the file named by APP_SECRET_KEY_FILE)
+	WithSecretKeyFile(filename string) ConfigurationBuilder
+	// Choose how lists under path ("" for all) combine across sources
+	WithListStrategy(path string, strategy ListStrategy) ConfigurationBuilder
+	// Reload runs the load pipeline again and swaps the configuration when
+	// the new one is valid; an invalid one is rejected and the current kept
+	Reload() error
+	// Current returns the last valid configuration
+	Current() Configuration
+	// Subscribe to reloads that changed at least one setting
+	Watch(fn func(old, new Configuration)) ConfigurationBuilder
+	// Subscribe to changes of the value at a dot path
+	OnChange(path string, fn func(old, new interface{})) ConfigurationBuilder
+	// Reload when a watched file or a remote source changes (polled every
+	// interval) or on SIGHUP, until stop is called
+	StartWatching(interval time.Duration) (stop func())
+	// Describe the configuration files, including the registered sections,
+	// as a JSON Schema
+	Schema() map[string]interface{}
+	// Document every setting of Schema as markdown
+	Reference() string
+	// Check a configuration file against Schema, reporting every problem
+	// with its line as ValidationErrors
+	Validate(filename string) error
 	// Convenience method to load configuration from default files
 	LoadConfig(options ...ConfigOption) (Configuration, error)
-	// Convenience method to load configuration from a specific file
+	// Convenience method to load configuration from a specific file, the format
+	// is detected from the extension (.json, .yaml, .yml or .toml)
 	LoadConfigFromFile(path string, options ...ConfigOption) (Configuration, error)
 }
 
@@ -29,12 +64,36 @@ type Configuration interface {
 	// Retrieve the server idle timeout in seconds
 	GetIdleTimeout() int
 
-	// Check if the environment is development
+	// Retrieve the raw value at a dot path such as "app.database.host"
+	Get(path string) (interface{}, bool)
+	// Retrieve the value at a dot path converted to the requested type, or
+	// the zero value when it is missing or cannot be converted
+	GetString(path string) string
+	GetInt(path string) int
+	GetFloat(path string) float64
+	GetBool(path string) bool
+	GetDuration(path string) time.Duration
+	GetStringSlice(path string) []string
+
+	// Retrieve the source that set the value at path, e.g. "env APP_PORT"
+	Origin(path string) (string, bool)
+	// Retrieve every setting with the secrets redacted, for logs and admin
+	// endpoints
+	Dump() map[string]interface{}
+
+	// Retrieve a variable of the process environment, or else of the .env
+	// files, which are not exported to the process
+	LookupEnv(name string) (string, bool)
+
+	// Retrieve the framework defaults of the environment
+	GetProfile() env.Profile
+
+	// Check if the environment is development, or extends it
 	IsDevelopment() bool
-	// Check if the environment is staging
+	// Check if the environment is staging, or extends it
 	IsStaging() bool
-	// Check if the environment is production
+	// Check if the environment is production, or extends it
 	IsProduction() bool
-	// Check if the environment is testing
+	// Check if the environment is testing, or extends it
 	IsTesting() bool
 }
@@ -0,0 +1,62 @@
+package configuration
+
+import (
+	"fmt"
+	"goserve/configuration/utils"
+	"strconv"
+)
+
+// interpolateDocument expands ${VAR} and ${VAR:-default} in every string of
+// a parsed configuration file, resolved from the environment of config. A
+// value made of a single reference takes the type of its result, so
+// `port: ${APP_PORT}` decodes into an int.
+func interpolateDocument(config *Config, value interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			expanded, err := interpolateDocument(config, child, joinKey(path, key))
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []interface{}:
+		for i, child := range v {
+			expanded, err := interpolateDocument(config, child, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	case string:
+		expanded, err := utils.Interpolate(v, config.resolveEnv)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if utils.IsPlaceholder(v) {
+			return typedScalar(expanded), nil
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
+
+func typedScalar(text string) interface{} {
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(text); err == nil {
+		return b
+	}
+	return text
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
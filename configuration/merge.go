@@ -0,0 +1,258 @@
+package configuration
+
+import (
+	"fmt"
+	"goserve/internal/naming"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ListStrategy tells how a list from a later source combines with the
+// current one
+type ListStrategy int
+
+const (
+	// ListReplace keeps only the list of the last source (default)
+	ListReplace ListStrategy = iota
+	// ListAppend adds the items of the later source after the current ones
+	ListAppend
+)
+
+// Origin describing values set by the tag defaults
+const originDefault = "default"
+
+// mergeDocument merges a parsed configuration document into config:
+//   - server keys set the matching fields, even to their zero value
+//   - app and custom maps are merged recursively
+//   - null removes a key, or restores the default of a server field
+//   - lists are replaced or appended depending on their ListStrategy
+//
+// Every key set is recorded with origin.
+func mergeDocument(config *Config, document map[string]interface{}, origin string) error {
+	for key, value := range document {
+		switch normalizeKey(key) {
+		case "server":
+			if value == nil {
+				config.Server.setDefaults()
+				config.dropOrigins("server")
+				config.recordDefaults()
+				continue
+			}
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("server: expected a section, got %T", value)
+			}
+			if err := mergeServer(config, values, origin); err != nil {
+				return err
+			}
+		case "app":
+			if err := mergeSection(config, &config.App, "app", value, origin); err != nil {
+				return err
+			}
+		case "custom":
+			if err := mergeSection(config, &config.Custom, "custom", value, origin); err != nil {
+				return err
+			}
+		default:
+			config.unknownKeys = append(config.unknownKeys, fmt.Sprintf("%s (%s)", key, origin))
+		}
+	}
+	return nil
+}
+
+func mergeServer(config *Config, values map[string]interface{}, origin string) error {
+	server := reflect.ValueOf(&config.Server).Elem()
+	typ := server.Type()
+	for key := range values {
+		if _, ok := typ.FieldByNameFunc(func(name string) bool { return normalizeKey(name) == normalizeKey(key) }); !ok {
+			config.unknownKeys = append(config.unknownKeys, fmt.Sprintf("server.%s (%s)", key, origin))
+		}
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		_, raw, ok := lookupKey(values, field.Name)
+		if !ok {
+			continue
+		}
+		path := serverPath(field.Name)
+
+		if raw == nil {
+			// Restore the default, or the zero value without one
+			defaults := ServeurConfiguration{}
+			defaults.setDefaults()
+			server.Field(i).Set(reflect.ValueOf(defaults).Field(i))
+			config.dropOrigins(path)
+			if _, hasDefault := field.Tag.Lookup("default"); hasDefault {
+				config.setOrigin(path, originDefault)
+			}
+			continue
+		}
+
+		value := reflect.New(field.Type).Elem()
+		if err := decodeValue(raw, value, path); err != nil {
+			return err
+		}
+		server.Field(i).Set(value)
+		config.setOrigin(path, origin)
+	}
+	return nil
+}
+
+func mergeSection(config *Config, target *map[string]interface{}, root string, value interface{}, origin string) error {
+	if value == nil {
+		*target = make(map[string]interface{})
+		config.dropOrigins(root)
+		return nil
+	}
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: expected a section, got %T", root, value)
+	}
+	if *target == nil {
+		*target = make(map[string]interface{})
+	}
+	mergeMaps(config, *target, values, root, origin)
+	return nil
+}
+
+func mergeMaps(config *Config, target, source map[string]interface{}, path, origin string) {
+	for key, value := range source {
+		// Keep the spelling of the first source setting the key
+		existingKey, existing, found := lookupKey(target, key)
+		if !found {
+			existingKey = key
+		}
+		keyPath := joinKey(path, existingKey)
+
+		switch v := value.(type) {
+		case nil:
+			delete(target, existingKey)
+			config.dropOrigins(keyPath)
+			continue
+		case map[string]interface{}:
+			if current, ok := existing.(map[string]interface{}); ok {
+				mergeMaps(config, current, v, keyPath, origin)
+				continue
+			}
+			config.dropOrigins(keyPath)
+			child := make(map[string]interface{}, len(v))
+			mergeMaps(config, child, v, keyPath, origin)
+			target[existingKey] = child
+			if len(v) == 0 {
+				config.setOrigin(keyPath, origin)
+			}
+			continue
+		case []interface{}:
+			if current, ok := existing.([]interface{}); ok && config.listStrategy(keyPath) == ListAppend {
+				target[existingKey] = append(append([]interface{}{}, current...), v...)
+				config.setOrigin(keyPath, origin)
+				continue
+			}
+		}
+
+		config.dropOrigins(keyPath)
+		target[existingKey] = value
+		config.setOrigin(keyPath, origin)
+	}
+}
+
+// serverPath is the key of a server field in origins and flags:
+// ReadTimeout becomes server.read-timeout
+func serverPath(field string) string {
+	return "server." + naming.Kebab(field)
+}
+
+// goPath converts a binding path such as Server.ReadTimeout into a key
+func goPath(field string) string {
+	parts := strings.Split(field, ".")
+	for i, part := range parts {
+		parts[i] = naming.Kebab(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func normalizePath(path string) string {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		parts[i] = normalizeKey(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (c *Config) setOrigin(path, origin string) {
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+	normalized := normalizePath(path)
+	for key := range c.origins {
+		if normalizePath(key) == normalized {
+			delete(c.origins, key)
+		}
+	}
+	c.origins[path] = origin
+}
+
+// dropOrigins forgets path and every key below it
+func (c *Config) dropOrigins(path string) {
+	normalized := normalizePath(path)
+	for key := range c.origins {
+		current := normalizePath(key)
+		if current == normalized || strings.HasPrefix(current, normalized+".") {
+			delete(c.origins, key)
+		}
+	}
+}
+
+// recordDefaults marks the server fields holding a tag default
+func (c *Config) recordDefaults() {
+	typ := reflect.TypeOf(c.Server)
+	for i := 0; i < typ.NumField(); i++ {
+		if _, ok := typ.Field(i).Tag.Lookup("default"); ok {
+			c.setOrigin(serverPath(typ.Field(i).Name), originDefault)
+		}
+	}
+}
+
+// Origin tells which source set the value at path, e.g. "env APP_PORT",
+// "file config.yaml" or "flag --server.port"
+func (c *Config) Origin(path string) (string, bool) {
+	normalized := normalizePath(path)
+	for key, origin := range c.origins {
+		if normalizePath(key) == normalized {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// origins sorted by key, for logging
+func (c *Config) sortedOrigins(root string) []string {
+	var keys []string
+	for key := range c.origins {
+		if strings.HasPrefix(normalizePath(key), root+".") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// listStrategy returns the strategy of path or of its closest configured
+// parent, ListReplace by default
+func (c *Config) listStrategy(path string) ListStrategy {
+	normalized := normalizePath(path)
+	for {
+		if strategy, ok := c.listStrategies[normalized]; ok {
+			return strategy
+		}
+		if normalized == "" {
+			return ListReplace
+		}
+		if i := strings.LastIndexByte(normalized, '.'); i >= 0 {
+			normalized = normalized[:i]
+		} else {
+			normalized = ""
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// source is a document merged with its origin
+type source struct {
+	origin   string
+	document string
+}
+
+func mergeSources(t *testing.T, strategies map[string]ListStrategy, sources ...source) *Config {
+	t.Helper()
+	config := &Config{
+		App:            make(map[string]interface{}),
+		Custom:         make(map[string]interface{}),
+		listStrategies: strategies,
+	}
+	config.Server.setDefaults()
+	config.recordDefaults()
+	for _, s := range sources {
+		decoder := json.NewDecoder(strings.NewReader(s.document))
+		decoder.UseNumber()
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); err != nil {
+			t.Fatal(err)
+		}
+		if err := mergeDocument(config, document, s.origin); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return config
+}
+
+func TestMergeDocuments(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategies map[string]ListStrategy
+		sources    []source
+		// app section as JSON
+		app string
+		// expected origins, an empty origin when the key has none
+		origins map[string]string
+	}{
+		{
+			name: "later sources win",
+			sources: []source{
+				{"file base.json", `{"app": {"name": "shop", "debug": true}}`},
+				{"file prod.json", `{"app": {"debug": false}}`},
+			},
+			app:     `{"debug":false,"name":"shop"}`,
+			origins: map[string]string{"app.name": "file base.json", "app.debug": "file prod.json"},
+		},
+		{
+			name: "null removes a key",
+			sources: []source{
+				{"file base.json", `{"app": {"name": "shop", "cache": {"ttl": 60}}}`},
+				{"file prod.json", `{"app": {"cache": null}}`},
+			},
+			app:     `{"name":"shop"}`,
+			origins: map[string]string{"app.cache": "", "app.cache.ttl": ""},
+		},
+		{
+			name: "null clears a section",
+			sources: []source{
+				{"file base.json", `{"app": {"name": "shop"}}`},
+				{"file prod.json", `{"app": null}`},
+			},
+			app:     `{}`,
+			origins: map[string]string{"app.name": ""},
+		},
+		{
+			name: "nested maps merge",
+			sources: []source{
+				{"file base.json", `{"app": {"database": {"host": "localhost", "pool": {"min": 1, "max": 5}}}}`},
+				{"env", `{"app": {"Database": {"pool": {"max": 20}, "name": "orders"}}}`},
+			},
+			app: `{"database":{"host":"localhost","name":"orders","pool":{"max":20,"min":1}}}`,
+			origins: map[string]string{
+				"app.database.host":     "file base.json",
+				"app.database.pool.min": "file base.json",
+				"app.database.pool.max": "env",
+				"app.database.name":     "env",
+			},
+		},
+		{
+			name: "a value replaces a map",
+			sources: []source{
+				{"file base.json", `{"app": {"cache": {"ttl": 60}}}`},
+				{"file prod.json", `{"app": {"cache": "redis://cache"}}`},
+			},
+			app:     `{"cache":"redis://cache"}`,
+			origins: map[string]string{"app.cache": "file prod.json", "app.cache.ttl": ""},
+		},
+		{
+			name: "a map replaces a value",
+			sources: []source{
+				{"file base.json", `{"app": {"cache": "none"}}`},
+				{"file prod.json", `{"app": {"cache": {"ttl": 60}}}`},
+			},
+			app:     `{"cache":{"ttl":60}}`,
+			origins: map[string]string{"app.cache": "", "app.cache.ttl": "file prod.json"},
+		},
+		{
+			name: "lists are replaced by default",
+			sources: []source{
+				{"file base.json", `{"app": {"origins": ["a", "b"]}}`},
+				{"file prod.json", `{"app": {"origins": ["c"]}}`},
+			},
+			app:     `{"origins":["c"]}`,
+			origins: map[string]string{"app.origins": "file prod.json"},
+		},
+		{
+			name:       "appended lists",
+			strategies: map[string]ListStrategy{"app.origins": ListAppend},
+			sources: []source{
+				{"file base.json", `{"app": {"origins": ["a", "b"], "hosts": ["x"]}}`},
+				{"file prod.json", `{"app": {"origins": ["c"], "hosts": ["y"]}}`},
+			},
+			app:     `{"hosts":["y"],"origins":["a","b","c"]}`,
+			origins: map[string]string{"app.origins": "file prod.json"},
+		},
+		{
+			name:       "strategies apply below their path",
+			strategies: map[string]ListStrategy{"app": ListAppend, "app.cors.methods": ListReplace},
+			sources: []source{
+				{"file base.json", `{"app": {"cors": {"origins": ["a"], "methods": ["GET"]}}}`},
+				{"file prod.json", `{"app": {"cors": {"origins": ["b"], "methods": ["POST"]}}}`},
+			},
+			app: `{"cors":{"methods":["POST"],"origins":["a","b"]}}`,
+		},
+		{
+			name: "keys keep their first spelling",
+			sources: []source{
+				{"file base.json", `{"app": {"max_items": 1}}`},
+				{"env", `{"app": {"max-items": 2}}`},
+			},
+			app:     `{"max_items":2}`,
+			origins: map[string]string{"app.max-items": "env", "app.maxItems": "env"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := mergeSources(t, test.strategies, test.sources...)
+
+			var app bytes.Buffer
+			json.NewEncoder(&app).Encode(config.App)
+			if got := strings.TrimSpace(app.String()); got != test.app {
+				t.Errorf("expected %s, got %s", test.app, got)
+			}
+			for path, expected := range test.origins {
+				origin, ok := config.Origin(path)
+				switch {
+				case expected == "" && ok:
+					t.Errorf("%s: expected no origin, got %q", path, origin)
+				case expected != "" && origin != expected:
+					t.Errorf("%s: expected %q, got %q", path, expected, origin)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeServer(t *testing.T) {
+	config := mergeSources(t, nil,
+		source{"file base.json", `{"server": {"port": 9090, "read_timeout": 30, "host": "0.0.0.0"}}`},
+		source{"file prod.json", `{"server": {"port": 0, "readTimeout": null, "unknown": 1}, "other": {}}`},
+	)
+
+	// An explicit zero is kept, null restores the default
+	if config.Server.Port != 0 || config.Server.ReadTimeout != 15 || config.Server.Host != "0.0.0.0" {
+		t.Errorf("unexpected server %+v", config.Server)
+	}
+	origins := map[string]string{
+		"server.port":          "file prod.json",
+		"server.read-timeout":  "default",
+		"server.host":          "file base.json",
+		"server.write-timeout": "default",
+	}
+	for path, expected := range origins {
+		if origin, _ := config.Origin(path); origin != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, origin)
+		}
+	}
+	if _, ok := config.Origin("server.pprof"); ok {
+		t.Error("expected no origin for a field without default")
+	}
+	expected := []string{"other (file prod.json)", "server.unknown (file prod.json)"}
+	sort.Strings(config.unknownKeys)
+	if !reflect.DeepEqual(config.unknownKeys, expected) {
+		t.Errorf("expected %v, got %v", expected, config.unknownKeys)
+	}
+
+	config = mergeSources(t, nil,
+		source{"file base.json", `{"server": {"port": 9090, "pprof": true}}`},
+		source{"file prod.json", `{"server": null}`},
+	)
+	if config.Server.Port != 8080 || config.Server.Pprof {
+		t.Errorf("expected the defaults, got %+v", config.Server)
+	}
+	if origin, _ := config.Origin("server.port"); origin != "default" {
+		t.Errorf("expected the default origin, got %q", origin)
+	}
+
+	for _, document := range []string{`{"server": 1}`, `{"app": []}`, `{"server": {"port": "eighty"}}`} {
+		config := &Config{}
+		var parsed map[string]interface{}
+		json.Unmarshal([]byte(document), &parsed)
+		if err := mergeDocument(config, parsed, "file bad.json"); err == nil {
+			t.Errorf("%s: expected an error", document)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"goserve/configuration/yaml"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemotePriority is the default priority of a remote source, above the
+// files (1) and the config.{env}.* files (2) and below the environment
+// variables (4)
+const RemotePriority = 3
+
+// Remote fetches a JSON or YAML document from an HTTP endpoint:
+//
+//	remote := configuration.NewRemote("https://config.internal/api/orders").
+//		WithHeader("Authorization", "Bearer "+token).
+//		WithCacheFile("/var/cache/orders/config.json")
+//	cfg, err := configuration.New().AddSource(remote.Source()).LoadConfig()
+//
+// Requests are conditional on the ETag of the last response. When the
+// endpoint cannot be reached, or answers with a server error, the last
+// document is used again: from memory, or from the cache file at startup.
+// With StartWatching, the endpoint is polled and a new document reloads the
+// configuration.
+type Remote struct {
+	url       string
+	headers   http.Header
+	format    string
+	cacheFile string
+	client    *http.Client
+	priority  int
+	required  bool
+
+	mu sync.Mutex
+	// Last document fetched, and the one fetched by Changed waiting for the
+	// next load
+	last    *remoteDocument
+	pending *remoteDocument
+}
+
+type remoteDocument struct {
+	ETag   string `json:"etag,omitempty"`
+	Format string `json:"format"`
+	Body   string `json:"body"`
+}
+
+func NewRemote(url string) *Remote {
+	return &Remote{
+		url:      url,
+		headers:  make(http.Header),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		priority: RemotePriority,
+	}
+}
+
+// WithHeader adds a header to every request, e.g. Authorization
+func (r *Remote) WithHeader(name, value string) *Remote {
+	r.headers.Add(name, value)
+	return r
+}
+
+// WithFormat forces "json" or "yaml" instead of detecting it from the
+// Content-Type and the URL
+func (r *Remote) WithFormat(format string) *Remote {
+	r.format = strings.ToLower(format)
+	return r
+}
+
+// WithCacheFile keeps the last document in filename, used when the endpoint
+// cannot be reached at startup
+func (r *Remote) WithCacheFile(filename string) *Remote {
+	r.cacheFile = filename
+	return r
+}
+
+// WithClient replaces the HTTP client (default: 10 seconds timeout)
+func (r *Remote) WithClient(client *http.Client) *Remote {
+	r.client = client
+	return r
+}
+
+func (r *Remote) WithPriority(priority int) *Remote {
+	r.priority = priority
+	return r
+}
+
+// Required fails Load when the endpoint answers 404
+func (r *Remote) Required() *Remote {
+	r.required = true
+	return r
+}
+
+// Source returns the configuration source of the endpoint
+func (r *Remote) Source() ConfigurationSource {
+	return ConfigurationSource{
+		Filename: r.url,
+		Priority: r.priority,
+		Load:     r.load,
+		Required: r.required,
+		Changed:  r.changed,
+	}
+}
+
+func (r *Remote) load(config *Config) error {
+	r.mu.Lock()
+	document := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+
+	if document == nil {
+		fetched, err := r.fetch()
+		if err != nil {
+			return err
+		}
+		document = fetched
+	}
+
+	r.mu.Lock()
+	r.last = document
+	r.mu.Unlock()
+	return r.apply(config, document)
+}
+
+// changed polls the endpoint and keeps a new document for the next load
+func (r *Remote) changed() bool {
+	r.mu.Lock()
+	last := r.last
+	r.mu.Unlock()
+
+	document, err := r.fetch()
+	if err != nil {
+		log.Printf("Polling %s failed: %v", r.url, err)
+		return false
+	}
+	if last != nil && document.Body == last.Body {
+		return false
+	}
+
+	r.mu.Lock()
+	r.pending = document
+	r.mu.Unlock()
+	return true
+}
+
+// fetch gets the document, falling back to the last one when the endpoint
+// is unreachable, answers 304 or a server error
+func (r *Remote) fetch() (*remoteDocument, error) {
+	r.mu.Lock()
+	last := r.last
+	r.mu.Unlock()
+
+	request, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.headers {
+		request.Header[name] = values
+	}
+	request.Header.Set("Accept", "application/json, application/yaml;q=0.9")
+	if last != nil && last.ETag != "" {
+		request.Header.Set("If-None-Match", last.ETag)
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return r.fallback(last, err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotModified && last != nil:
+		return last, nil
+	case response.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", r.url, ErrSourceNotFound)
+	case response.StatusCode >= 500:
+		return r.fallback(last, fmt.Errorf("unexpected status %s", response.Status))
+	case response.StatusCode != http.StatusOK:
+		return nil, &SourceError{Source: r.url, Err: fmt.Errorf("unexpected status %s", response.Status)}
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return r.fallback(last, err)
+	}
+	document := &remoteDocument{
+		ETag:   response.Header.Get("ETag"),
+		Format: r.detectFormat(response.Header.Get("Content-Type")),
+		Body:   string(body),
+	}
+	if _, err := document.parse(); err != nil {
+		return nil, &SourceError{Source: r.url, Err: fmt.Errorf("invalid %s: %v", document.Format, err)}
+	}
+	r.saveCache(document)
+	return document, nil
+}
+
+func (r *Remote) fallback(last *remoteDocument, cause error) (*remoteDocument, error) {
+	if last != nil {
+		log.Printf("Cannot reach %s, keeping the last configuration: %v", r.url, cause)
+		return last, nil
+	}
+	if cached := r.readCache(); cached != nil {
+		log.Printf("Cannot reach %s, using the cache %s: %v", r.url, r.cacheFile, cause)
+		return cached, nil
+	}
+	return nil, &SourceError{Source: r.url, Err: cause}
+}
+
+func (r *Remote) detectFormat(contentType string) string {
+	if r.format != "" {
+		return r.format
+	}
+	if strings.Contains(contentType, "yaml") {
+		return "yaml"
+	}
+	switch strings.ToLower(path.Ext(strings.SplitN(r.url, "?", 2)[0])) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
+
+func (d *remoteDocument) parse() (map[string]interface{}, error) {
+	if d.Format == "yaml" {
+		return yaml.ParseMap([]byte(d.Body))
+	}
+	return parseJSONDocument([]byte(d.Body))
+}
+
+func (r *Remote) apply(config *Config, document *remoteDocument) error {
+	parsed, err := document.parse()
+	if err != nil {
+		return &SourceError{Source: r.url, Err: fmt.Errorf("invalid %s: %v", document.Format, err)}
+	}
+	expanded, err := interpolateDocument(config, parsed, "")
+	if err != nil {
+		return &SourceError{Source: r.url, Err: err}
+	}
+	decrypted, err := decryptDocument(config, expanded, "")
+	if err != nil {
+		return &SourceError{Source: r.url, Err: err}
+	}
+	if err := mergeDocument(config, decrypted.(map[string]interface{}), "remote "+r.url); err != nil {
+		return &SourceError{Source: r.url, Err: err}
+	}
+	return nil
+}
+
+func (r *Remote) saveCache(document *remoteDocument) {
+	if r.cacheFile == "" {
+		return
+	}
+	data, err := json.Marshal(document)
+	if err == nil {
+		err = os.WriteFile(r.cacheFile, data, 0600)
+	}
+	if err != nil {
+		log.Printf("Cannot write the configuration cache %s: %v", r.cacheFile, err)
+	}
+}
+
+func (r *Remote) readCache() *remoteDocument {
+	if r.cacheFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.cacheFile)
+	if err != nil {
+		return nil
+	}
+	var document remoteDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		log.Printf("Ignoring the invalid configuration cache %s: %v", r.cacheFile, err)
+		return nil
+	}
+	return &document
+}
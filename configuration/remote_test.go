@@ -0,0 +1,128 @@
+package configuration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// stubConfigServer serves document with an ETag, answers 304 to matching
+// conditional requests and 500 once failing is set
+type stubConfigServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	document    string
+	failing     bool
+	conditional []string
+	notModified int
+}
+
+func newStubConfigServer(t *testing.T, document string) *stubConfigServer {
+	stub := &stubConfigServer{document: document}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if stub.failing {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		if match := r.Header.Get("If-None-Match"); match != "" {
+			stub.conditional = append(stub.conditional, match)
+			if match == `"v1"` {
+				stub.notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(stub.document))
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func writeFile(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemotePriority(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFile(t, "config.yaml", "app:\n  file: base\n  source: base\n")
+	writeFile(t, "config.development.yaml", "app:\n  source: environment file\n  envfile: true\n")
+	t.Setenv("APP_PORT", "9000")
+
+	stub := newStubConfigServer(t, `{"app": {"source": "remote"}, "server": {"port": 7000}}`)
+	config, err := New().AddSource(NewRemote(stub.URL).Source()).LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := config.GetString("app.source"); source != "remote" {
+		t.Errorf("expected the remote to override the environment file, got %q", source)
+	}
+	if config.GetString("app.file") != "base" || !config.GetBool("app.envfile") {
+		t.Errorf("expected the keys of the files to be kept, got %v", config.Dump()["app"])
+	}
+	if config.GetPort() != 9000 {
+		t.Errorf("expected APP_PORT to override the remote, got port %d", config.GetPort())
+	}
+}
+
+func TestRemoteETag(t *testing.T) {
+	stub := newStubConfigServer(t, `{"app": {"source": "remote"}}`)
+	loader := New().AddSource(NewRemote(stub.URL).Source())
+	filename := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, filename, `{}`)
+	if _, err := loader.LoadConfigFromFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.conditional) != 1 || stub.conditional[0] != `"v1"` || stub.notModified != 1 {
+		t.Errorf("expected one conditional request answered 304, got %v", stub.conditional)
+	}
+	if source := loader.Current().GetString("app.source"); source != "remote" {
+		t.Errorf("expected the document to be kept on 304, got %q", source)
+	}
+}
+
+func TestRemoteCacheFallback(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "remote.json")
+	filename := filepath.Join(dir, "config.json")
+	writeFile(t, filename, `{}`)
+
+	stub := newStubConfigServer(t, `{"app": {"source": "remote"}}`)
+	if _, err := New().AddSource(NewRemote(stub.URL).WithCacheFile(cache).Source()).LoadConfigFromFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process starting while the endpoint fails reads the cache
+	stub.mu.Lock()
+	stub.failing = true
+	stub.mu.Unlock()
+	config, err := New().AddSource(NewRemote(stub.URL).WithCacheFile(cache).Source()).LoadConfigFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := config.GetString("app.source"); source != "remote" {
+		t.Errorf("expected the cached document on 500, got %q", source)
+	}
+
+	// Without a cache the failure is reported
+	_, err = New().AddSource(NewRemote(stub.URL).Source()).LoadConfigFromFile(filename)
+	if err == nil {
+		t.Error("expected a 500 without a cache to fail Load")
+	}
+}
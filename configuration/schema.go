@@ -0,0 +1,282 @@
+package configuration
+
+import (
+	"fmt"
+	"goserve/configuration/env"
+	"goserve/configuration/utils"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema describes the configuration files as a JSON Schema: the server
+// settings, the sections registered with Section and the free-form app and
+// custom roots. Fields carry their default, their description (usage or
+// description tag) and their environment variable in x-env.
+func (cl *ConfigLoader) Schema() map[string]interface{} {
+	roots := map[string]map[string]interface{}{
+		"app":    objectSchema("Application settings", true),
+		"custom": objectSchema("Custom settings", true),
+	}
+	for _, section := range cl.sections {
+		keys := strings.Split(section.path, ".")
+		parent, ok := roots[keys[0]]
+		if !ok || len(keys) < 2 {
+			continue
+		}
+		for _, key := range keys[1 : len(keys)-1] {
+			parent = childObject(parent, key)
+		}
+		typ := reflect.TypeOf(section.target).Elem()
+		parent["properties"].(map[string]interface{})[keys[len(keys)-1]] = structSchema(typ, false, "")
+	}
+
+	server := structSchema(reflect.TypeOf(ServeurConfiguration{}), true, "")
+	server["description"] = "HTTP server settings"
+
+	root := objectSchema("goserve configuration", false)
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["properties"] = map[string]interface{}{
+		"server": server,
+		"app":    roots["app"],
+		"custom": roots["custom"],
+	}
+	return root
+}
+
+func objectSchema(description string, open bool) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{},
+		"additionalProperties": open,
+	}
+	if description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+// childObject returns the open object schema of key, created if needed
+func childObject(parent map[string]interface{}, key string) map[string]interface{} {
+	properties := parent["properties"].(map[string]interface{})
+	if child, ok := properties[key].(map[string]interface{}); ok {
+		return child
+	}
+	child := objectSchema("", true)
+	properties[key] = child
+	return child
+}
+
+// structSchema describes a struct. Server fields use their kebab case key,
+// section fields their json name as decoded by Bind.
+func structSchema(typ reflect.Type, server bool, prefix string) map[string]interface{} {
+	schema := objectSchema("", false)
+	properties := schema["properties"].(map[string]interface{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		switch {
+		case server:
+			name = strings.TrimPrefix(serverPath(field.Name), "server.")
+		case name == "":
+			name = field.Name
+		}
+
+		variable, hasEnv := field.Tag.Lookup("env")
+		if variable != "" && variable != "-" {
+			variable = utils.JoinEnv(prefix, variable)
+		} else {
+			variable = ""
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && !reflect.PointerTo(fieldType).Implements(textUnmarshalerType) {
+			childPrefix := prefix
+			if hasEnv && variable != "" {
+				childPrefix = variable
+			}
+			properties[name] = structSchema(fieldType, false, childPrefix)
+			continue
+		}
+
+		property := typeSchema(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			property["description"] = description
+		} else if usage := field.Tag.Get("usage"); usage != "" {
+			property["description"] = usage
+		}
+		if def, ok := field.Tag.Lookup("default"); ok && !isSecretField(field) {
+			property["default"] = schemaDefault(fieldType, def)
+		}
+		if variable != "" {
+			property["x-env"] = variable
+		}
+		if field.Tag.Get("required") == "true" {
+			property["x-required"] = true
+		}
+		if isSecretField(field) {
+			property["writeOnly"] = true
+		}
+		properties[name] = property
+	}
+	return schema
+}
+
+var environmentType = reflect.TypeOf(env.Environment(""))
+
+// typeSchema describes the values accepted for a Go type
+func typeSchema(typ reflect.Type) map[string]interface{} {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ == environmentType:
+		var names []interface{}
+		for _, name := range env.Registered() {
+			names = append(names, string(name))
+		}
+		return map[string]interface{}{"type": "string", "enum": names}
+	case typ == durationType:
+		return map[string]interface{}{"type": []interface{}{"string", "number"}, "format": "duration"}
+	case reflect.PointerTo(typ).Implements(textUnmarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(typ.Elem())}
+	case reflect.Struct:
+		return structSchema(typ, false, "")
+	}
+	return map[string]interface{}{}
+}
+
+// schemaDefault converts a default tag to the JSON type of the field
+func schemaDefault(typ reflect.Type, raw string) interface{} {
+	value := reflect.New(typ).Elem()
+	if typ == durationType || typ == environmentType || utils.SetField(value, raw) != nil {
+		return raw
+	}
+	switch value.Kind() {
+	case reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Slice, reflect.Map:
+		return value.Interface()
+	}
+	return raw
+}
+
+// Reference documents every setting of Schema as markdown tables, one per
+// root and registered section
+func (cl *ConfigLoader) Reference() string {
+	var builder strings.Builder
+	builder.WriteString("# Configuration reference\n\n")
+	builder.WriteString("Keys are matched ignoring case, dashes and underscores. Environment variables override the files.\n")
+
+	properties := cl.Schema()["properties"].(map[string]interface{})
+	for _, root := range []string{"server", "app", "custom"} {
+		node := properties[root].(map[string]interface{})
+		fmt.Fprintf(&builder, "\n## %s\n\n", root)
+		if description, ok := node["description"].(string); ok {
+			builder.WriteString(description + ".")
+			if open, _ := node["additionalProperties"].(bool); open {
+				builder.WriteString(" Any key is accepted.")
+			}
+			builder.WriteString("\n\n")
+		}
+		var rows []string
+		referenceRows(node, root, &rows)
+		if len(rows) == 0 {
+			continue
+		}
+		builder.WriteString("| Key | Type | Default | Environment variable | Description |\n")
+		builder.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, row := range rows {
+			builder.WriteString(row + "\n")
+		}
+	}
+	return builder.String()
+}
+
+func referenceRows(node map[string]interface{}, path string, rows *[]string) {
+	properties, _ := node["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		property := properties[key].(map[string]interface{})
+		keyPath := joinKey(path, key)
+		if _, nested := property["properties"]; nested {
+			referenceRows(property, keyPath, rows)
+			continue
+		}
+
+		description, _ := property["description"].(string)
+		if required, _ := property["x-required"].(bool); required {
+			description = strings.TrimSpace("Required. " + description)
+		}
+		if secret, _ := property["writeOnly"].(bool); secret {
+			description = strings.TrimSpace(description + " Secret, redacted in logs.")
+		}
+		def := ""
+		if value, ok := property["default"]; ok {
+			def = fmt.Sprintf("`%v`", value)
+		}
+		variable := ""
+		if name, ok := property["x-env"].(string); ok {
+			variable = "`" + name + "`"
+		}
+		*rows = append(*rows, fmt.Sprintf("| `%s` | %s | %s | %s | %s |", keyPath, schemaTypeName(property), def, variable, description))
+	}
+}
+
+func schemaTypeName(property map[string]interface{}) string {
+	name := ""
+	switch typ := property["type"].(type) {
+	case string:
+		name = typ
+	case []interface{}:
+		parts := make([]string, len(typ))
+		for i, part := range typ {
+			parts[i] = fmt.Sprint(part)
+		}
+		name = strings.Join(parts, " or ")
+	default:
+		name = "any"
+	}
+	if format, ok := property["format"].(string); ok {
+		name = format
+	}
+	if items, ok := property["items"].(map[string]interface{}); ok {
+		name = "array of " + schemaTypeName(items)
+	}
+	if enum, ok := property["enum"].([]interface{}); ok {
+		values := make([]string, len(enum))
+		for i, value := range enum {
+			values[i] = fmt.Sprint(value)
+		}
+		name += ": " + strings.Join(values, ", ")
+	}
+	return name
+}
@@ -0,0 +1,45 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func schemaLoader() ConfigurationBuilder {
+	return New().Section("app.database", &database{}).Section("custom.limits", &limits{})
+}
+
+func compareGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("%s is outdated, run go test -update\n%s", golden, actual)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := json.MarshalIndent(schemaLoader().Schema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, "schema.json.golden", append(data, '\n'))
+}
+
+func TestReference(t *testing.T) {
+	compareGolden(t, "reference.md.golden", []byte(schemaLoader().Reference()))
+}
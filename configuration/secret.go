@@ -0,0 +1,194 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"goserve/configuration/secrets"
+	"reflect"
+	"strings"
+)
+
+// SecretKeyFileEnv names the environment variable giving the key file used
+// to decrypt "enc:" values when WithSecretKeyFile is not called
+const SecretKeyFileEnv = "APP_SECRET_KEY_FILE"
+
+// Text shown in place of a secret
+const redacted = "[REDACTED]"
+
+// Secret holds a credential that is never printed: fmt, logs and JSON show
+// it redacted, Reveal returns the actual value
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("configuration.Secret(%q)", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// isSecretField tells whether a struct field is tagged `secret:"true"` or
+// holds a Secret
+func isSecretField(field reflect.StructField) bool {
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return field.Tag.Get("secret") == "true" || typ == secretType
+}
+
+// decryptDocument replaces the "enc:" strings of a parsed document with
+// their decrypted value and marks them as secrets
+func decryptDocument(config *Config, value interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			decrypted, err := decryptDocument(config, child, joinKey(path, key))
+			if err != nil {
+				return nil, err
+			}
+			v[key] = decrypted
+		}
+	case []interface{}:
+		for i, child := range v {
+			decrypted, err := decryptDocument(config, child, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = decrypted
+		}
+	case string:
+		if !secrets.IsEncrypted(v) {
+			return v, nil
+		}
+		if config.secretKey == nil {
+			return nil, fmt.Errorf("%s: encrypted value but no key, set %s or call WithSecretKeyFile", path, SecretKeyFileEnv)
+		}
+		plaintext, err := secrets.Decrypt(config.secretKey, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		// Lists are redacted as a whole
+		if i := strings.IndexByte(path, '['); i >= 0 {
+			config.markSecret(path[:i])
+		} else {
+			config.markSecret(path)
+		}
+		return plaintext, nil
+	}
+	return value, nil
+}
+
+func (c *Config) markSecret(path string) {
+	if c.secrets == nil {
+		c.secrets = make(map[string]bool)
+	}
+	c.secrets[normalizePath(path)] = true
+}
+
+// isSecret tells whether path or one of its parents holds a secret
+func (c *Config) isSecret(path string) bool {
+	normalized := normalizePath(path)
+	for {
+		if c.secrets[normalized] {
+			return true
+		}
+		i := strings.LastIndexByte(normalized, '.')
+		if i < 0 {
+			return false
+		}
+		normalized = normalized[:i]
+	}
+}
+
+// markSectionSecrets marks the keys of the secret fields of a section type
+func (c *Config) markSectionSecrets(path string, typ reflect.Type) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if isSecretField(field) {
+			c.markSecret(joinKey(path, name))
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
+			c.markSectionSecrets(joinKey(path, name), field.Type)
+		}
+	}
+}
+
+// Dump returns every setting, keyed like the configuration files, with the
+// secrets redacted: the fields tagged `secret:"true"` or of type Secret in
+// the server and in registered sections, and the values decrypted from
+// "enc:" strings.
+func (c *Config) Dump() map[string]interface{} {
+	server := make(map[string]interface{})
+	value := reflect.ValueOf(c.Server)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := strings.TrimPrefix(serverPath(field.Name), "server.")
+		if isSecretField(field) {
+			server[key] = redacted
+			continue
+		}
+		server[key] = value.Field(i).Interface()
+	}
+	return map[string]interface{}{
+		"server": server,
+		"app":    c.redactedCopy(c.App, "app"),
+		"custom": c.redactedCopy(c.Custom, "custom"),
+	}
+}
+
+func (c *Config) redactedCopy(values map[string]interface{}, path string) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		keyPath := joinKey(path, key)
+		if c.isSecret(keyPath) {
+			result[key] = redacted
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			result[key] = c.redactedCopy(v, keyPath)
+		case []interface{}:
+			items := make([]interface{}, len(v))
+			for i, item := range v {
+				if child, ok := item.(map[string]interface{}); ok {
+					items[i] = c.redactedCopy(child, keyPath)
+				} else {
+					items[i] = item
+				}
+			}
+			result[key] = items
+		default:
+			result[key] = value
+		}
+	}
+	return result
+}
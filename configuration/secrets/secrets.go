@@ -0,0 +1,144 @@
+// Package secrets encrypts and decrypts configuration values with AES-GCM.
+// An encrypted value is written "enc:" followed by the base64 encoding of
+// the nonce and the sealed text:
+//
+//	database:
+//	  password: enc:3q2+7w0K...
+//
+// The key is read from a local file, which stays out of the repository,
+// holding "base64:" followed by the base64 encoding of 16, 24 or 32 bytes.
+// WriteKeyFile creates one with a random key.
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix marks an encrypted value
+const Prefix = "enc:"
+
+// KeyPrefix starts the content of a key file
+const KeyPrefix = "base64:"
+
+// IsEncrypted tells whether value is an encrypted value
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// ReadKeyFile reads an AES key from filename. Other formats are rejected
+// rather than guessed: 32 raw characters would otherwise also read as a 24
+// bytes base64 key.
+func ReadKeyFile(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	key, err := DecodeKey(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return key, nil
+}
+
+// WriteKeyFile creates filename, readable by its owner only, holding key.
+// An existing file is never overwritten, as its key may still be needed to
+// decrypt values.
+func WriteKeyFile(filename string, key []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(EncodeKey(key) + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// EncodeKey returns the key file content for key
+func EncodeKey(key []byte) string {
+	return KeyPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodeKey reads a key written by EncodeKey
+func DecodeKey(encoded string) ([]byte, error) {
+	if !strings.HasPrefix(encoded, KeyPrefix) {
+		return nil, fmt.Errorf("invalid key, expected %s followed by 16, 24 or 32 base64 encoded bytes", KeyPrefix)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, KeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %v", err)
+	}
+	if !validKeySize(len(key)) {
+		return nil, fmt.Errorf("invalid key size %d, expected 16, 24 or 32 bytes", len(key))
+	}
+	return key, nil
+}
+
+// GenerateKey returns a random 32 bytes key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt seals plaintext with key and returns the "enc:" value
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens an "enc:" value with key
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted, expected the %s prefix", Prefix)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(value, Prefix)))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value, wrong key or altered value")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if !validKeySize(len(key)) {
+		return nil, fmt.Errorf("invalid key size %d, expected 16, 24 or 32 bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func validKeySize(size int) bool {
+	return size == 16 || size == 24 || size == 32
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, size := range []int{16, 24, 32} {
+		key := make([]byte, size)
+		for i := range key {
+			key[i] = byte(i)
+		}
+		for _, plaintext := range []string{"s3cret", "", "pässwörd with spaces\nand lines"} {
+			value, err := Encrypt(key, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEncrypted(value) || strings.Contains(value, plaintext) && plaintext != "" {
+				t.Errorf("unexpected value %q", value)
+			}
+			decrypted, err := Decrypt(key, value)
+			if err != nil || decrypted != plaintext {
+				t.Errorf("%d bytes key: expected %q, got %q, %v", size, plaintext, decrypted, err)
+			}
+		}
+	}
+
+	key, _ := GenerateKey()
+	first, _ := Encrypt(key, "s3cret")
+	second, _ := Encrypt(key, "s3cret")
+	if first == second {
+		t.Error("expected a fresh nonce for every value")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+	value, err := Encrypt(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	tamper := func(i int) string {
+		altered := append([]byte{}, sealed...)
+		altered[i] ^= 1
+		return Prefix + base64.StdEncoding.EncodeToString(altered)
+	}
+
+	tests := []struct {
+		name  string
+		key   []byte
+		value string
+		err   string
+	}{
+		{"wrong key", other, value, "wrong key or altered value"},
+		{"altered nonce", key, tamper(0), "wrong key or altered value"},
+		{"altered ciphertext", key, tamper(len(sealed) / 2), "wrong key or altered value"},
+		{"altered tag", key, tamper(len(sealed) - 1), "wrong key or altered value"},
+		{"truncated", key, Prefix + base64.StdEncoding.EncodeToString(sealed[:8]), "too short"},
+		{"not base64", key, Prefix + "not base64!", "invalid encrypted value"},
+		{"not encrypted", key, "s3cret", "expected the enc: prefix"},
+		{"invalid key", key[:10], value, "invalid key size 10"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plaintext, err := Decrypt(test.key, test.value)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected %q, got %q, %v", test.err, plaintext, err)
+			}
+		})
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := GenerateKey()
+	filename := filepath.Join(dir, "secret.key")
+	if err := WriteKeyFile(filename, key); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected a private file, got %v", info.Mode())
+	}
+	read, err := ReadKeyFile(filename)
+	if err != nil || string(read) != string(key) {
+		t.Errorf("expected the written key, got %x, %v", read, err)
+	}
+	if err := WriteKeyFile(filename, key); err == nil {
+		t.Error("expected an existing key file to be kept")
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"raw key", "0123456789abcdef0123456789abcdef", "expected base64: followed by"},
+		{"unprefixed base64", base64.StdEncoding.EncodeToString(key), "expected base64: followed by"},
+		{"invalid base64", KeyPrefix + "0123!", "invalid key"},
+		{"short key", KeyPrefix + base64.StdEncoding.EncodeToString(key[:20]), "invalid key size 20"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(dir, strings.ReplaceAll(test.name, " ", "-"))
+			os.WriteFile(filename, []byte(test.content+"\n"), 0o600)
+			if _, err := ReadKeyFile(filename); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+	if _, err := ReadKeyFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
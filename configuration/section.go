@@ -0,0 +1,247 @@
+package configuration
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"goserve/configuration/utils"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator is implemented by sections checking their own values once
+// decoded
+type Validator interface {
+	Validate() error
+}
+
+type section struct {
+	path   string
+	target interface{}
+}
+
+// Bind decodes the section at path, e.g. "app.database", into a new T:
+//
+//	type DatabaseConfig struct {
+//		Host    string        `json:"host" env:"DB_HOST" required:"true"`
+//		Pool    int           `json:"pool" default:"10"`
+//		Timeout time.Duration `json:"timeout" default:"5s"`
+//	}
+//	db, err := configuration.Bind[DatabaseConfig](cfg, "app.database")
+//
+// Keys match the json tag or the field name, ignoring case, dashes and
+// underscores. Environment variables from env tags override the file
+// values but not the command-line flags, then defaults fill the zero fields,
+// required fields are checked and Validate is called when T implements
+// Validator.
+func Bind[T any](cfg Configuration, path string) (T, error) {
+	var target T
+	err := BindTo(cfg, path, &target)
+	return target, err
+}
+
+// BindTo decodes the section at path into target, a pointer to a struct
+// whose current values are kept when the section does not set them
+func BindTo(cfg Configuration, path string, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s: target must be a pointer to a struct, got %T", path, target)
+	}
+
+	resolve := utils.LookupEnv
+	if config, ok := cfg.(*Config); ok {
+		config.markSectionSecrets(path, value.Type())
+		resolve = config.resolveEnv
+	}
+	if raw, ok := cfg.Get(path); ok {
+		if err := decodeValue(raw, value.Elem(), path); err != nil {
+			return err
+		}
+	}
+	if err := utils.BindEnvWith(target, "", resolve, nil); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// The env tags of the section are read here, after every source: the
+	// values set by flags are decoded again so they keep the last word
+	if config, ok := cfg.(*Config); ok {
+		if flagged := config.flagged(path); len(flagged) > 0 {
+			if err := decodeValue(flagged, value.Elem(), path); err != nil {
+				return err
+			}
+		}
+	}
+	if err := utils.ApplyDefaults(target); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := utils.CheckRequired(target); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if validator, ok := target.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// decodeValue converts a value from a configuration document (maps, slices,
+// strings, numbers and booleans) into target
+func decodeValue(raw interface{}, target reflect.Value, path string) error {
+	if raw == nil {
+		return nil
+	}
+
+	if target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return decodeValue(raw, target.Elem(), path)
+	}
+
+	if text, ok := scalarText(raw); ok {
+		if target.CanAddr() && target.Addr().Type().Implements(textUnmarshalerType) {
+			if err := target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			return nil
+		}
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		values, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a section, got %T", path, raw)
+		}
+		return decodeStruct(values, target, path)
+
+	case reflect.Map:
+		values, ok := raw.(map[string]interface{})
+		if !ok {
+			break
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMapWithSize(target.Type(), len(values)))
+		}
+		for key, item := range values {
+			k := reflect.New(target.Type().Key()).Elem()
+			if err := utils.SetField(k, key); err != nil {
+				return fmt.Errorf("%s: key %q: %v", path, key, err)
+			}
+			v := reflect.New(target.Type().Elem()).Elem()
+			if err := decodeValue(item, v, joinKey(path, key)); err != nil {
+				return err
+			}
+			target.SetMapIndex(k, v)
+		}
+		return nil
+
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			break
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+
+	case reflect.Interface:
+		if target.NumMethod() == 0 {
+			target.Set(reflect.ValueOf(raw))
+			return nil
+		}
+	}
+
+	text, ok := scalarText(raw)
+	if !ok {
+		return fmt.Errorf("%s: cannot decode %T into %s", path, raw, target.Type())
+	}
+	if err := utils.SetField(target, text); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func decodeStruct(values map[string]interface{}, target reflect.Value, path string) error {
+	typ := target.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		key, raw, ok := lookupKey(values, name)
+		if !ok {
+			continue
+		}
+		if err := decodeValue(raw, target.Field(i), joinKey(path, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupKey finds key exactly, then ignoring case, dashes and underscores
+func lookupKey(values map[string]interface{}, key string) (string, interface{}, bool) {
+	if value, ok := values[key]; ok {
+		return key, value, true
+	}
+	normalized := normalizeKey(key)
+	for candidate, value := range values {
+		if normalizeKey(candidate) == normalized {
+			return candidate, value, true
+		}
+	}
+	return "", nil, false
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+}
+
+// scalarText returns the text form of strings, numbers and booleans
+func scalarText(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), true
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	// Named scalar types such as env.Environment
+	value := reflect.ValueOf(raw)
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), true
+	}
+	return "", false
+}
@@ -1,71 +1,76 @@
 package configuration
 
 import (
+	"fmt"
+	"goserve/configuration/env"
+	"goserve/configuration/utils"
 	"log"
-	"os"
+	"reflect"
 	"strings"
-	"goserve/configuration/env"
 )
 
 type ServeurConfiguration struct {
-	Environment env.Environment
-	Port        int
-	Host        string
+	Environment env.Environment `env:"APP_ENV" default:"development" usage:"development, staging, production, testing or a registered environment"`
+	Port        int             `env:"APP_PORT" default:"8080" usage:"HTTP port"`
+	Host        string          `env:"APP_HOST" usage:"listen address, empty for all interfaces"`
+
+	// Timeouts in seconds
+	ReadTimeout  int `env:"READ_TIMEOUT" default:"15" usage:"read timeout in seconds"`
+	WriteTimeout int `env:"WRITE_TIMEOUT" default:"15" usage:"write timeout in seconds"`
+	IdleTimeout  int `env:"IDLE_TIMEOUT" default:"60" usage:"idle timeout in seconds"`
 
-	ReadTimeout  int
-	WriteTimeout int
-	IdleTimeout  int
+	// Serves the runtime profiles under /debug/pprof/, whatever the
+	// environment
+	Pprof bool `env:"APP_PPROF" usage:"serve the net/http/pprof handlers under /debug/pprof/"`
 }
 
 func (c *ServeurConfiguration) setDefaults() {
-	c.Environment = env.Development
-	c.Port = 8080
-	c.Host = ""
-	c.ReadTimeout = 15
-	c.WriteTimeout = 15
-	c.IdleTimeout = 60
+	*c = ServeurConfiguration{}
+	if err := utils.ApplyDefaults(c); err != nil {
+		panic(err)
+	}
 }
 
-func (c *ServeurConfiguration) loadFromEnv() {
-	if envValue := os.Getenv(env.APP_ENVIRONMENT_KEY); envValue != "" {
-		c.Environment = env.Environment(strings.ToLower(envValue))
-	}
-	if port := os.Getenv(env.APP_PORT_KEY); port != "" {
-		c.Port = int(port[0])
-	}
-	if host := os.Getenv(env.APP_HOST_KEY); host != "" {
-		c.Host = host
+func (c *ServeurConfiguration) validate() error {
+	if !c.Environment.IsRegistered() {
+		var names []string
+		for _, name := range env.Registered() {
+			names = append(names, string(name))
+		}
+		return fmt.Errorf("invalid environment %q, expected one of %s or an environment added with env.Register", c.Environment, strings.Join(names, ", "))
 	}
-	if rt := os.Getenv("READ_TIMEOUT"); rt != "" {
-		c.ReadTimeout = int(rt[0])
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d, expected 0 to 65535", c.Port)
 	}
-	if wt := os.Getenv("WRITE_TIMEOUT"); wt != "" {
-		c.WriteTimeout = int(wt[0])
-	}
-	if it := os.Getenv("IDLE_TIMEOUT"); it != "" {
-		c.IdleTimeout = int(it[0])
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 {
+		return fmt.Errorf("invalid timeouts, expected positive seconds")
 	}
+	return nil
 }
 
-func (c *ServeurConfiguration) validate() {
-	validEnvs := map[env.Environment]bool{
-		env.Development: true,
-		env.Staging:     true,
-		env.Production:  true,
-		env.Testing:     true,
+// LogConfiguration logs the server settings and the application keys with
+// the source of each value
+func (c *Config) LogConfiguration() {
+	log.Println("Configuration loaded successfully")
+	server := reflect.ValueOf(c.Server)
+	for i := 0; i < server.NumField(); i++ {
+		field := server.Type().Field(i)
+		path := serverPath(field.Name)
+		var line string
+		if isSecretField(field) {
+			line = fmt.Sprintf("%s=%s", path, redacted)
+		} else {
+			line = fmt.Sprintf("%s=%v", path, server.Field(i).Interface())
+		}
+		if origin, ok := c.Origin(path); ok {
+			line += fmt.Sprintf(" (from %s)", origin)
+		}
+		log.Print(line)
 	}
-
-	if !validEnvs[c.Environment] {
-		panic("Invalid environment setting")
+	// Application values may hold credentials, only their source is logged
+	for _, root := range []string{"app", "custom"} {
+		for _, key := range c.sortedOrigins(root) {
+			log.Printf("%s (from %s)", key, c.origins[key])
+		}
 	}
 }
-
-func (c *ServeurConfiguration) LogConfiguration() {
-	log.Println("Configuration loaded successfully")
-	log.Printf("Environment: %s", c.Environment)
-	log.Printf("Host: %s", c.Host)
-	log.Printf("Port: %d", c.Port)
-	log.Printf("Read Timeout: %d", c.ReadTimeout)
-	log.Printf("Write Timeout: %d", c.WriteTimeout)
-	log.Printf("Idle Timeout: %d", c.IdleTimeout)
-}
@@ -0,0 +1,596 @@
+// Package toml parses TOML v1.0 documents. Dates and times are returned as
+// their RFC 3339 text.
+package toml
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse decodes a TOML document into maps, slices and scalars (string,
+// bool, int64 or float64)
+func Parse(data []byte) (map[string]interface{}, error) {
+	p := &parser{
+		text:    strings.TrimPrefix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\ufeff"),
+		line:    1,
+		root:    make(map[string]interface{}),
+		defined: make(map[string]bool),
+	}
+	if err := p.document(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+type parser struct {
+	text string
+	pos  int
+	line int
+
+	root    map[string]interface{}
+	current map[string]interface{}
+	// Tables declared by a header, keyed by their path
+	defined map[string]bool
+	// Paths of the arrays of tables, the only arrays headers may extend
+	arrayTables map[string]bool
+	// Inline tables cannot be extended once written
+	sealed map[uintptr]bool
+	// Tables created by dotted keys cannot be declared by a header
+	dotted map[uintptr]bool
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *parser) advance() byte {
+	c := p.text[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// space skips spaces and tabs
+func (p *parser) space() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// blank skips whitespace, newlines and comments
+func (p *parser) blank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n':
+			p.advance()
+		case '#':
+			p.comment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) comment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// endOfLine accepts trailing whitespace and a comment
+func (p *parser) endOfLine() error {
+	p.space()
+	if p.peek() == '#' {
+		p.comment()
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return p.errorf("expected end of line, got %q", p.rest())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) rest() string {
+	end := strings.IndexByte(p.text[p.pos:], '\n')
+	if end < 0 {
+		return p.text[p.pos:]
+	}
+	return p.text[p.pos : p.pos+end]
+}
+
+func (p *parser) document() error {
+	p.current = p.root
+	p.arrayTables = make(map[string]bool)
+	p.sealed = make(map[uintptr]bool)
+	p.dotted = make(map[uintptr]bool)
+	for {
+		p.blank()
+		if p.eof() {
+			return nil
+		}
+		var err error
+		if strings.HasPrefix(p.text[p.pos:], "[[") {
+			err = p.arrayTable()
+		} else if p.peek() == '[' {
+			err = p.table()
+		} else {
+			err = p.keyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) table() error {
+	p.pos++
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.peek() != ']' {
+		return p.errorf("expected ']' after table name")
+	}
+	p.pos++
+
+	name := strings.Join(keys, ".")
+	if p.defined["["+name+"]"] {
+		return p.errorf("table [%s] defined twice", name)
+	}
+	p.defined["["+name+"]"] = true
+
+	table, err := p.descend(p.root, keys, false)
+	if err != nil {
+		return err
+	}
+	if p.dotted[reflect.ValueOf(table).Pointer()] {
+		return p.errorf("table [%s] is already defined by dotted keys", name)
+	}
+	p.current = table
+	return nil
+}
+
+func (p *parser) arrayTable() error {
+	p.pos += 2
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(p.text[p.pos:], "]]") {
+		return p.errorf("expected ']]' after array of tables name")
+	}
+	p.pos += 2
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	name := strings.Join(keys, ".")
+	table := make(map[string]interface{})
+	switch existing := parent[last].(type) {
+	case nil:
+		parent[last] = []interface{}{table}
+		p.arrayTables[name] = true
+	case []interface{}:
+		if !p.arrayTables[name] {
+			return p.errorf("cannot append to static array %s", name)
+		}
+		parent[last] = append(existing, table)
+	default:
+		return p.errorf("key %s is already defined", name)
+	}
+	// Sub-tables of the new element may be declared again
+	prefix := "[" + name + "."
+	for name := range p.defined {
+		if strings.HasPrefix(name, prefix) {
+			delete(p.defined, name)
+		}
+	}
+	p.current = table
+	return nil
+}
+
+// descend walks to the table at keys, creating missing tables. Arrays of
+// tables resolve to their last element.
+func (p *parser) descend(table map[string]interface{}, keys []string, dotted bool) (map[string]interface{}, error) {
+	for i, key := range keys {
+		switch next := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+			if dotted {
+				p.dotted[reflect.ValueOf(child).Pointer()] = true
+			}
+		case map[string]interface{}:
+			if p.sealed[reflect.ValueOf(next).Pointer()] {
+				return nil, p.errorf("inline table %s cannot be extended", strings.Join(keys[:i+1], "."))
+			}
+			table = next
+		case []interface{}:
+			path := strings.Join(keys[:i+1], ".")
+			if dotted || !p.arrayTables[path] {
+				return nil, p.errorf("key %s is already defined", path)
+			}
+			table = next[len(next)-1].(map[string]interface{})
+		default:
+			return nil, p.errorf("key %s is already defined", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+func (p *parser) keyValue(table map[string]interface{}) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.space()
+
+	parent, err := p.descend(table, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorf("key %s is already defined", strings.Join(keys, "."))
+	}
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent[last] = value
+	return nil
+}
+
+// key parses a possibly dotted key
+func (p *parser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.space()
+		var key string
+		var err error
+		switch c := p.peek(); {
+		case c == '"':
+			key, err = p.basicString()
+		case c == '\'':
+			key, err = p.literalString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKey(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("invalid key %q", p.rest())
+			}
+			key = p.text[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.space()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *parser) value() (interface{}, error) {
+	rest := p.text[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multilineBasicString()
+	case strings.HasPrefix(rest, `'''`):
+		return p.multilineLiteralString()
+	case strings.HasPrefix(rest, `"`):
+		return p.basicString()
+	case strings.HasPrefix(rest, `'`):
+		return p.literalString()
+	case strings.HasPrefix(rest, "["):
+		return p.array()
+	case strings.HasPrefix(rest, "{"):
+		return p.inlineTable()
+	case strings.HasPrefix(rest, "true") && !isBareKey(at(rest, 4)):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false") && !isBareKey(at(rest, 5)):
+		p.pos += 5
+		return false, nil
+	}
+	return p.scalar()
+}
+
+func at(text string, i int) byte {
+	if i < len(text) {
+		return text[i]
+	}
+	return 0
+}
+
+func (p *parser) array() (interface{}, error) {
+	p.pos++
+	result := make([]interface{}, 0)
+	for {
+		p.blank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return result, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+		p.blank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array, got %q", p.rest())
+		}
+	}
+}
+
+func (p *parser) inlineTable() (interface{}, error) {
+	p.pos++
+	result := make(map[string]interface{})
+	p.space()
+	if p.peek() == '}' {
+		p.pos++
+		p.sealInline(result)
+		return result, nil
+	}
+	for {
+		if err := p.keyValue(result); err != nil {
+			return nil, err
+		}
+		p.space()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.sealInline(result)
+			return result, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table, got %q", p.rest())
+		}
+	}
+}
+
+func (p *parser) sealInline(table map[string]interface{}) {
+	p.sealed[reflect.ValueOf(table).Pointer()] = true
+	for _, value := range table {
+		if child, ok := value.(map[string]interface{}); ok {
+			p.sealInline(child)
+		}
+	}
+}
+
+func (p *parser) basicString() (string, error) {
+	p.pos++
+	var buf strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.advance()
+		switch c {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			if err := p.escape(&buf); err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) multilineBasicString() (string, error) {
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.advance()
+	}
+	var buf strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.text[p.pos:], `"""`) {
+			// Up to two quotes may precede the closing delimiter
+			extra := 0
+			for extra < 2 && at(p.text, p.pos+3+extra) == '"' {
+				extra++
+			}
+			buf.WriteString(strings.Repeat(`"`, extra))
+			p.pos += 3 + extra
+			return buf.String(), nil
+		}
+		c := p.advance()
+		if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+		// A line ending backslash trims the following whitespace
+		save, saveLine := p.pos, p.line
+		p.space()
+		if p.peek() == '\n' {
+			for !p.eof() && strings.IndexByte(" \t\n", p.peek()) >= 0 {
+				p.advance()
+			}
+			continue
+		}
+		p.pos, p.line = save, saveLine
+		if err := p.escape(&buf); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *parser) escape(buf *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated escape")
+	}
+	c := p.advance()
+	switch c {
+	case 'b':
+		buf.WriteByte('\b')
+	case 't':
+		buf.WriteByte('\t')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'r':
+		buf.WriteByte('\r')
+	case 'e':
+		buf.WriteByte(0x1b)
+	case '"', '\\':
+		buf.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.text) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.text[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape \\%c%s", c, p.text[p.pos:p.pos+size])
+		}
+		buf.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+func (p *parser) literalString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.text[p.pos:], "'\n")
+	if end < 0 || p.text[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated literal string")
+	}
+	value := p.text[p.pos : p.pos+end]
+	p.pos += end + 1
+	return value, nil
+}
+
+func (p *parser) multilineLiteralString() (string, error) {
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.advance()
+	}
+	end := strings.Index(p.text[p.pos:], `'''`)
+	if end < 0 {
+		return "", p.errorf("unterminated multi-line literal string")
+	}
+	for at(p.text, p.pos+end+3) == '\'' && end < len(p.text) {
+		end++
+	}
+	value := p.text[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 3
+	return value, nil
+}
+
+// scalar parses numbers, special floats and dates
+func (p *parser) scalar() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\n,]}#", p.peek()) < 0 {
+		p.pos++
+	}
+	token := p.text[start:p.pos]
+	// Local date followed by a time: 1979-05-27 07:32:00
+	if isDate(token) && p.peek() == ' ' && at(p.text, p.pos+3) == ':' {
+		p.pos++
+		for !p.eof() && strings.IndexByte(" \t\n,]}#", p.peek()) < 0 {
+			p.pos++
+		}
+		token = strings.Replace(p.text[start:p.pos], " ", "T", 1)
+	}
+	if token == "" {
+		return nil, p.errorf("missing value")
+	}
+
+	switch token {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if isDate(token) || (len(token) >= 8 && token[2] == ':' && token[5] == ':') {
+		return token, nil
+	}
+
+	if strings.Contains(token, "__") || strings.HasPrefix(token, "_") || strings.HasSuffix(token, "_") {
+		return nil, p.errorf("invalid number %q", token)
+	}
+	number := strings.ReplaceAll(token, "_", "")
+	if strings.HasPrefix(number, "0x") || strings.HasPrefix(number, "0o") || strings.HasPrefix(number, "0b") {
+		n, err := strconv.ParseInt(number, 0, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", token)
+		}
+		return n, nil
+	}
+	digits := strings.TrimLeft(number, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' && digits[1] != 'e' && digits[1] != 'E' {
+		return nil, p.errorf("leading zeros are not allowed in %q", token)
+	}
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil && !strings.ContainsAny(digits, "xXpPin") {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+func isDate(token string) bool {
+	return len(token) >= 10 && token[4] == '-' && token[7] == '-' && strings.Trim(token[:4], "0123456789") == ""
+}
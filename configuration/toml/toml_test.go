@@ -0,0 +1,73 @@
+package toml
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/config.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"title": "goserve",
+		"server": map[string]interface{}{
+			"port":    int64(8080),
+			"timeout": 1.5,
+			"debug":   false,
+			"tags":    []interface{}{"api", "web"},
+			"limits":  map[string]interface{}{"burst": int64(10)},
+			"tls":     map[string]interface{}{"cert": "cert.pem"},
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "admin"},
+			map[string]interface{}{"name": "guest", "roles": map[string]interface{}{"read": true}},
+		},
+		"fruit": map[string]interface{}{
+			"apple": map[string]interface{}{
+				"color":   "red",
+				"texture": map[string]interface{}{"smooth": true},
+			},
+		},
+	}
+	if !reflect.DeepEqual(document, expected) {
+		t.Errorf("unexpected document:\n got %#v\nwant %#v", document, expected)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{"table after dotted keys", "a.b = 1\n[a]\nc = 2\n", "line 2: table [a] is already defined by dotted keys"},
+		{"sub-table after dotted keys", "[fruit]\napple.taste.sweet = true\n[fruit.apple.taste]\n", "line 3: table [fruit.apple.taste] is already defined by dotted keys"},
+		{"table defined twice", "[a]\n[a]\n", "line 2: table [a] defined twice"},
+		{"duplicate key", "a = 1\na = 2\n", "line 2: key a is already defined"},
+		{"extended inline table", "a = {b = 1}\na.c = 2\n", "inline table a cannot be extended"},
+		{"static array extended", "a = []\n[[a]]\n", "cannot append to static array a"},
+		{"leading zeros", "a = 010\n", `leading zeros are not allowed in "010"`},
+		{"missing value", "a =\n", "missing value"},
+		{"unterminated string", "a = \"b\n", "unterminated string"},
+		{"trailing content", "a = 1 2\n", "expected end of line"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.document))
+			if err == nil {
+				t.Fatalf("expected an error for %q", test.document)
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected %q in %q", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// InterpolationError reports an undefined variable or a reference cycle
+type InterpolationError struct {
+	Variable string
+	// Chain of references leading back to Variable, set for cycles
+	Cycle []string
+}
+
+func (e *InterpolationError) Error() string {
+	if len(e.Cycle) > 0 {
+		return fmt.Sprintf("cycle in variable references: %s", strings.Join(e.Cycle, " -> "))
+	}
+	return fmt.Sprintf("undefined variable ${%s}", e.Variable)
+}
+
+// Resolver returns the value of a variable and whether it is defined
+type Resolver func(name string) (string, bool, error)
+
+// LookupEnv resolves variables from the process environment. When NAME is
+// not set, NAME_FILE names a file holding the value, as mounted by Docker
+// and Kubernetes secrets; its trailing newline is dropped.
+func LookupEnv(name string) (string, bool, error) {
+	return LookupFunc(os.LookupEnv)(name)
+}
+
+// LookupFunc is LookupEnv reading the variables from lookup instead of the
+// process environment
+func LookupFunc(lookup func(name string) (string, bool)) Resolver {
+	return func(name string) (string, bool, error) {
+		if value, ok := lookup(name); ok {
+			return value, true, nil
+		}
+		filename, ok := lookup(name + FileSuffix)
+		if !ok || filename == "" {
+			return "", false, nil
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return "", false, fmt.Errorf("%s%s: %v", name, FileSuffix, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+}
+
+// FileSuffix marks the variables naming a file that holds the value
+const FileSuffix = "_FILE"
+
+// Interpolate replaces ${VAR}, ${VAR:-default} (unset or empty) and
+// ${VAR-default} (unset) in text; $$ is a literal $. A variable that is
+// undefined and has no default is an *InterpolationError.
+func Interpolate(text string, resolve Resolver) (string, error) {
+	if !strings.Contains(text, "$") {
+		return text, nil
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i+1 == len(text) {
+			buf.WriteByte(text[i])
+			continue
+		}
+		switch text[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i++
+		case '{':
+			end := closingBrace(text, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", text)
+			}
+			value, err := expand(text[i+2:end], resolve)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(value)
+			i = end
+		default:
+			buf.WriteByte('$')
+		}
+	}
+	return buf.String(), nil
+}
+
+// IsPlaceholder reports whether text is exactly one ${...} expression
+func IsPlaceholder(text string) bool {
+	return strings.HasPrefix(text, "${") && closingBrace(text, 2) == len(text)-1
+}
+
+// closingBrace finds the brace closing an expression starting at start,
+// skipping nested ${...} in defaults
+func closingBrace(text string, start int) int {
+	depth := 1
+	for i := start; i < len(text); i++ {
+		switch {
+		case text[i] == '$' && i+1 < len(text) && text[i+1] == '{':
+			depth++
+			i++
+		case text[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func expand(expression string, resolve Resolver) (string, error) {
+	name, fallback, operator := expression, "", ""
+	if i := strings.IndexAny(expression, ":-"); i >= 0 {
+		name = expression[:i]
+		switch {
+		case strings.HasPrefix(expression[i:], ":-"):
+			operator, fallback = ":-", expression[i+2:]
+		case expression[i] == '-':
+			operator, fallback = "-", expression[i+1:]
+		default:
+			return "", fmt.Errorf("invalid expression ${%s}", expression)
+		}
+	}
+	if !validName(name) {
+		return "", fmt.Errorf("invalid variable name in ${%s}", expression)
+	}
+
+	value, found, err := resolve(name)
+	if err != nil {
+		return "", err
+	}
+	if found && (operator != ":-" || value != "") {
+		return value, nil
+	}
+	if operator != "" {
+		return Interpolate(fallback, resolve)
+	}
+	return "", &InterpolationError{Variable: name}
+}
+
+func validName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,81 +1,369 @@
 package utils
 
 import (
-	"goserve/configuration/env"
-	"log"
-	"os"
+	"encoding"
+	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// BindError reports a field that could not be set from its environment
+// variable or tag
+type BindError struct {
+	Field    string
+	Variable string
+	Err      error
+}
+
+func (e *BindError) Error() string {
+	if e.Variable == "" {
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", e.Variable, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// EnvErrors aggregates every binding error of a struct
+type EnvErrors []*BindError
+
+func (e EnvErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e EnvErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// LoadStructFromEnv binds the `env` tagged fields of target from the
+// environment, see BindEnv
 func LoadStructFromEnv(target interface{}) error {
-	value := reflect.ValueOf(target).Elem()
-	typ := value.Type()
+	return BindEnv(target, "")
+}
+
+// BindEnv sets the fields of target, a pointer to a struct, from environment
+// variables. A field tagged `env:"NAME"` reads PREFIX_NAME. A nested struct
+// tagged `env:"TLS"` extends the prefix of its fields (PREFIX_TLS_CERT);
+// without a tag its fields keep the current prefix. PREFIX_NAME_FILE is read
+// when PREFIX_NAME is not set, see LookupEnv. Empty variables are ignored.
+// Every failure is returned as EnvErrors.
+func BindEnv(target interface{}, prefix string) error {
+	return BindEnvFunc(target, prefix, nil)
+}
+
+// BindEnvFunc is BindEnv calling set with the field path (Server.Port) and
+// the variable of every field it assigns
+func BindEnvFunc(target interface{}, prefix string, set func(field, variable string)) error {
+	return BindEnvWith(target, prefix, LookupEnv, set)
+}
+
+// BindEnvWith is BindEnvFunc reading the variables through resolve
+func BindEnvWith(target interface{}, prefix string, resolve Resolver, set func(field, variable string)) error {
+	var errs EnvErrors
+	err := walk(target, prefix, func(f field) {
+		if f.variable == "" {
+			return
+		}
+		raw, ok, err := resolve(f.variable)
+		if err != nil {
+			errs = append(errs, &BindError{Field: f.path, Variable: f.variable, Err: err})
+			return
+		}
+		if !ok || raw == "" {
+			return
+		}
+		if err := SetField(f.value, raw); err != nil {
+			errs = append(errs, &BindError{Field: f.path, Variable: f.variable, Err: err})
+		} else if set != nil {
+			set(f.path, f.variable)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return errs.orNil()
+}
+
+// ApplyDefaults sets the zero fields of target tagged `default:"value"`
+func ApplyDefaults(target interface{}) error {
+	var errs EnvErrors
+	err := walk(target, "", func(f field) {
+		def, ok := f.tag.Lookup("default")
+		if !ok || !f.value.IsZero() {
+			return
+		}
+		if err := SetField(f.value, def); err != nil {
+			errs = append(errs, &BindError{Field: f.path, Err: fmt.Errorf("invalid default %q: %v", def, err)})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return errs.orNil()
+}
+
+// CheckRequired reports the fields tagged `required:"true"` that are still
+// zero
+func CheckRequired(target interface{}) error {
+	var errs EnvErrors
+	err := walk(target, "", func(f field) {
+		if f.tag.Get("required") != "true" || !f.value.IsZero() {
+			return
+		}
+		errs = append(errs, &BindError{Field: f.path, Variable: f.variable, Err: fmt.Errorf("is required")})
+	})
+	if err != nil {
+		return err
+	}
+	return errs.orNil()
+}
+
+type field struct {
+	value    reflect.Value
+	tag      reflect.StructTag
+	path     string
+	variable string
+}
+
+func walk(target interface{}, prefix string, visit func(field)) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("target must be a pointer to a struct, got %T", target)
+	}
+	walkStruct(value.Elem(), prefix, "", visit)
+	return nil
+}
 
-	for i := 0; i < value.NumField(); i++ {
-		field := value.Field(i)
+func walkStruct(value reflect.Value, prefix, path string, visit func(field)) {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
 		fieldType := typ.Field(i)
+		if !fieldType.IsExported() {
+			continue
+		}
 
-		envTag := fieldType.Tag.Get("env")
-		if envTag == "" {
+		name, hasTag := fieldType.Tag.Lookup("env")
+		if name == "-" {
 			continue
 		}
+		fieldValue := value.Field(i)
+		fieldPath := joinPath(path, fieldType.Name)
 
-		envValue := os.Getenv(envTag)
-		if envValue == "" {
+		if isNested(fieldType.Type) {
+			childPrefix := prefix
+			if hasTag && name != "" {
+				childPrefix = JoinEnv(prefix, name)
+			}
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					// Only keep the allocation if something was set
+					fresh := reflect.New(fieldType.Type.Elem())
+					walkStruct(fresh.Elem(), childPrefix, fieldPath, visit)
+					if !fresh.Elem().IsZero() {
+						fieldValue.Set(fresh)
+					}
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			walkStruct(fieldValue, childPrefix, fieldPath, visit)
 			continue
 		}
 
-		if err := setFieldFromString(field, envValue); err != nil {
-			log.Printf("⚠️ Error setting field %s from env var %s: %v", fieldType.Name, envTag, err)
+		variable := ""
+		if hasTag && name != "" {
+			variable = JoinEnv(prefix, name)
 		}
+		visit(field{
+			value:    fieldValue,
+			tag:      fieldType.Tag,
+			path:     fieldPath,
+			variable: variable,
+		})
 	}
+}
 
-	return nil
+func isNested(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	return !reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// JoinEnv joins environment variable name parts with underscores
+func JoinEnv(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
 }
 
-func setFieldFromString(field reflect.Value, value string) error {
+// SetField converts a raw string into the field. Supported types are the
+// scalar kinds, time.Duration, encoding.TextUnmarshaler, pointers, slices
+// (comma separated) and maps (K=V,K2=V2).
+func SetField(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.Ptr {
+		fresh := reflect.New(field.Type().Elem())
+		if err := SetField(fresh.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(fresh)
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if field.Type() == durationType {
+		d, err := ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
 	switch field.Kind() {
 	case reflect.String:
-		field.SetString(value)
+		field.SetString(raw)
 
 	case reflect.Bool:
-		b := parseBool(value)
+		b, err := ParseBool(raw)
+		if err != nil {
+			return err
+		}
 		field.SetBool(b)
 
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Base 10 so that zero padded values such as 08080 are not octal
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(raw), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		field.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(n)
+
 	case reflect.Slice:
-		if field.Type().Elem().Kind() == reflect.String {
-			parts := strings.Split(value, ",")
-			slice := make([]string, len(parts))
-			for i, part := range parts {
-				slice[i] = strings.TrimSpace(part)
+		parts := splitList(raw)
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := SetField(slice.Index(i), part); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+		}
+		field.Set(slice)
+
+	case reflect.Map:
+		m := reflect.MakeMap(field.Type())
+		for _, part := range splitList(raw) {
+			key, val, ok := strings.Cut(part, "=")
+			if !ok {
+				return fmt.Errorf("invalid map entry %q, expected KEY=VALUE", part)
 			}
-			field.Set(reflect.ValueOf(slice))
+			k := reflect.New(field.Type().Key()).Elem()
+			if err := SetField(k, strings.TrimSpace(key)); err != nil {
+				return fmt.Errorf("key %q: %v", key, err)
+			}
+			v := reflect.New(field.Type().Elem()).Elem()
+			if err := SetField(v, strings.TrimSpace(val)); err != nil {
+				return fmt.Errorf("value of %q: %v", key, err)
+			}
+			m.SetMapIndex(k, v)
 		}
+		field.Set(m)
 
-	case reflect.Struct:
-		// Special case for Duration
-		// if field.Type().Name() == "Duration" {
-		// 	if d, err := time.ParseDuration(value); err == nil {
-		// 		field.Set(reflect.ValueOf(Duration{d}))
-		// 	}
-		// }
-		// Special case for Environment
-		if field.Type().Name() == "Environment" {
-			field.Set(reflect.ValueOf(env.Environment(strings.ToLower(value))))
+	case reflect.Interface:
+		if field.NumMethod() != 0 {
+			return fmt.Errorf("unsupported type %s", field.Type())
 		}
+		field.Set(reflect.ValueOf(raw))
+
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
 	}
 
 	return nil
 }
 
-func parseBool(value string) bool {
-	trueValues := []string{"1", "t", "true", "yes", "y"}
-	value = strings.ToLower(strings.TrimSpace(value))
-	for _, v := range trueValues {
-		if value == v {
-			return true
+func splitList(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return []string{}
+	}
+	parts := strings.Split(raw, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// ParseDuration accepts Go durations (1m30s) and bare numbers, read as
+// seconds to match the historical timeout settings
+func ParseDuration(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		// ParseFloat also reads NaN and Inf, which have no duration, and
+		// values past the range of time.Duration would wrap around
+		nanoseconds := seconds * float64(time.Second)
+		if math.IsNaN(seconds) || math.Abs(nanoseconds) >= math.MaxInt64 {
+			return 0, fmt.Errorf("invalid duration %q", raw)
 		}
+		return time.Duration(nanoseconds), nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", raw)
+	}
+	return d, nil
+}
+
+// ParseBool accepts 1/0, t/f, true/false, yes/no, y/n and on/off
+func ParseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "t", "true", "yes", "y", "on":
+		return true, nil
+	case "0", "f", "false", "no", "n", "off":
+		return false, nil
 	}
-	return false
+	return false, fmt.Errorf("invalid boolean %q", value)
 }
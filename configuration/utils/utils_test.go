@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetField(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   interface{}
+		raw      string
+		expected interface{}
+		err      string
+	}{
+		{name: "int", target: new(int), raw: " 9090 ", expected: 9090},
+		{name: "zero padded int is decimal", target: new(int), raw: "010", expected: 10},
+		{name: "zero padded port", target: new(int), raw: "08080", expected: 8080},
+		{name: "negative int", target: new(int64), raw: "-3", expected: int64(-3)},
+		{name: "hexadecimal int", target: new(int), raw: "0x10", err: `invalid integer "0x10"`},
+		{name: "int overflow", target: new(int8), raw: "300", err: `invalid integer "300"`},
+		{name: "uint", target: new(uint16), raw: "0080", expected: uint16(80)},
+		{name: "negative uint", target: new(uint), raw: "-1", err: `invalid unsigned integer "-1"`},
+		{name: "float", target: new(float64), raw: "0.25", expected: 0.25},
+		{name: "bool", target: new(bool), raw: "on", expected: true},
+		{name: "invalid bool", target: new(bool), raw: "maybe", err: `invalid boolean "maybe"`},
+		{name: "duration", target: new(time.Duration), raw: "1m30s", expected: 90 * time.Second},
+		{name: "duration in seconds", target: new(time.Duration), raw: "1.5", expected: 1500 * time.Millisecond},
+		{name: "NaN duration", target: new(time.Duration), raw: "NaN", err: `invalid duration "NaN"`},
+		{name: "infinite duration", target: new(time.Duration), raw: "Inf", err: `invalid duration "Inf"`},
+		{name: "negative infinite duration", target: new(time.Duration), raw: "-inf", err: `invalid duration "-inf"`},
+		{name: "overflowing duration", target: new(time.Duration), raw: "1e12", err: `invalid duration "1e12"`},
+		{name: "slice", target: new([]int), raw: "1, 02,3", expected: []int{1, 2, 3}},
+		{name: "invalid slice item", target: new([]int), raw: "1,x", err: `item 1: invalid integer "x"`},
+		{name: "map", target: new(map[string]int), raw: "a=1, b=2", expected: map[string]int{"a": 1, "b": 2}},
+		{name: "invalid map entry", target: new(map[string]int), raw: "a", err: "expected KEY=VALUE"},
+		{name: "pointer", target: new(*int), raw: "7", expected: intPointer(7)},
+		{name: "text unmarshaler", target: new(net.IP), raw: "10.0.0.1", expected: net.ParseIP("10.0.0.1")},
+		{name: "unsupported", target: new(chan int), raw: "1", err: "unsupported type chan int"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			field := reflect.ValueOf(test.target).Elem()
+			err := SetField(field, test.raw)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(field.Interface(), test.expected) {
+				t.Errorf("got %#v, want %#v", field.Interface(), test.expected)
+			}
+		})
+	}
+}
+
+func intPointer(n int) *int {
+	return &n
+}
+
+type tlsSettings struct {
+	Cert string `env:"CERT"`
+	Key  string `env:"KEY" required:"true"`
+}
+
+type settings struct {
+	Port    int               `env:"PORT" default:"8080"`
+	Timeout time.Duration     `env:"TIMEOUT" default:"30s"`
+	Hosts   []string          `env:"HOSTS"`
+	Limits  map[string]int    `env:"LIMITS"`
+	TLS     tlsSettings       `env:"TLS"`
+	Labels  map[string]string `env:"-"`
+}
+
+func TestBindEnv(t *testing.T) {
+	t.Setenv("APP_PORT", "09090")
+	t.Setenv("APP_TIMEOUT", "2m")
+	t.Setenv("APP_HOSTS", "a, b")
+	t.Setenv("APP_LIMITS", "reads=10,writes=2")
+	t.Setenv("APP_TLS_CERT", "cert.pem")
+	t.Setenv("APP_TLS_KEY", "key.pem")
+
+	var target settings
+	var assigned []string
+	err := BindEnvFunc(&target, "APP", func(field, variable string) {
+		assigned = append(assigned, field+"="+variable)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := settings{
+		Port:    9090,
+		Timeout: 2 * time.Minute,
+		Hosts:   []string{"a", "b"},
+		Limits:  map[string]int{"reads": 10, "writes": 2},
+		TLS:     tlsSettings{Cert: "cert.pem", Key: "key.pem"},
+	}
+	if !reflect.DeepEqual(target, expected) {
+		t.Errorf("got %+v, want %+v", target, expected)
+	}
+	if len(assigned) != 6 || assigned[4] != "TLS.Cert=APP_TLS_CERT" {
+		t.Errorf("unexpected assignments %v", assigned)
+	}
+}
+
+func TestBindEnvErrors(t *testing.T) {
+	t.Setenv("APP_PORT", "80a")
+	t.Setenv("APP_TIMEOUT", "NaN")
+
+	var target settings
+	err := BindEnv(&target, "APP")
+	var errs EnvErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 aggregated errors, got %v", err)
+	}
+	if errs[0].Variable != "APP_PORT" || errs[1].Variable != "APP_TIMEOUT" {
+		t.Errorf("unexpected variables %q, %q", errs[0].Variable, errs[1].Variable)
+	}
+	if !strings.Contains(err.Error(), `APP_TIMEOUT (Timeout): invalid duration "NaN"`) {
+		t.Errorf("unexpected message %q", err)
+	}
+
+	if err := BindEnv(target, "APP"); err == nil {
+		t.Error("expected an error for a non pointer target")
+	}
+}
+
+func TestDefaultsAndRequired(t *testing.T) {
+	target := settings{Port: 9000}
+	if err := ApplyDefaults(&target); err != nil {
+		t.Fatal(err)
+	}
+	if target.Port != 9000 || target.Timeout != 30*time.Second {
+		t.Errorf("defaults must only fill zero fields, got %+v", target)
+	}
+
+	err := CheckRequired(&target)
+	if err == nil || err.Error() != "TLS_KEY (TLS.Key): is required" {
+		t.Errorf("expected the missing key, got %v", err)
+	}
+
+	var invalid struct {
+		Port int `default:"eighty"`
+	}
+	if err := ApplyDefaults(&invalid); err == nil || !strings.Contains(err.Error(), `invalid default "eighty"`) {
+		t.Errorf("expected an invalid default error, got %v", err)
+	}
+}
@@ -0,0 +1,376 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goserve/configuration/toml"
+	"goserve/configuration/utils"
+	"goserve/configuration/yaml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is a problem found in a configuration file by Validate
+type ValidationError struct {
+	Filename string
+	// Line of the key, 0 when it cannot be located
+	Line    int
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	location := e.Filename
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", e.Filename, e.Line)
+	}
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", location, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Path, e.Message)
+}
+
+// ValidationErrors lists every problem of a file, in document order
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Validate checks a configuration file against the schema of a new loader,
+// see ConfigLoader.Validate
+func Validate(filename string) error {
+	return New().Validate(filename)
+}
+
+// Validate checks a configuration file against Schema without loading it:
+// syntax, unknown keys, types and allowed values. Values holding ${VAR}
+// references or encrypted values are only known when loading and are not
+// type checked. The result is nil or ValidationErrors.
+func (cl *ConfigLoader) Validate(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	format := strings.ToLower(filepath.Ext(filename))
+	var document map[string]interface{}
+	var lines map[string]int
+	switch format {
+	case ".yaml", ".yml":
+		document, err = yaml.ParseMap(data)
+		lines = yamlKeyLines(data)
+	case ".toml":
+		document, err = toml.Parse(data)
+		lines = tomlKeyLines(data)
+	default:
+		document, err = parseJSONDocument(data)
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return ValidationErrors{{Filename: filename, Line: lineAt(data, int(syntax.Offset)), Message: err.Error()}}
+		}
+		lines = jsonKeyLines(data)
+	}
+	if err != nil {
+		return ValidationErrors{{Filename: filename, Message: err.Error()}}
+	}
+
+	var errs ValidationErrors
+	validateValue(document, cl.Schema(), "", func(path, message string) {
+		errs = append(errs, &ValidationError{Filename: filename, Line: locate(lines, path), Path: path, Message: message})
+	})
+	if len(errs) == 0 {
+		return nil
+	}
+	// Errors that could not be located come last
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line == 0 || errs[j].Line == 0 {
+			return errs[j].Line == 0 && errs[i].Line != 0
+		}
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
+
+// validateValue checks value against the subset of JSON Schema produced by
+// Schema, matching keys like the loader
+func validateValue(value interface{}, schema map[string]interface{}, path string, report func(path, message string)) {
+	if value == nil {
+		// null restores the default
+		return
+	}
+	if text, ok := value.(string); ok && (strings.Contains(text, "${") || strings.HasPrefix(text, "enc:")) {
+		return
+	}
+	if !matchesType(value, schema) {
+		report(path, fmt.Sprintf("expected %s, got %s", schemaTypeName(schema), jsonTypeName(value)))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		allowed := false
+		for _, candidate := range enum {
+			if strings.EqualFold(fmt.Sprint(candidate), fmt.Sprint(value)) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			report(path, fmt.Sprintf("invalid value %q, expected one of %v", fmt.Sprint(value), enum))
+		}
+	}
+	if schema["format"] == "duration" {
+		if text, ok := value.(string); ok {
+			if _, err := utils.ParseDuration(text); err != nil {
+				report(path, err.Error())
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, key := range sortedKeys(v) {
+			keyPath := joinKey(path, key)
+			if _, property, ok := lookupKey(properties, key); ok {
+				validateValue(v[key], property.(map[string]interface{}), keyPath, report)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					report(keyPath, "unknown key")
+				}
+			case map[string]interface{}:
+				validateValue(v[key], additional, keyPath, report)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(item, items, fmt.Sprintf("%s[%d]", path, i), report)
+			}
+		}
+	}
+}
+
+func matchesType(value interface{}, schema map[string]interface{}) bool {
+	var types []string
+	switch typ := schema["type"].(type) {
+	case string:
+		types = []string{typ}
+	case []interface{}:
+		for _, t := range typ {
+			types = append(types, fmt.Sprint(t))
+		}
+	default:
+		return true
+	}
+	actual := jsonTypeName(value)
+	for _, expected := range types {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		if f, _ := strconv.ParseFloat(fmt.Sprint(v), 64); f == float64(int64(f)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// locate returns the line of path, or of its closest parent found
+func locate(lines map[string]int, path string) int {
+	normalized := normalizePath(path)
+	for normalized != "" {
+		if line, ok := lines[normalized]; ok {
+			return line
+		}
+		if i := strings.LastIndexAny(normalized, ".["); i >= 0 {
+			normalized = normalized[:i]
+		} else {
+			normalized = ""
+		}
+	}
+	return 0
+}
+
+func lineAt(data []byte, offset int) int {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// jsonKeyLines maps the normalized path of every key to its line
+func jsonKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				keyPath := joinKey(path, fmt.Sprint(key))
+				lines[normalizePath(keyPath)] = lineAt(data, int(decoder.InputOffset()))
+				if err := walk(keyPath); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+	walk("")
+	return lines
+}
+
+var yamlKeyPattern = regexp.MustCompile(`^("([^"]*)"|'([^']*)'|[^\s#"'{\[][^:#]*?)\s*:(\s|$)`)
+
+// yamlKeyLines maps the normalized path of block mapping keys to their line
+func yamlKeyLines(data []byte) map[string]int {
+	type level struct {
+		indent int
+		path   string
+	}
+	lines := make(map[string]int)
+	items := make(map[string]int)
+	var stack []level
+
+	parent := func(indent int) string {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			return ""
+		}
+		return stack[len(stack)-1].path
+	}
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(raw, " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		indent := len(text) - len(trimmed)
+
+		for strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			path := parent(indent)
+			itemPath := fmt.Sprintf("%s[%d]", path, items[path])
+			items[path]++
+			lines[normalizePath(itemPath)] = i + 1
+			stack = append(stack, level{indent: indent, path: itemPath})
+			trimmed = strings.TrimLeft(strings.TrimPrefix(trimmed, "-"), " ")
+			indent = len(text) - len(trimmed)
+		}
+
+		match := yamlKeyPattern.FindStringSubmatch(trimmed)
+		if match == nil {
+			continue
+		}
+		key := strings.TrimSpace(match[1])
+		if match[2] != "" || match[3] != "" {
+			key = match[2] + match[3]
+		}
+		keyPath := joinKey(parent(indent), key)
+		lines[normalizePath(keyPath)] = i + 1
+		stack = append(stack, level{indent: indent, path: keyPath})
+	}
+	return lines
+}
+
+// tomlKeyLines maps the normalized path of tables and keys to their line
+func tomlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	arrays := make(map[string]int)
+	table := ""
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+			continue
+		case strings.HasPrefix(text, "[["):
+			name := tomlKey(strings.TrimSuffix(strings.SplitN(strings.TrimPrefix(text, "[["), "]]", 2)[0], "]]"))
+			if arrays[name] == 0 {
+				lines[normalizePath(name)] = i + 1
+			}
+			table = fmt.Sprintf("%s[%d]", name, arrays[name])
+			arrays[name]++
+			lines[normalizePath(table)] = i + 1
+		case strings.HasPrefix(text, "["):
+			table = tomlKey(strings.SplitN(strings.TrimPrefix(text, "["), "]", 2)[0])
+			lines[normalizePath(table)] = i + 1
+		default:
+			key, _, ok := strings.Cut(text, "=")
+			if !ok {
+				continue
+			}
+			lines[normalizePath(joinKey(table, tomlKey(key)))] = i + 1
+		}
+	}
+	return lines
+}
+
+// tomlKey turns a dotted TOML key into a path, dropping quotes
+func tomlKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
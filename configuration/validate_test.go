@@ -0,0 +1,62 @@
+package configuration
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateLines(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected []string
+	}{
+		{"invalid.yaml", []string{
+			"testdata/invalid.yaml:3: server.port: expected integer, got string",
+			`testdata/invalid.yaml:4: server.environment: invalid value "moon", expected one of [development testing staging production]`,
+			"testdata/invalid.yaml:5: server.unknown: unknown key",
+			`testdata/invalid.yaml:10: app.database.timeout: invalid duration "soon"`,
+			"testdata/invalid.yaml:13: app.database.replicas[1]: expected string, got integer",
+			"testdata/invalid.yaml:15: app.database.pool.max-open: expected integer, got string",
+			"testdata/invalid.yaml:20: custom.limits.windoww: unknown key",
+		}},
+		{"invalid.json", []string{
+			"testdata/invalid.json:4: server.read-timeout: expected integer, got string",
+			"testdata/invalid.json:8: app.database.debug: expected boolean, got string",
+			"testdata/invalid.json:10: app.database.options.sslmode: expected string, got integer",
+		}},
+		{"invalid.toml", []string{
+			"testdata/invalid.toml:3: server.idle_timeout: expected integer, got string",
+			"testdata/invalid.toml:6: app.database.port: expected integer, got string",
+			`testdata/invalid.toml:9: custom.limits.interval: invalid duration "1 second"`,
+		}},
+		{"syntax.json", []string{
+			"testdata/syntax.json:4: invalid character '}' looking for beginning of object key string",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			err := schemaLoader().Validate(filepath.Join("testdata", test.file))
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+			var messages []string
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			if !reflect.DeepEqual(messages, test.expected) {
+				t.Errorf("expected\n%q\ngot\n%q", test.expected, messages)
+			}
+		})
+	}
+}
+
+func TestValidateValidFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, filename, "server:\n  port: 9090\n  read_timeout: 30\napp:\n  database:\n    timeout: 5s\n    replicas: [a, b]\n  free: form\n")
+	if err := schemaLoader().Validate(filename); err != nil {
+		t.Errorf("expected a valid file, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package configuration
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Interval used by StartWatching when none is given
+const defaultWatchInterval = 2 * time.Second
+
+type keyWatcher struct {
+	path string
+	fn   func(old, new interface{})
+}
+
+// watchFile records a file read or looked for by a source, so StartWatching
+// reloads when it is changed, created or removed
+func (c *Config) watchFile(filename string) {
+	for _, existing := range c.files {
+		if existing == filename {
+			return
+		}
+	}
+	c.files = append(c.files, filename)
+}
+
+func (cl *ConfigLoader) Current() Configuration {
+	if config := cl.current.Load(); config != nil {
+		return config
+	}
+	return nil
+}
+
+func (cl *ConfigLoader) Watch(fn func(old, new Configuration)) ConfigurationBuilder {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.watchers = append(cl.watchers, fn)
+	return cl
+}
+
+func (cl *ConfigLoader) OnChange(path string, fn func(old, new interface{})) ConfigurationBuilder {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.keyWatchers = append(cl.keyWatchers, keyWatcher{path: path, fn: fn})
+	return cl
+}
+
+// Reload runs the sources again. The new configuration replaces the current
+// one only when it is valid; sections registered with Section are validated
+// but not updated, subscribers read the new values from the Configuration.
+func (cl *ConfigLoader) Reload() error {
+	cl.mu.Lock()
+	old := cl.current.Load()
+	if old == nil {
+		cl.mu.Unlock()
+		return fmt.Errorf("configuration not loaded, call Load() before Reload()")
+	}
+	config, err := cl.build(false)
+	if err != nil {
+		cl.mu.Unlock()
+		log.Printf("Configuration reload rejected, keeping the current one: %v", err)
+		return err
+	}
+	cl.current.Store(config)
+	changed := changedKeys(old, config)
+	if len(changed) == 0 {
+		cl.mu.Unlock()
+		log.Printf("Configuration reloaded, no change")
+		return nil
+	}
+	watchers := append([]func(old, new Configuration){}, cl.watchers...)
+	keyWatchers := append([]keyWatcher{}, cl.keyWatchers...)
+	cl.mu.Unlock()
+
+	log.Printf("Configuration reloaded, changed: %v", changed)
+	for _, watcher := range keyWatchers {
+		before, _ := old.Get(watcher.path)
+		after, _ := config.Get(watcher.path)
+		if !reflect.DeepEqual(before, after) {
+			watcher.fn(before, after)
+		}
+	}
+	for _, watcher := range watchers {
+		watcher(old, config)
+	}
+	return nil
+}
+
+// StartWatching reloads the configuration when a file of its sources or a
+// source with a Changed function changes, checked every interval, and when
+// the process receives SIGHUP.
+// Calling stop ends the watch.
+func (cl *ConfigLoader) StartWatching(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		defer signal.Stop(hangup)
+
+		states := cl.fileStates()
+		for {
+			select {
+			case <-done:
+				return
+			case <-hangup:
+				log.Printf("SIGHUP received, reloading configuration")
+			case <-ticker.C:
+				current := cl.fileStates()
+				if !reflect.DeepEqual(states, current) {
+					log.Printf("Configuration files changed, reloading configuration")
+				} else if source, changed := cl.changedSource(); changed {
+					log.Printf("Configuration source %s changed, reloading configuration", source)
+				} else {
+					continue
+				}
+			}
+			cl.Reload()
+			states = cl.fileStates()
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// changedSource polls the sources able to detect their own changes
+func (cl *ConfigLoader) changedSource() (string, bool) {
+	cl.mu.Lock()
+	sources := append([]ConfigurationSource{}, cl.sources...)
+	cl.mu.Unlock()
+
+	for _, source := range sources {
+		if source.Changed != nil && source.Changed() {
+			return source.Filename, true
+		}
+	}
+	return "", false
+}
+
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func (cl *ConfigLoader) fileStates() map[string]fileState {
+	config := cl.current.Load()
+	if config == nil {
+		return nil
+	}
+	states := make(map[string]fileState, len(config.files))
+	for _, filename := range config.files {
+		info, err := os.Stat(filename)
+		if err != nil {
+			states[filename] = fileState{}
+			continue
+		}
+		states[filename] = fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+	}
+	return states
+}
+
+// changedKeys lists the keys whose value differs between two configurations
+func changedKeys(old, new *Config) []string {
+	before, after := flatten(old), flatten(new)
+	var keys []string
+	for key, value := range before {
+		if other, ok := after[key]; !ok || !reflect.DeepEqual(value, other) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func flatten(c *Config) map[string]interface{} {
+	values := make(map[string]interface{})
+	server := reflect.ValueOf(c.Server)
+	for i := 0; i < server.NumField(); i++ {
+		values[serverPath(server.Type().Field(i).Name)] = server.Field(i).Interface()
+	}
+	flattenMap(values, "app", c.App)
+	flattenMap(values, "custom", c.Custom)
+	return values
+}
+
+func flattenMap(values map[string]interface{}, path string, section map[string]interface{}) {
+	for key, value := range section {
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			flattenMap(values, joinKey(path, key), child)
+			continue
+		}
+		values[joinKey(path, key)] = value
+	}
+}
@@ -0,0 +1,133 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flow parses flow collections: [a, b] and {key: value}
+type flow struct {
+	text string
+	pos  int
+	line line
+}
+
+func (f *flow) space() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *flow) parse() (interface{}, error) {
+	f.space()
+	if f.pos >= len(f.text) {
+		return nil, f.line.errorf("unexpected end of flow collection")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		value, end, err := quoted(f.line, f.text[f.pos:])
+		if err != nil {
+			return nil, err
+		}
+		f.pos += end
+		return value, nil
+	case '&', '*', '!':
+		return nil, f.line.errorf("anchors, aliases and tags are not supported")
+	}
+
+	start := f.pos
+	for f.pos < len(f.text) && !strings.ContainsRune(",]}", rune(f.text[f.pos])) {
+		if f.text[f.pos] == ':' && (f.pos+1 == len(f.text) || strings.ContainsRune(" ,]}", rune(f.text[f.pos+1]))) {
+			break
+		}
+		f.pos++
+	}
+	return resolve(strings.TrimSpace(f.text[start:f.pos])), nil
+}
+
+func (f *flow) sequence() (interface{}, error) {
+	f.pos++
+	result := make([]interface{}, 0)
+	for {
+		f.space()
+		if f.pos >= len(f.text) {
+			return nil, f.line.errorf("unterminated flow sequence")
+		}
+		if f.text[f.pos] == ']' {
+			f.pos++
+			return result, nil
+		}
+		value, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *flow) mapping() (interface{}, error) {
+	f.pos++
+	result := make(map[string]interface{})
+	for {
+		f.space()
+		if f.pos >= len(f.text) {
+			return nil, f.line.errorf("unterminated flow mapping")
+		}
+		if f.text[f.pos] == '}' {
+			f.pos++
+			return result, nil
+		}
+		key, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			if key == nil {
+				return nil, f.line.errorf("empty key in flow mapping")
+			}
+			name = fmt.Sprint(key)
+		}
+
+		f.space()
+		var value interface{}
+		if f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			f.space()
+			if f.pos < len(f.text) && f.text[f.pos] != ',' && f.text[f.pos] != '}' {
+				if value, err = f.parse(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if _, exists := result[name]; exists {
+			return nil, f.line.errorf("duplicate key %q", name)
+		}
+		result[name] = value
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *flow) separator(closing byte) error {
+	f.space()
+	if f.pos >= len(f.text) {
+		return f.line.errorf("unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return f.line.errorf("expected ',' or '%c', got %q", closing, f.text[f.pos:])
+}
@@ -0,0 +1,610 @@
+// Package yaml parses the subset of YAML used by configuration files: block
+// mappings and sequences, flow collections, quoted and block scalars and
+// comments. Anchors, aliases, tags and multiple documents are rejected.
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parse decodes a YAML document into maps, slices and scalars (string,
+// bool, int64, float64 or nil)
+func Parse(data []byte) (interface{}, error) {
+	p := &parser{}
+	if err := p.split(string(data)); err != nil {
+		return nil, err
+	}
+	p.skip()
+	if p.done() {
+		return map[string]interface{}{}, nil
+	}
+	value, err := p.block(p.current().indent)
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if !p.done() {
+		l := p.current()
+		return nil, l.errorf("unexpected content %q", l.text)
+	}
+	return value, nil
+}
+
+// ParseMap decodes a document whose root must be a mapping
+func ParseMap(data []byte) (map[string]interface{}, error) {
+	value, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml: the document root must be a mapping")
+	}
+	return root, nil
+}
+
+type line struct {
+	num    int
+	indent int
+	// Content without indentation nor trailing comment
+	text string
+	// Original line, needed by block scalars
+	raw string
+}
+
+func (l line) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) split(data string) error {
+	data = strings.TrimPrefix(data, "\ufeff")
+	documents := 0
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		l := line{num: i + 1, raw: raw}
+		trimmed := strings.TrimLeft(raw, " ")
+		l.indent = len(raw) - len(trimmed)
+		if strings.HasPrefix(trimmed, "\t") {
+			return l.errorf("tabs are not allowed for indentation")
+		}
+		l.text = strings.TrimRight(stripComment(trimmed), " \t")
+
+		if l.indent == 0 && (l.text == "---" || strings.HasPrefix(l.text, "--- ")) {
+			documents++
+			if documents > 1 {
+				return l.errorf("multiple documents are not supported")
+			}
+			l.text = strings.TrimSpace(strings.TrimPrefix(l.text, "---"))
+			if l.text == "" {
+				l.raw = ""
+			}
+		}
+		if l.indent == 0 && l.text == "..." {
+			break
+		}
+		if l.indent == 0 && strings.HasPrefix(l.text, "%") {
+			continue
+		}
+		p.lines = append(p.lines, l)
+	}
+	return nil
+}
+
+// stripComment removes a comment starting with " #" outside of quotes
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" \t[{,:-", rune(text[i-1])) {
+				quote = c
+			}
+		case c == '#':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '\t' {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.lines)
+}
+
+func (p *parser) current() line {
+	return p.lines[p.pos]
+}
+
+// skip moves past blank and comment lines
+func (p *parser) skip() {
+	for !p.done() && p.current().text == "" {
+		p.pos++
+	}
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the node starting at the current line, indented by indent
+func (p *parser) block(indent int) (interface{}, error) {
+	p.skip()
+	if p.done() {
+		return nil, nil
+	}
+	l := p.current()
+	if isSequenceItem(l.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.mapping(indent)
+	}
+	p.pos++
+	return p.inline(l, l.text)
+}
+
+func (p *parser) mapping(indent int) (interface{}, error) {
+	result := make(map[string]interface{})
+	for {
+		p.skip()
+		if p.done() {
+			return result, nil
+		}
+		l := p.current()
+		if l.indent < indent {
+			return result, nil
+		}
+		if l.indent > indent {
+			return nil, l.errorf("unexpected indentation")
+		}
+		if isSequenceItem(l.text) {
+			return result, nil
+		}
+
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, l.errorf("expected a mapping key, got %q", l.text)
+		}
+		name, err := parseKey(l, key)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := result[name]; exists {
+			return nil, l.errorf("duplicate key %q", name)
+		}
+		p.pos++
+
+		value, err := p.value(l, indent, rest, true)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+}
+
+func (p *parser) sequence(indent int) (interface{}, error) {
+	result := make([]interface{}, 0)
+	for {
+		p.skip()
+		if p.done() {
+			return result, nil
+		}
+		l := p.current()
+		if l.indent != indent || !isSequenceItem(l.text) {
+			if l.indent > indent {
+				return nil, l.errorf("unexpected indentation")
+			}
+			return result, nil
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			value, err := p.value(l, indent, "", false)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		// "- key: value" and "- - item" open a collection indented at the
+		// item content
+		offset := len(l.text) - len(rest)
+		_, _, isKey := splitKey(rest)
+		if isKey || isSequenceItem(rest) {
+			p.lines[p.pos].indent = indent + offset
+			p.lines[p.pos].text = rest
+			value, err := p.block(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		p.pos++
+		value, err := p.value(l, indent, rest, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+}
+
+// value parses what follows "key:" or "-": an inline value, a block scalar
+// or a nested block
+func (p *parser) value(l line, indent int, rest string, inMapping bool) (interface{}, error) {
+	if strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">") {
+		return p.blockScalar(l, indent, rest)
+	}
+	if rest != "" {
+		return p.inline(l, rest)
+	}
+
+	p.skip()
+	if p.done() {
+		return nil, nil
+	}
+	next := p.current()
+	if next.indent > indent {
+		return p.block(next.indent)
+	}
+	// A mapping value may be a sequence at the same indentation
+	if inMapping && next.indent == indent && isSequenceItem(next.text) {
+		return p.sequence(indent)
+	}
+	return nil, nil
+}
+
+// inline parses a scalar or a flow collection, which may continue on the
+// following lines until its brackets are balanced
+func (p *parser) inline(l line, text string) (interface{}, error) {
+	if strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") {
+		return nil, l.errorf("anchors and aliases are not supported")
+	}
+	if strings.HasPrefix(text, "!") {
+		return nil, l.errorf("tags are not supported")
+	}
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		for !balanced(text) && !p.done() {
+			text += " " + strings.TrimSpace(p.current().text)
+			p.pos++
+		}
+		f := &flow{text: text, line: l}
+		value, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		f.space()
+		if f.pos < len(f.text) {
+			return nil, l.errorf("unexpected %q after flow collection", f.text[f.pos:])
+		}
+		return value, nil
+	}
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		value, end, err := quoted(l, text)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(text[end:]) != "" {
+			return nil, l.errorf("unexpected %q after quoted string", text[end:])
+		}
+		return value, nil
+	}
+
+	// Plain scalars may be folded over more indented lines
+	if mappingIndicator(text) {
+		return nil, l.errorf("mapping values are not allowed in %q, quote the value or nest the mapping", text)
+	}
+	for !p.done() {
+		next := p.current()
+		if next.text == "" || next.indent <= l.indent || isSequenceItem(next.text) {
+			break
+		}
+		if _, _, ok := splitKey(next.text); ok {
+			break
+		}
+		text += " " + next.text
+		p.pos++
+	}
+	return resolve(text), nil
+}
+
+// blockScalar parses literal (|) and folded (>) scalars with their
+// chomping indicator
+func (p *parser) blockScalar(l line, indent int, header string) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	explicit := 0
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			explicit = int(c - '0')
+		case c == ' ':
+		default:
+			return nil, l.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	var lines []string
+	blockIndent := 0
+	if explicit > 0 {
+		blockIndent = indent + explicit
+	}
+	for !p.done() {
+		next := p.lines[p.pos]
+		if strings.TrimSpace(next.raw) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		rawIndent := len(next.raw) - len(strings.TrimLeft(next.raw, " "))
+		if blockIndent == 0 {
+			if rawIndent <= indent {
+				break
+			}
+			blockIndent = rawIndent
+		}
+		if rawIndent < blockIndent {
+			break
+		}
+		lines = append(lines, next.raw[blockIndent:])
+		p.pos++
+	}
+
+	// Trailing blank lines belong to the chomping, not the content
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var text string
+	if folded {
+		var buf strings.Builder
+		for i, current := range lines {
+			if i > 0 {
+				previous := lines[i-1]
+				switch {
+				case current == "":
+					buf.WriteString("\n")
+				case previous == "":
+				case strings.HasPrefix(current, " ") || strings.HasPrefix(previous, " "):
+					buf.WriteString("\n")
+				default:
+					buf.WriteString(" ")
+				}
+			}
+			buf.WriteString(current)
+		}
+		text = buf.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	switch chomp {
+	case '-':
+	case '+':
+		text += "\n" + strings.Repeat("\n", trailing)
+	default:
+		text += "\n"
+	}
+	return text, nil
+}
+
+// mappingIndicator tells whether a plain scalar contains ": " or ends with
+// ":", which YAML reads as a nested mapping rather than text
+func mappingIndicator(text string) bool {
+	return strings.Contains(text, ": ") || strings.HasSuffix(text, ":")
+}
+
+// splitKey splits "key: value" on the first colon followed by a space or
+// the end of the line, outside of quotes and brackets
+func splitKey(text string) (string, string, bool) {
+	if text == "" || strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+	var quote byte
+	if text[0] == '"' || text[0] == '\'' {
+		quote = text[0]
+	}
+	for i := 1; i < len(text)+1; i++ {
+		if quote != 0 {
+			if i < len(text) && text[i] == quote {
+				if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+					i++
+					continue
+				}
+				quote = 0
+			} else if i < len(text) && text[i] == '\\' && quote == '"' {
+				i++
+			}
+			continue
+		}
+		if i-1 < len(text) && text[i-1] == ':' && (i == len(text) || text[i] == ' ') {
+			if i-1 == 0 {
+				return "", "", false
+			}
+			return strings.TrimSpace(text[:i-1]), strings.TrimSpace(text[i:]), true
+		}
+	}
+	return "", "", false
+}
+
+func parseKey(l line, key string) (string, error) {
+	if strings.HasPrefix(key, "\"") || strings.HasPrefix(key, "'") {
+		value, _, err := quoted(l, key)
+		if err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+	if strings.HasPrefix(key, "&") || strings.HasPrefix(key, "*") || key == "<<" {
+		return "", l.errorf("anchors, aliases and merge keys are not supported")
+	}
+	return key, nil
+}
+
+// quoted decodes the quoted string at the start of text and returns the
+// offset following the closing quote
+func quoted(l line, text string) (string, int, error) {
+	if text[0] == '\'' {
+		var buf strings.Builder
+		for i := 1; i < len(text); i++ {
+			if text[i] == '\'' {
+				if i+1 < len(text) && text[i+1] == '\'' {
+					buf.WriteByte('\'')
+					i++
+					continue
+				}
+				return buf.String(), i + 1, nil
+			}
+			buf.WriteByte(text[i])
+		}
+		return "", 0, l.errorf("unterminated string %s", text)
+	}
+
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := unescape(text[1:i])
+			if err != nil {
+				return "", 0, l.errorf("%v", err)
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, l.errorf("unterminated string %s", text)
+}
+
+func unescape(text string) (string, error) {
+	var buf strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			buf.WriteByte(text[i])
+			continue
+		}
+		i++
+		if i >= len(text) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+		switch text[i] {
+		case '0':
+			buf.WriteByte(0)
+		case 'a':
+			buf.WriteByte('\a')
+		case 'b':
+			buf.WriteByte('\b')
+		case 't', '\t':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'v':
+			buf.WriteByte('\v')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'e':
+			buf.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			buf.WriteByte(text[i])
+		case 'x', 'u', 'U':
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[i]]
+			if i+1+size > len(text) {
+				return "", fmt.Errorf("invalid escape \\%s", text[i:])
+			}
+			code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape \\%s", text[i:i+1+size])
+			}
+			buf.WriteRune(rune(code))
+			i += size
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", text[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+// resolve types a plain scalar following the YAML 1.2 core schema
+func resolve(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return posInf
+	case "-.inf", "-.Inf", "-.INF":
+		return negInf
+	}
+
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		if n, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return n
+		}
+	}
+	if strings.ContainsAny(text, "0123456789") && !strings.ContainsAny(text, "_xXpP") {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+var (
+	posInf = math.Inf(1)
+	negInf = math.Inf(-1)
+)
+
+func balanced(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,81 @@
+package yaml
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := ParseMap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":    int64(8080),
+			"host":    "0.0.0.0",
+			"timeout": 1.5,
+			"debug":   false,
+			"empty":   nil,
+			"url":     "http://example.com:8080/path",
+			"time":    "12:30",
+		},
+		"tags": []interface{}{
+			"api",
+			"web",
+			map[string]interface{}{"name": "admin", "level": int64(2)},
+		},
+		"flow":    map[string]interface{}{"a": int64(1), "b": []interface{}{"x", "y"}},
+		"script":  "echo one\necho two\n",
+		"summary": "folded text",
+	}
+	if !reflect.DeepEqual(document, expected) {
+		t.Errorf("unexpected document:\n got %#v\nwant %#v", document, expected)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{"nested plain mapping", "a: b: c\n", "line 1: mapping values are not allowed"},
+		{"trailing colon", "a: b:\n", "line 1: mapping values are not allowed"},
+		{"nested plain mapping in a sequence", "list:\n  - x: y: z\n", "line 2: mapping values are not allowed"},
+		{"duplicate key", "a: 1\na: 2\n", `line 2: duplicate key "a"`},
+		{"tab indentation", "a:\n\tb: 1\n", "line 2: tabs are not allowed"},
+		{"anchor", "a: &x 1\n", "anchors and aliases are not supported"},
+		{"tag", "a: !!str 1\n", "tags are not supported"},
+		{"multiple documents", "---\na: 1\n---\nb: 2\n", "multiple documents are not supported"},
+		{"unterminated string", "a: \"b\n", "unterminated string"},
+		{"unexpected indentation", "a: 1\n  b: 2\n", "line 2: unexpected indentation"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.document))
+			if err == nil {
+				t.Fatalf("expected an error for %q", test.document)
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected %q in %q", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestQuotedColons(t *testing.T) {
+	document, err := ParseMap([]byte("a: \"b: c\"\nd: 'e:'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document["a"] != "b: c" || document["d"] != "e:" {
+		t.Errorf("unexpected document %#v", document)
+	}
+}
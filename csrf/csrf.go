@@ -0,0 +1,418 @@
+// Package csrf protects cookie authenticated routes against cross-site
+// request forgery:
+//
+//	protection, err := csrf.New(config)
+//	builder.
+//		AddGlobalMiddleware("session", sessions.Middleware()).
+//		AddGlobalMiddleware("csrf", protection.Middleware()).
+//		AddRoutes(csrf.Exempt(webhooks...))
+//
+// Requests with an unsafe method must come from a trusted origin, checked
+// with Sec-Fetch-Site and Origin, and carry the token of the page in the
+// X-CSRF-Token header or the csrf_token form field. Pages add it with the
+// template functions, scripts read it from the page:
+//
+//	<meta name="csrf-token" content="{{ csrfToken }}">
+//	<form method="post">{{ csrfField }}</form>
+//
+// Clients sending neither header, such as scripts outside a browser, are
+// rejected: exempt the routes they call and authenticate them otherwise.
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"goserve/configuration"
+	"goserve/server"
+	"goserve/session"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MetaCSRF is the route meta exempting a route from the checks when false:
+//
+//	server.CreatePOST("/webhooks/stripe", stripe).WithMeta(csrf.MetaCSRF, false)
+const MetaCSRF = server.MetaCSRF
+
+// Modes of Config.Mode
+const (
+	// DoubleSubmit keeps the token in a cookie, compared with the submitted one
+	DoubleSubmit = "double-submit"
+	// Synchronizer keeps the token in the session, see package session. A
+	// new token replaces it once the session ID is renewed, e.g. on login.
+	Synchronizer = "synchronizer"
+)
+
+// sessionKey is the session value holding the token in synchronizer mode,
+// with the ID of the session it was issued for
+const sessionKey = "csrf_token"
+
+const tokenLength = 32
+
+// minSecretLength is the size of the HMAC key signing the cookie
+const minSecretLength = 32
+
+// Config configures the protection, usually bound from the configuration:
+//
+//	builder.Section("app.csrf", &csrfConfig)
+type Config struct {
+	Mode       string `json:"mode" default:"double-submit" description:"where the token is kept: double-submit (cookie) or synchronizer (session)"`
+	CookieName string `json:"cookie-name" default:"csrf_token" description:"cookie of the token in double-submit mode"`
+	HeaderName string `json:"header-name" default:"X-CSRF-Token" description:"request header carrying the token"`
+	FieldName  string `json:"field-name" default:"csrf_token" description:"form field carrying the token"`
+	// Signs the token cookie in double-submit mode, so that a cookie set by
+	// another site, e.g. from a subdomain, is rejected. 32 bytes at least.
+	Secret configuration.Secret `json:"secret" env:"CSRF_SECRET" description:"HMAC key of the token cookie, 32 bytes at least"`
+	// Origins allowed to send requests besides the one of the request, e.g.
+	// https://app.example.com
+	TrustedOrigins []string `json:"trusted-origins" env:"CSRF_TRUSTED_ORIGINS" description:"other origins allowed to send requests"`
+	// Sends the cookie over plain HTTP too, for local development
+	Insecure bool `json:"insecure" description:"send the cookie over plain HTTP too"`
+	// Takes X-Forwarded-Proto into account for the scheme of the request,
+	// behind a TLS terminating proxy only
+	TrustForwardedProto bool `json:"trust-forwarded-proto" description:"read the scheme of requests from X-Forwarded-Proto"`
+}
+
+// Protection checks the origin and the token of unsafe requests
+type Protection struct {
+	config  Config
+	trusted map[string]bool
+}
+
+func New(config Config) (*Protection, error) {
+	if config.Mode == "" {
+		config.Mode = DoubleSubmit
+	}
+	if config.Mode != DoubleSubmit && config.Mode != Synchronizer {
+		return nil, fmt.Errorf("csrf: invalid mode %q, expected %s or %s", config.Mode, DoubleSubmit, Synchronizer)
+	}
+	if config.CookieName == "" {
+		config.CookieName = "csrf_token"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "csrf_token"
+	}
+	if config.Mode == DoubleSubmit && len(config.Secret.Reveal()) < minSecretLength {
+		return nil, fmt.Errorf("csrf: double-submit mode needs a secret of %d bytes at least to sign the cookie", minSecretLength)
+	}
+
+	p := &Protection{config: config, trusted: make(map[string]bool)}
+	for _, origin := range config.TrustedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("csrf: invalid trusted origin %q, expected scheme://host[:port]", origin)
+		}
+		p.trusted[strings.ToLower(u.Scheme+"://"+u.Host)] = true
+	}
+	return p, nil
+}
+
+// Middleware makes the token available to handlers and rejects unsafe
+// requests from other origins or without a valid token with a 403 problem.
+// In synchronizer mode it must come after the session middleware, and the
+// token is only added to the session when a page asks for it, which must
+// happen before the response is written.
+func (p *Protection) Middleware() server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route, ok := server.RouteFromContext(r.Context()); ok {
+				if value, _ := route.GetMeta(MetaCSRF); value == false {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			token, err := p.token(w, r)
+			if err != nil {
+				log.Printf("CSRF token: %v", err)
+				server.WriteError(w, err)
+				return
+			}
+			if !safeMethod(r.Method) {
+				if detail := p.checkOrigin(r); detail != "" {
+					forbidden(w, detail)
+					return
+				}
+				if detail := p.checkToken(r, token); detail != "" {
+					forbidden(w, detail)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), stateKey{}, &state{protection: p, request: r, token: token})))
+		})
+	}
+}
+
+// Exempt marks routes called by other sites without a token, e.g. webhooks
+// authenticated otherwise
+func Exempt(routes ...server.RouteInfo) []server.RouteInfo {
+	for _, route := range routes {
+		route.WithMeta(MetaCSRF, false)
+	}
+	return routes
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func forbidden(w http.ResponseWriter, detail string) {
+	server.WriteProblem(w, server.NewProblem(http.StatusForbidden, detail))
+}
+
+// token returns the token of the visitor. The cookie of double-submit mode
+// is set on the first request, since it cannot be once the page is
+// written; in synchronizer mode, the token is nil until a page asks for one.
+func (p *Protection) token(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if p.config.Mode == Synchronizer {
+		s := session.FromContext(r.Context())
+		if s == nil {
+			return nil, fmt.Errorf("csrf: synchronizer mode needs the session middleware before it")
+		}
+		return sessionToken(s), nil
+	}
+
+	if cookie, err := r.Cookie(p.config.CookieName); err == nil {
+		if token, ok := p.verifyCookie(cookie.Value); ok {
+			return token, nil
+		}
+	}
+	token := newToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.config.CookieName,
+		Value:    p.signCookie(token),
+		Path:     "/",
+		Secure:   !p.config.Insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// signCookie returns the value of the cookie: the token and its HMAC
+func (p *Protection) signCookie(token []byte) string {
+	return base64.RawURLEncoding.EncodeToString(token) + "." + base64.RawURLEncoding.EncodeToString(p.mac(token))
+}
+
+func (p *Protection) verifyCookie(value string) ([]byte, bool) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	token, ok := decodeToken(encoded)
+	if !ok {
+		return nil, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, p.mac(token)) {
+		return nil, false
+	}
+	return token, true
+}
+
+func (p *Protection) mac(token []byte) []byte {
+	h := hmac.New(sha256.New, []byte(p.config.Secret.Reveal()))
+	h.Write([]byte(p.config.CookieName))
+	h.Write(token)
+	return h.Sum(nil)
+}
+
+// checkOrigin returns why the request is rejected, empty when it comes from
+// the origin of the request or a trusted one. Browsers send Sec-Fetch-Site
+// or Origin on unsafe requests, so requests with neither are rejected.
+func (p *Protection) checkOrigin(r *http.Request) string {
+	site := r.Header.Get("Sec-Fetch-Site")
+	if site == "same-origin" || site == "none" {
+		return ""
+	}
+
+	source := r.Header.Get("Origin")
+	switch {
+	case source == "" && site == "":
+		return "cross-site request rejected: missing Origin and Sec-Fetch-Site headers"
+	case source == "":
+		return fmt.Sprintf("cross-site request rejected: Sec-Fetch-Site is %s", site)
+	case source == "null":
+		return "cross-site request rejected: opaque origin"
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Sprintf("cross-site request rejected: invalid origin %q", source)
+	}
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+	if origin == strings.ToLower(p.scheme(r)+"://"+r.Host) || p.trusted[origin] {
+		return ""
+	}
+	return fmt.Sprintf("cross-site request rejected: origin %s is not trusted", origin)
+}
+
+// scheme returns the scheme the request was sent with
+func (p *Protection) scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if p.config.TrustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		return "https"
+	}
+	return "http"
+}
+
+// checkToken returns why the submitted token is rejected, empty when valid
+func (p *Protection) checkToken(r *http.Request, expected []byte) string {
+	submitted := r.Header.Get(p.config.HeaderName)
+	if submitted == "" {
+		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") || strings.HasPrefix(contentType, "multipart/form-data") {
+			submitted = r.PostFormValue(p.config.FieldName)
+		}
+	}
+	if submitted == "" {
+		return fmt.Sprintf("missing CSRF token: send it in the %s header or the %s form field", p.config.HeaderName, p.config.FieldName)
+	}
+	token, ok := unmask(submitted)
+	if !ok || expected == nil || subtle.ConstantTimeCompare(token, expected) != 1 {
+		return "invalid CSRF token: reload the page and try again"
+	}
+	return ""
+}
+
+func newToken() []byte {
+	token := make([]byte, tokenLength)
+	rand.Read(token)
+	return token
+}
+
+func decodeToken(value string) ([]byte, bool) {
+	token, err := base64.RawURLEncoding.DecodeString(value)
+	return token, err == nil && len(token) == tokenLength
+}
+
+// mask encodes the token with a random pad, so that pages never contain the
+// same bytes and compression attacks such as BREACH cannot recover it
+func mask(token []byte) string {
+	masked := make([]byte, 2*tokenLength)
+	rand.Read(masked[:tokenLength])
+	for i, b := range token {
+		masked[tokenLength+i] = b ^ masked[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// unmask decodes a masked token; raw tokens are rejected
+func unmask(value string) ([]byte, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(decoded) != 2*tokenLength {
+		return nil, false
+	}
+	token := make([]byte, tokenLength)
+	for i := range token {
+		token[i] = decoded[tokenLength+i] ^ decoded[i]
+	}
+	return token, true
+}
+
+type stateKey struct{}
+
+type state struct {
+	protection *Protection
+	request    *http.Request
+	token      []byte
+}
+
+// current returns the token of the request. In synchronizer mode it is
+// read from the session each time, since the handler may have renewed its
+// ID, and added to it on first use.
+func (s *state) current() ([]byte, error) {
+	if s.protection.config.Mode != Synchronizer {
+		return s.token, nil
+	}
+	visitor := session.FromContext(s.request.Context())
+	if visitor == nil {
+		return nil, errors.New("csrf: synchronizer mode needs the session middleware before it")
+	}
+	if token := sessionToken(visitor); token != nil {
+		return token, nil
+	}
+	token := newToken()
+	visitor.Set(sessionKey, base64.RawURLEncoding.EncodeToString(token)+"."+visitor.ID())
+	return token, nil
+}
+
+// sessionToken returns the token kept in the session, nil when there is
+// none or when it was issued before the session ID was renewed, so that a
+// token known before a login is rejected after it
+func sessionToken(s *session.Session) []byte {
+	encoded, id, _ := strings.Cut(s.GetString(sessionKey), ".")
+	if id != s.ID() {
+		return nil
+	}
+	token, ok := decodeToken(encoded)
+	if !ok {
+		return nil
+	}
+	return token
+}
+
+func stateOf(r *http.Request) *state {
+	if r == nil {
+		return nil
+	}
+	s, _ := r.Context().Value(stateKey{}).(*state)
+	return s
+}
+
+// Token returns the token to send with the next unsafe request, masked
+// differently on each call; empty without the middleware
+func Token(r *http.Request) string {
+	s := stateOf(r)
+	if s == nil {
+		return ""
+	}
+	token, err := s.current()
+	if err != nil {
+		log.Printf("CSRF token: %v", err)
+		return ""
+	}
+	return mask(token)
+}
+
+// Field returns the hidden input carrying the token in HTML forms
+func Field(r *http.Request) template.HTML {
+	s := stateOf(r)
+	token := Token(r)
+	if token == "" {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(s.protection.config.FieldName), token))
+}
+
+// TemplateFuncs returns csrfToken and csrfField for the templates rendering
+// the request. Templates are parsed with the functions of a nil request and
+// cloned per request, since Funcs cannot change a template being executed:
+//
+//	var page = template.Must(template.New("page").Funcs(csrf.TemplateFuncs(nil)).Parse(source))
+//
+//	template.Must(page.Clone()).Funcs(csrf.TemplateFuncs(r)).Execute(w, data)
+func TemplateFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": func() string { return Token(r) },
+		"csrfField": func() template.HTML { return Field(r) },
+	}
+}
@@ -0,0 +1,337 @@
+package csrf
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"goserve/configuration"
+	"goserve/goservetest"
+	"goserve/server"
+	"goserve/session"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testSecret = configuration.Secret("0123456789abcdef0123456789abcdef")
+
+func TestMask(t *testing.T) {
+	token := newToken()
+	first, second := mask(token), mask(token)
+	if first == second {
+		t.Error("expected the token to be masked differently on each call")
+	}
+	for _, masked := range []string{first, second} {
+		unmasked, ok := unmask(masked)
+		if !ok || !bytes.Equal(unmasked, token) {
+			t.Errorf("%s: expected the token back, got %x", masked, unmasked)
+		}
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(token)
+	for _, value := range []string{raw, "", "not base64!", first[:len(first)-2]} {
+		if _, ok := unmask(value); ok {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestCookieSignature(t *testing.T) {
+	p, err := New(Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(Config{Secret: testSecret + "!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := newToken()
+	signed := p.signCookie(token)
+	if verified, ok := p.verifyCookie(signed); !ok || !bytes.Equal(verified, token) {
+		t.Fatalf("expected the signed cookie to verify, got %x", verified)
+	}
+
+	encoded, signature, _ := strings.Cut(signed, ".")
+	forged := base64.RawURLEncoding.EncodeToString(newToken()) + "." + signature
+	tests := map[string]string{
+		"unsigned":     encoded,
+		"forged token": forged,
+		"bad encoding": encoded + ".%%",
+		"empty":        "",
+	}
+	for name, value := range tests {
+		if _, ok := p.verifyCookie(value); ok {
+			t.Errorf("%s: expected %q to be rejected", name, value)
+		}
+	}
+	if _, ok := other.verifyCookie(signed); ok {
+		t.Error("expected a cookie signed with another secret to be rejected")
+	}
+
+	if _, err := New(Config{}); err == nil {
+		t.Error("expected double-submit mode to require a secret")
+	}
+	if _, err := New(Config{Mode: Synchronizer}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	p, err := New(Config{Secret: testSecret, TrustedOrigins: []string{"https://app.example.com"}, TrustForwardedProto: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		tls    bool
+		// Expected rejection, empty when the request is accepted
+		detail string
+	}{
+		{name: "same origin fetch", header: map[string]string{"Sec-Fetch-Site": "same-origin"}},
+		{name: "typed address", header: map[string]string{"Sec-Fetch-Site": "none"}},
+		{name: "same origin", header: map[string]string{"Origin": "http://example.com"}},
+		{name: "same origin over TLS", header: map[string]string{"Origin": "https://example.com"}, tls: true},
+		{name: "forwarded TLS", header: map[string]string{"Origin": "https://example.com", "X-Forwarded-Proto": "https"}},
+		{name: "trusted origin", header: map[string]string{"Origin": "https://app.example.com", "Sec-Fetch-Site": "same-site"}},
+		{
+			name:   "no headers",
+			detail: "cross-site request rejected: missing Origin and Sec-Fetch-Site headers",
+		},
+		{
+			name:   "referer only",
+			header: map[string]string{"Referer": "http://example.com/form"},
+			detail: "cross-site request rejected: missing Origin and Sec-Fetch-Site headers",
+		},
+		{
+			name:   "cross site without origin",
+			header: map[string]string{"Sec-Fetch-Site": "cross-site"},
+			detail: "cross-site request rejected: Sec-Fetch-Site is cross-site",
+		},
+		{
+			name:   "other scheme",
+			header: map[string]string{"Origin": "http://example.com"},
+			tls:    true,
+			detail: "cross-site request rejected: origin http://example.com is not trusted",
+		},
+		{
+			name:   "trusted host with another scheme",
+			header: map[string]string{"Origin": "http://app.example.com"},
+			detail: "cross-site request rejected: origin http://app.example.com is not trusted",
+		},
+		{
+			name:   "other site",
+			header: map[string]string{"Origin": "https://evil.example.net", "Sec-Fetch-Site": "cross-site"},
+			detail: "cross-site request rejected: origin https://evil.example.net is not trusted",
+		},
+		{
+			name:   "opaque origin",
+			header: map[string]string{"Origin": "null"},
+			detail: "cross-site request rejected: opaque origin",
+		},
+		{
+			name:   "invalid origin",
+			header: map[string]string{"Origin": "example.com"},
+			detail: `cross-site request rejected: invalid origin "example.com"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			if test.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for key, value := range test.header {
+				r.Header.Set(key, value)
+			}
+			if detail := p.checkOrigin(r); detail != test.detail {
+				t.Errorf("expected %q, got %q", test.detail, detail)
+			}
+		})
+	}
+}
+
+// tokenRoutes renders the token on GET /form and accepts POST /orders
+func tokenRoutes() []server.RouteInfo {
+	return []server.RouteInfo{
+		server.CreateGET("/form", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, Token(r))
+		}),
+		server.CreateGET("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "home")
+		}),
+		server.CreatePOST("/orders", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}),
+	}
+}
+
+func cookieNamed(response *goservetest.Response, name string) *http.Cookie {
+	for _, cookie := range response.Raw().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestDoubleSubmit(t *testing.T) {
+	protection, err := New(Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := server.New().
+		AddGlobalMiddleware("csrf", protection.Middleware()).
+		AddRoutes(tokenRoutes()).
+		AddRoutes(Exempt(server.CreatePOST("/webhooks", func(w http.ResponseWriter, r *http.Request) {})))
+	srv := goservetest.New(t, builder)
+
+	page := srv.GET("/form").Expect(t).Status(http.StatusOK)
+	cookie := cookieNamed(page, "csrf_token")
+	if cookie == nil || !cookie.HttpOnly || !strings.Contains(cookie.Value, ".") {
+		t.Fatalf("expected a signed HttpOnly cookie, got %v", cookie)
+	}
+	token := string(page.BodyBytes())
+
+	origin := "http://example.com"
+	srv.POST("/orders").WithCookie(cookie).WithHeader("Origin", origin).WithHeader("X-CSRF-Token", token).
+		Expect(t).Status(http.StatusCreated)
+	srv.POST("/orders").WithCookie(cookie).WithHeader("Origin", origin).
+		WithBody("application/x-www-form-urlencoded", []byte(url.Values{"csrf_token": {token}}.Encode())).
+		Expect(t).Status(http.StatusCreated)
+
+	rejected := []struct {
+		name    string
+		request *goservetest.Request
+		detail  string
+	}{
+		{
+			name:    "missing token",
+			request: srv.POST("/orders").WithCookie(cookie).WithHeader("Origin", origin),
+			detail:  "missing CSRF token: send it in the X-CSRF-Token header or the csrf_token form field",
+		},
+		{
+			name:    "raw token of the cookie",
+			request: srv.POST("/orders").WithCookie(cookie).WithHeader("Origin", origin).WithHeader("X-CSRF-Token", strings.Split(cookie.Value, ".")[0]),
+			detail:  "invalid CSRF token: reload the page and try again",
+		},
+		{
+			name: "unsigned cookie set by another site",
+			request: srv.POST("/orders").
+				WithCookie(&http.Cookie{Name: "csrf_token", Value: strings.Split(cookie.Value, ".")[0]}).
+				WithHeader("Origin", origin).WithHeader("X-CSRF-Token", token),
+			detail: "invalid CSRF token: reload the page and try again",
+		},
+		{
+			name:    "other origin",
+			request: srv.POST("/orders").WithCookie(cookie).WithHeader("Origin", "https://evil.example.net").WithHeader("X-CSRF-Token", token),
+			detail:  "cross-site request rejected: origin https://evil.example.net is not trusted",
+		},
+	}
+	for _, test := range rejected {
+		t.Run(test.name, func(t *testing.T) {
+			problem := test.request.Expect(t).Status(http.StatusForbidden).Problem()
+			if problem.Detail != test.detail {
+				t.Errorf("expected %q, got %q", test.detail, problem.Detail)
+			}
+		})
+	}
+
+	srv.POST("/webhooks").Expect(t).Status(http.StatusOK)
+}
+
+func TestSynchronizer(t *testing.T) {
+	protection, err := New(Config{Mode: Synchronizer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := configuration.Secret(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	sessions, err := session.NewManager(session.NewMemoryStore(), session.Config{Keys: []configuration.Secret{key}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := server.New().
+		AddGlobalMiddleware("session", sessions.Middleware()).
+		AddGlobalMiddleware("csrf", protection.Middleware()).
+		AddRoutes(tokenRoutes())
+	srv := goservetest.New(t, builder)
+
+	if cookie := cookieNamed(srv.GET("/").Expect(t).Status(http.StatusOK), "session"); cookie != nil {
+		t.Errorf("expected pages without a token not to create a session, got %v", cookie)
+	}
+
+	page := srv.GET("/form").Expect(t).Status(http.StatusOK)
+	cookie := cookieNamed(page, "session")
+	if cookie == nil {
+		t.Fatal("expected the token to be kept in a new session")
+	}
+	token := string(page.BodyBytes())
+
+	// The token is created once and kept in the session
+	if again := cookieNamed(srv.GET("/form").WithCookie(cookie).Expect(t), "session"); again != nil {
+		t.Errorf("expected the session to be left unchanged, got %v", again)
+	}
+	srv.POST("/orders").WithCookie(cookie).WithHeader("Sec-Fetch-Site", "same-origin").WithHeader("X-CSRF-Token", token).
+		Expect(t).Status(http.StatusCreated)
+	srv.POST("/orders").WithHeader("Sec-Fetch-Site", "same-origin").WithHeader("X-CSRF-Token", token).
+		Expect(t).Status(http.StatusForbidden)
+}
+
+func TestSynchronizerRotatesOnRenewID(t *testing.T) {
+	protection, err := New(Config{Mode: Synchronizer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := session.NewManager(session.NewMemoryStore(), session.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := func(w http.ResponseWriter, r *http.Request) {
+		session.FromContext(r.Context()).RenewID()
+		if r.URL.Query().Has("page") {
+			fmt.Fprint(w, Token(r))
+		}
+	}
+	builder := server.New().
+		AddGlobalMiddleware("session", sessions.Middleware()).
+		AddGlobalMiddleware("csrf", protection.Middleware()).
+		AddRoutes(append(tokenRoutes(), server.CreatePOST("/login", login)))
+	srv := goservetest.New(t, builder)
+
+	post := func(path string, cookie *http.Cookie, token string) *goservetest.Response {
+		return srv.POST(path).WithCookie(cookie).WithHeader("Sec-Fetch-Site", "same-origin").WithHeader("X-CSRF-Token", token).Expect(t)
+	}
+
+	t.Run("token of the login page", func(t *testing.T) {
+		page := srv.GET("/form").Expect(t).Status(http.StatusOK)
+		before, cookie := string(page.BodyBytes()), cookieNamed(page, "session")
+
+		response := post("/login?page", cookie, before).Status(http.StatusOK)
+		after, renewed := string(response.BodyBytes()), cookieNamed(response, "session")
+		if renewed == nil || renewed.Value == cookie.Value {
+			t.Fatalf("expected a renewed session cookie, got %v", renewed)
+		}
+		first, _ := unmask(before)
+		second, _ := unmask(after)
+		if bytes.Equal(first, second) {
+			t.Error("expected a new token once the session ID is renewed")
+		}
+
+		post("/orders", renewed, before).Status(http.StatusForbidden)
+		post("/orders", renewed, after).Status(http.StatusCreated)
+	})
+
+	t.Run("token of the next page", func(t *testing.T) {
+		page := srv.GET("/form").Expect(t).Status(http.StatusOK)
+		before, cookie := string(page.BodyBytes()), cookieNamed(page, "session")
+		renewed := cookieNamed(post("/login", cookie, before).Status(http.StatusOK), "session")
+
+		post("/orders", renewed, before).Status(http.StatusForbidden)
+		next := srv.GET("/form").WithCookie(renewed).Expect(t).Status(http.StatusOK)
+		post("/orders", renewed, string(next.BodyBytes())).Status(http.StatusCreated)
+	})
+}
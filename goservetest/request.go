@@ -0,0 +1,209 @@
+package goservetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"goserve/server"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Request is built fluently and sent with Expect or Do
+type Request struct {
+	server      *Server
+	method      string
+	path        string
+	header      http.Header
+	query       url.Values
+	body        []byte
+	contentType string
+	encodeErr   error
+}
+
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+func (r *Request) WithQuery(key, value string) *Request {
+	if r.query == nil {
+		r.query = make(url.Values)
+	}
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) WithBearer(token string) *Request {
+	return r.WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithCookie sends the name and value of cookie, as a browser would, such
+// as a cookie read from a Set-Cookie header. Cookies added by several calls
+// share the Cookie header.
+func (r *Request) WithCookie(cookie *http.Cookie) *Request {
+	pair := (&http.Cookie{Name: cookie.Name, Value: cookie.Value}).String()
+	if existing := r.header.Get("Cookie"); existing != "" {
+		pair = existing + "; " + pair
+	}
+	r.header.Set("Cookie", pair)
+	return r
+}
+
+// WithJSON encodes v as the request body
+func (r *Request) WithJSON(v interface{}) *Request {
+	r.body, r.encodeErr = json.Marshal(v)
+	r.contentType = "application/json"
+	return r
+}
+
+func (r *Request) WithBody(contentType string, body []byte) *Request {
+	r.body = body
+	r.contentType = contentType
+	return r
+}
+
+// Do sends the request in-process and returns the recorded response
+func (r *Request) Do() *http.Response {
+	target := r.path
+	if len(r.query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, target, bytes.NewReader(r.body))
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	if r.contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+
+	recorder := httptest.NewRecorder()
+	r.server.handler.ServeHTTP(recorder, req)
+	return recorder.Result()
+}
+
+// Expect sends the request and returns the response for assertions
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	if r.encodeErr != nil {
+		t.Fatalf("%s %s: encoding body: %v", r.method, r.path, r.encodeErr)
+	}
+
+	start := time.Now()
+	resp := r.Do()
+	duration := time.Since(start)
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", r.method, r.path, err)
+	}
+
+	return &Response{
+		t:        t,
+		request:  r.method + " " + r.path,
+		response: resp,
+		body:     body,
+		duration: duration,
+	}
+}
+
+// Response holds a recorded response; assertion failures are reported with
+// t.Errorf so that every check runs
+type Response struct {
+	t        testing.TB
+	request  string
+	response *http.Response
+	body     []byte
+	duration time.Duration
+}
+
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.response.StatusCode != code {
+		r.t.Errorf("%s: expected status %d, got %d\n%s", r.request, code, r.response.StatusCode, truncate(r.body))
+	}
+	return r
+}
+
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if actual := r.response.Header.Get(key); actual != value {
+		r.t.Errorf("%s: expected header %s %q, got %q", r.request, key, value, actual)
+	}
+	return r
+}
+
+func (r *Response) HeaderContains(key, fragment string) *Response {
+	r.t.Helper()
+	if actual := r.response.Header.Get(key); !strings.Contains(actual, fragment) {
+		r.t.Errorf("%s: expected header %s to contain %q, got %q", r.request, key, fragment, actual)
+	}
+	return r
+}
+
+func (r *Response) Body(expected string) *Response {
+	r.t.Helper()
+	if string(r.body) != expected {
+		r.t.Errorf("%s: expected body %q, got %q", r.request, expected, truncate(r.body))
+	}
+	return r
+}
+
+func (r *Response) BodyContains(fragment string) *Response {
+	r.t.Helper()
+	if !strings.Contains(string(r.body), fragment) {
+		r.t.Errorf("%s: expected body to contain %q, got %q", r.request, fragment, truncate(r.body))
+	}
+	return r
+}
+
+func (r *Response) FasterThan(limit time.Duration) *Response {
+	r.t.Helper()
+	if r.duration > limit {
+		r.t.Errorf("%s: took %s, limit is %s", r.request, r.duration, limit)
+	}
+	return r
+}
+
+// JSON decodes the body into v and stops the test if it cannot
+func (r *Response) JSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.body, v); err != nil {
+		r.t.Fatalf("%s: decoding JSON body: %v\n%s", r.request, err, truncate(r.body))
+	}
+	return r
+}
+
+// Problem decodes an application/problem+json body
+func (r *Response) Problem() *server.Problem {
+	r.t.Helper()
+	r.HeaderContains("Content-Type", server.ProblemContentType)
+	var problem server.Problem
+	r.JSON(&problem)
+	return &problem
+}
+
+func (r *Response) Raw() *http.Response {
+	return r.response
+}
+
+func (r *Response) BodyBytes() []byte {
+	return r.body
+}
+
+func truncate(body []byte) string {
+	if len(body) > 512 {
+		return string(body[:512]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,28 @@
+package goservetest
+
+import (
+	"goserve/server"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithCookie(t *testing.T) {
+	srv := New(t, server.New().GET("/cookies", func(w http.ResponseWriter, r *http.Request) {
+		var pairs []string
+		for _, cookie := range r.Cookies() {
+			pairs = append(pairs, cookie.Name+"="+cookie.Value)
+		}
+		w.Write([]byte(strings.Join(pairs, " ") + "\n" + strings.Join(r.Header.Values("Cookie"), " | ")))
+	}))
+
+	// Attributes of a cookie read from Set-Cookie are not sent back
+	session := &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true, Secure: true, Expires: time.Now().Add(time.Hour), SameSite: http.SameSiteLaxMode}
+	srv.GET("/cookies").
+		WithCookie(session).
+		WithCookie(&http.Cookie{Name: "theme", Value: "dark mode"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body("session=abc theme=dark mode\nsession=abc; theme=\"dark mode\"")
+}
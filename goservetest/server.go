@@ -0,0 +1,267 @@
+package goservetest
+
+import (
+	"bytes"
+	"fmt"
+	"goserve/configuration"
+	"goserve/httpfile"
+	"goserve/server"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is a built goserve server answering requests in-process
+type Server struct {
+	server  server.HttpServer
+	handler http.Handler
+	logs    *logCapture
+
+	mu         sync.Mutex
+	httpServer *httptest.Server
+}
+
+type settings struct {
+	configOptions []configuration.ConfigOption
+	replaced      []server.MiddlewareInfo
+	removed       []string
+}
+
+type Option func(*settings)
+
+// WithConfig loads a configuration with the given overrides (on top of the
+// defaults and environment variables) and applies it to the builder
+func WithConfig(options ...configuration.ConfigOption) Option {
+	return func(s *settings) {
+		s.configOptions = append(s.configOptions, options...)
+	}
+}
+
+// WithMiddleware replaces the global middleware registered under name, or
+// adds it when there is none. The replacement stands in for the route meta
+// the original enforced, e.g. a fake authentication, so Build accepts the
+// routes relying on it.
+func WithMiddleware(name string, middleware server.MiddlewareFunc) Option {
+	return func(s *settings) {
+		s.replaced = append(s.replaced, server.MiddlewareInfo{Name: name, Middleware: middleware})
+	}
+}
+
+// WithoutMiddleware removes a global middleware, e.g. authentication. The
+// routes it protected are served unprotected, which Build accepts in tests.
+func WithoutMiddleware(name string) Option {
+	return func(s *settings) {
+		s.removed = append(s.removed, name)
+	}
+}
+
+// New builds the builder into a test server. Logs written during the test
+// are captured and reported through t, and everything is released with
+// t.Cleanup. The logs are read from the global logger, so tests creating
+// servers must not call t.Parallel: New fails when another test is capturing
+// them.
+func New(t testing.TB, builder server.ServerBuilder, options ...Option) *Server {
+	t.Helper()
+
+	s := &settings{}
+	for _, option := range options {
+		option(s)
+	}
+
+	logs, err := captureLogs(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.configOptions) > 0 {
+		config, err := configuration.New().Load(s.configOptions...)
+		if err != nil {
+			t.Fatalf("goservetest: loading configuration: %v", err)
+		}
+		builder.WithConfiguration(config)
+	}
+	for _, mw := range s.replaced {
+		builder.ReplaceGlobalMiddleware(mw.Name, standIn(builder, mw.Name, mw.Middleware))
+	}
+	for _, name := range s.removed {
+		builder.ReplaceGlobalMiddleware(name, standIn(builder, name, nil))
+	}
+
+	built := builder.Build()
+	srv := &Server{
+		server:  built,
+		handler: built.GetHttpServer().Handler,
+		logs:    logs,
+	}
+
+	t.Cleanup(func() {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if srv.httpServer != nil {
+			srv.httpServer.Close()
+		}
+	})
+
+	return srv
+}
+
+// standIn returns replacement, or a pass-through when nil, claiming the
+// route meta enforced by the middleware registered under name
+func standIn(builder server.ServerBuilder, name string, replacement server.MiddlewareFunc) server.MiddlewareFunc {
+	var original server.MiddlewareFunc
+	for _, mw := range builder.GetMiddlewares() {
+		if mw.Name == name {
+			original = mw.Middleware
+		}
+	}
+	if replacement == nil {
+		replacement = func(next http.Handler) http.Handler { return next }
+	}
+	if original == nil {
+		return replacement
+	}
+	enforcer, ok := original(http.NotFoundHandler()).(server.MetaEnforcer)
+	if !ok {
+		return replacement
+	}
+	return func(next http.Handler) http.Handler {
+		return server.EnforcedHandler{Handler: replacement(next), Enforces: enforcer.EnforcesMeta}
+	}
+}
+
+// Handler returns the handler of the built server
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// HttpServer returns the built server
+func (s *Server) HttpServer() server.HttpServer {
+	return s.server
+}
+
+// URL starts a real listener on first use, for clients that need one
+func (s *Server) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpServer == nil {
+		s.httpServer = httptest.NewServer(s.handler)
+	}
+	return s.httpServer.URL
+}
+
+// Logs returns what was logged since the server was created
+func (s *Server) Logs() string {
+	return s.logs.String()
+}
+
+func (s *Server) GET(path string) *Request {
+	return s.Request(http.MethodGet, path)
+}
+
+func (s *Server) POST(path string) *Request {
+	return s.Request(http.MethodPost, path)
+}
+
+func (s *Server) PUT(path string) *Request {
+	return s.Request(http.MethodPut, path)
+}
+
+func (s *Server) PATCH(path string) *Request {
+	return s.Request(http.MethodPatch, path)
+}
+
+func (s *Server) DELETE(path string) *Request {
+	return s.Request(http.MethodDelete, path)
+}
+
+func (s *Server) Request(method, path string) *Request {
+	return &Request{
+		server: s,
+		method: method,
+		path:   path,
+		header: make(http.Header),
+	}
+}
+
+// RunHTTPFile executes a .http file against the server, see httpfile.Run
+func (s *Server) RunHTTPFile(t *testing.T, filename string, options ...httpfile.Option) {
+	t.Helper()
+	httpfile.Run(t, s.server, filename, options...)
+}
+
+// logCapture redirects the standard logger for the duration of a test. The
+// framework logs through the global logger, so captures are stacked: a test
+// or one of its subtests may capture again, but two tests running in
+// parallel cannot share it.
+type logCapture struct {
+	mu     sync.Mutex
+	t      testing.TB
+	buf    bytes.Buffer
+	closed bool
+}
+
+var captures struct {
+	mu    sync.Mutex
+	stack []*logCapture
+	// Logger settings restored when the last capture ends
+	output io.Writer
+	flags  int
+}
+
+func captureLogs(t testing.TB) (*logCapture, error) {
+	captures.mu.Lock()
+	defer captures.mu.Unlock()
+
+	if n := len(captures.stack); n > 0 {
+		current := captures.stack[n-1].t.Name()
+		if t.Name() != current && !strings.HasPrefix(t.Name(), current+"/") {
+			return nil, fmt.Errorf("goservetest: %s and %s capture the global logger at the same time; servers cannot be used from parallel tests", current, t.Name())
+		}
+	} else {
+		captures.output = log.Writer()
+		captures.flags = log.Flags()
+	}
+
+	capture := &logCapture{t: t}
+	captures.stack = append(captures.stack, capture)
+	log.SetOutput(capture)
+	log.SetFlags(0)
+
+	t.Cleanup(func() {
+		capture.mu.Lock()
+		capture.closed = true
+		capture.mu.Unlock()
+
+		captures.mu.Lock()
+		defer captures.mu.Unlock()
+		captures.stack = slices.DeleteFunc(captures.stack, func(c *logCapture) bool { return c == capture })
+		if n := len(captures.stack); n > 0 {
+			log.SetOutput(captures.stack[n-1])
+		} else {
+			log.SetOutput(captures.output)
+			log.SetFlags(captures.flags)
+		}
+	})
+	return capture, nil
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf.Write(p)
+	if !c.closed {
+		c.t.Log(strings.TrimRight(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (c *logCapture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
@@ -0,0 +1,164 @@
+package goservetest
+
+import (
+	"fmt"
+	"goserve/configuration"
+	"goserve/server"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func builder() server.ServerBuilder {
+	return server.New().
+		AddGlobalMiddleware("auth", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					server.WriteProblem(w, server.NewProblem(http.StatusUnauthorized, "missing token"))
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}).
+		GET("/items", func(w http.ResponseWriter, r *http.Request) {
+			log.Printf("listing items of page %s", r.URL.Query().Get("page"))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"id":1,"name":"first"}]`)
+		})
+}
+
+func TestRequests(t *testing.T) {
+	srv := New(t, builder())
+
+	var items []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	srv.GET("/items").WithBearer("secret").WithQuery("page", "2").Expect(t).
+		Status(http.StatusOK).
+		HeaderContains("Content-Type", "json").
+		JSON(&items)
+	if len(items) != 1 || items[0].Name != "first" {
+		t.Errorf("unexpected items %+v", items)
+	}
+
+	problem := srv.GET("/items").Expect(t).Status(http.StatusUnauthorized).Problem()
+	if problem.Detail != "missing token" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+
+	if !strings.Contains(srv.Logs(), "listing items of page 2") {
+		t.Errorf("handler logs not captured:\n%s", srv.Logs())
+	}
+}
+
+func TestMiddlewareOverrides(t *testing.T) {
+	t.Run("removed", func(t *testing.T) {
+		srv := New(t, builder(), WithoutMiddleware("auth"))
+		srv.GET("/items").Expect(t).Status(http.StatusOK)
+	})
+	t.Run("replaced", func(t *testing.T) {
+		deny := func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+		}
+		srv := New(t, builder(), WithMiddleware("auth", deny))
+		srv.GET("/items").WithBearer("secret").Expect(t).Status(http.StatusForbidden)
+	})
+}
+
+func TestWithConfig(t *testing.T) {
+	srv := New(t, builder(), WithConfig(func(c *configuration.Config) { c.Server.Port = 9191 }))
+	if addr := srv.HttpServer().GetHttpServer().Addr; addr != ":9191" {
+		t.Errorf("expected the configured port, got %q", addr)
+	}
+}
+
+func TestURL(t *testing.T) {
+	srv := New(t, builder())
+	req, _ := http.NewRequest(http.MethodGet, srv.URL()+"/items", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+// recorder is a testing.TB whose failures are recorded instead of reported
+type recorder struct {
+	testing.TB
+	name     string
+	failures []string
+	cleanups []func()
+}
+
+func (r *recorder) Name() string       { return r.name }
+func (r *recorder) Helper()            {}
+func (r *recorder) Log(...interface{}) {}
+func (r *recorder) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) cleanup() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestAssertionFailures(t *testing.T) {
+	srv := New(t, builder())
+	r := &recorder{name: t.Name()}
+	srv.GET("/items").Expect(r).
+		Status(http.StatusOK).
+		Header("Content-Type", "text/plain").
+		BodyContains("first")
+
+	if len(r.failures) != 3 {
+		t.Fatalf("expected 3 failures, got %q", r.failures)
+	}
+	if !strings.Contains(r.failures[0], "expected status 200, got 401") {
+		t.Errorf("unexpected failure %q", r.failures[0])
+	}
+}
+
+func TestLogCaptureStack(t *testing.T) {
+	previous := log.Writer()
+
+	parent := &recorder{name: "TestParent"}
+	first, err := captureLogs(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := &recorder{name: "TestParent/child"}
+	second, err := captureLogs(child)
+	if err != nil {
+		t.Fatalf("a subtest must be able to capture: %v", err)
+	}
+	if _, err := captureLogs(&recorder{name: "TestOther"}); err == nil || !strings.Contains(err.Error(), "parallel") {
+		t.Errorf("expected a parallel capture to fail, got %v", err)
+	}
+	if _, err := captureLogs(&recorder{name: "TestParent/sibling"}); err == nil {
+		t.Error("expected a parallel sibling capture to fail")
+	}
+
+	log.Print("to the child")
+	child.cleanup()
+	log.Print("to the parent")
+	parent.cleanup()
+
+	if second.String() != "to the child\n" || first.String() != "to the parent\n" {
+		t.Errorf("logs mixed: parent %q, child %q", first.String(), second.String())
+	}
+	if log.Writer() != previous {
+		t.Error("the logger output was not restored")
+	}
+}
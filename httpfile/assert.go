@@ -0,0 +1,296 @@
+package httpfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response is what assertions are evaluated against
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Duration   time.Duration
+
+	json      interface{}
+	decoded   bool
+	decodeErr error
+}
+
+// Assertion is a `?? subject [key] operator [expected]` line, e.g.
+//
+//	?? status == 200
+//	?? header Content-Type includes json
+//	?? json $.items[0].name == "first"
+//	?? duration < 200ms
+type Assertion struct {
+	Line     int
+	Subject  string
+	Key      string
+	Operator string
+	Expected string
+}
+
+var operators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"includes": true, "contains": true, "matches": true, "exists": true,
+	"isTrue": true, "isFalse": true,
+}
+
+func parseAssertion(text string) (Assertion, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return Assertion{}, fmt.Errorf("incomplete assertion %q", text)
+	}
+
+	assertion := Assertion{Subject: fields[0]}
+	rest := fields[1:]
+	switch assertion.Subject {
+	case "status", "body", "duration":
+	case "header", "json":
+		assertion.Key = rest[0]
+		rest = rest[1:]
+	default:
+		return Assertion{}, fmt.Errorf("unknown assertion subject %q", assertion.Subject)
+	}
+
+	if len(rest) == 0 || !operators[rest[0]] {
+		return Assertion{}, fmt.Errorf("missing operator in assertion %q", text)
+	}
+	assertion.Operator = rest[0]
+
+	// The expected value keeps its inner spacing
+	index := strings.Index(text, " "+assertion.Operator)
+	assertion.Expected = strings.TrimSpace(text[index+len(assertion.Operator)+1:])
+
+	return assertion, nil
+}
+
+func (a Assertion) String() string {
+	return strings.Join(strings.Fields(fmt.Sprintf("%s %s %s %s", a.Subject, a.Key, a.Operator, a.Expected)), " ")
+}
+
+// Check evaluates the assertion and returns a descriptive error on failure
+func (a Assertion) Check(response *Response) error {
+	switch a.Subject {
+	case "status":
+		return compare(float64(response.StatusCode), a.Operator, a.Expected)
+	case "duration":
+		limit, err := parseDuration(a.Expected)
+		if err != nil {
+			return err
+		}
+		if err := compare(float64(response.Duration), a.Operator, fmt.Sprint(float64(limit))); err != nil {
+			return fmt.Errorf("expected %s %s, got %s", a.Operator, limit, response.Duration.Round(time.Millisecond))
+		}
+		return nil
+	case "header":
+		values, ok := response.Header[http.CanonicalHeaderKey(a.Key)]
+		if a.Operator == "exists" {
+			if !ok {
+				return fmt.Errorf("header %s is missing", a.Key)
+			}
+			return nil
+		}
+		return compare(strings.Join(values, ", "), a.Operator, a.Expected)
+	case "body":
+		return compare(string(response.Body), a.Operator, a.Expected)
+	case "json":
+		value, err := response.JSONPath(a.Key)
+		if a.Operator == "exists" {
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		return compare(value, a.Operator, a.Expected)
+	}
+	return fmt.Errorf("unknown assertion subject %q", a.Subject)
+}
+
+func parseDuration(text string) (time.Duration, error) {
+	if ms, err := strconv.ParseFloat(text, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+	return duration, nil
+}
+
+// compare checks actual, a decoded JSON value, a number or a string, against
+// the expected literal
+func compare(actual interface{}, operator, expectedText string) error {
+	expected := parseLiteral(expectedText)
+
+	fail := func() error {
+		return fmt.Errorf("expected %s %s, got %s", operator, expectedText, describe(actual))
+	}
+
+	switch operator {
+	case "isTrue", "isFalse":
+		if actual != (operator == "isTrue") {
+			return fmt.Errorf("expected %s, got %s", operator, describe(actual))
+		}
+	case "==", "!=":
+		equal := reflect.DeepEqual(normalize(actual), normalize(expected))
+		if str, ok := actual.(string); ok && !equal {
+			equal = str == expectedText
+		}
+		if equal != (operator == "==") {
+			return fail()
+		}
+	case "<", "<=", ">", ">=":
+		left, leftOk := toNumber(actual)
+		right, rightOk := toNumber(expected)
+		if !leftOk || !rightOk {
+			return fmt.Errorf("cannot compare %s with %s", describe(actual), expectedText)
+		}
+		ok := map[string]bool{
+			"<": left < right, "<=": left <= right,
+			">": left > right, ">=": left >= right,
+		}[operator]
+		if !ok {
+			return fail()
+		}
+	case "includes", "contains":
+		if !includes(actual, expected, expectedText) {
+			return fail()
+		}
+	case "matches":
+		re, err := regexp.Compile(expectedText)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", expectedText, err)
+		}
+		if !re.MatchString(toString(actual)) {
+			return fail()
+		}
+	case "exists":
+	default:
+		return fmt.Errorf("unknown operator %q", operator)
+	}
+	return nil
+}
+
+func includes(actual, expected interface{}, expectedText string) bool {
+	switch v := actual.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if reflect.DeepEqual(normalize(item), normalize(expected)) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		_, ok := v[expectedText]
+		return ok
+	}
+	return strings.Contains(toString(actual), toString(expected))
+}
+
+// parseLiteral decodes JSON literals and falls back to the raw text
+func parseLiteral(text string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err == nil {
+		return value
+	}
+	return text
+}
+
+func normalize(value interface{}) interface{} {
+	if number, ok := toNumber(value); ok {
+		if _, isString := value.(string); !isString {
+			return number
+		}
+	}
+	return value
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func toString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func describe(value interface{}) string {
+	text := toString(value)
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	if _, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// JSONPath evaluates a simple path such as $.items[0].name against the JSON
+// body of the response
+func (r *Response) JSONPath(path string) (interface{}, error) {
+	if !r.decoded {
+		r.decoded = true
+		if err := json.Unmarshal(r.Body, &r.json); err != nil {
+			r.decodeErr = fmt.Errorf("body is not JSON: %v", err)
+		}
+	}
+	// The error is kept, so every assertion on a non JSON body fails
+	if r.decodeErr != nil {
+		return nil, r.decodeErr
+	}
+	return evalPath(r.json, path)
+}
+
+var pathToken = regexp.MustCompile(`\.?([^.\[\]]+)|\[(\d+)\]|\["([^"]+)"\]`)
+
+func evalPath(root interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	current := root
+
+	for _, match := range pathToken.FindAllStringSubmatch(path, -1) {
+		switch {
+		case match[2] != "":
+			index, _ := strconv.Atoi(match[2])
+			items, ok := current.([]interface{})
+			if !ok || index >= len(items) {
+				return nil, fmt.Errorf("%s: no element [%d]", path, index)
+			}
+			current = items[index]
+		default:
+			key := match[1]
+			if match[3] != "" {
+				key = match[3]
+			}
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %q is not an object key", path, key)
+			}
+			value, ok := object[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %q", path, key)
+			}
+			current = value
+		}
+	}
+	return current, nil
+}
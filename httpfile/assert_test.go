@@ -0,0 +1,120 @@
+package httpfile
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssertionCheck(t *testing.T) {
+	response := &Response{
+		StatusCode: 201,
+		Header:     http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:       []byte(`{"id": 7, "name": "first", "tags": ["a", "b"], "active": true, "meta": {"x-y": 1}}`),
+		Duration:   150 * time.Millisecond,
+	}
+
+	tests := []struct {
+		assertion string
+		err       string
+	}{
+		{assertion: "status == 201"},
+		{assertion: "status >= 200"},
+		{assertion: "status == 200", err: "expected == 200, got 201"},
+		{assertion: "header content-type includes json"},
+		{assertion: "header Location exists", err: "header Location is missing"},
+		{assertion: "body contains \"first\""},
+		{assertion: "body matches ^\\{\"id\""},
+		{assertion: "json $.id == 7"},
+		{assertion: "json $.name == first"},
+		{assertion: "json $.name == \"first\""},
+		{assertion: "json $.name != first", err: "expected != first"},
+		{assertion: "json $.tags[1] == b"},
+		{assertion: "json $.tags includes \"a\""},
+		{assertion: "json $.tags[2] exists", err: "no element [2]"},
+		{assertion: "json $.meta[\"x-y\"] == 1"},
+		{assertion: "json $.active isTrue"},
+		{assertion: "json $.missing exists", err: `no key "missing"`},
+		{assertion: "duration < 200ms"},
+		{assertion: "duration < 100", err: "expected < 100ms, got 150ms"},
+		{assertion: "json $.name < 3", err: "cannot compare"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.assertion, func(t *testing.T) {
+			assertion, err := parseAssertion(test.assertion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = assertion.Check(response)
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected failure: %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestJSONPathOnText(t *testing.T) {
+	response := &Response{StatusCode: 200, Body: []byte("Hello, World!")}
+	// Every JSON assertion fails, not only the first one decoding the body
+	for i := 0; i < 2; i++ {
+		for _, text := range []string{"json $ exists", "json $.name == null"} {
+			assertion, err := parseAssertion(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := assertion.Check(response); err == nil || !strings.Contains(err.Error(), "body is not JSON") {
+				t.Errorf("%s: expected a decoding error, got %v", text, err)
+			}
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	variables := newVariables()
+	variables.Set("host", "http://localhost")
+	variables.Set("api", "{{host}}/api")
+	variables.responses["login"] = &Response{
+		Header: http.Header{"Location": {"/users/7"}},
+		Body:   []byte(`{"token": "abc", "user": {"id": 7}}`),
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+		err      string
+	}{
+		{text: "{{api}}/items", expected: "http://localhost/api/items"},
+		{text: "Bearer {{ login.response.body.$.token }}", expected: "Bearer abc"},
+		{text: "{{login.response.body.$.user}}", expected: `{"id":7}`},
+		{text: "{{login.response.headers.Location}}", expected: "/users/7"},
+		{text: "{{unknown}}", err: `undefined variable "unknown"`},
+		{text: "{{$unknown}}", err: "unsupported dynamic variable"},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			result, err := variables.Substitute(test.text)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Errorf("got %q, want %q", result, test.expected)
+			}
+		})
+	}
+
+	variables.Set("loop", "{{loop}}")
+	if _, err := variables.Substitute("{{loop}}"); err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Errorf("expected a nesting error, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package httpfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// File is a parsed .http file
+type File struct {
+	Variables map[string]string
+	Requests  []*Request
+}
+
+// Request is a single request of a .http file, with its variables left
+// unresolved
+type Request struct {
+	Name       string
+	Line       int
+	Method     string
+	URL        string
+	Headers    []Header
+	Body       string
+	Assertions []Assertion
+}
+
+type Header struct {
+	Name  string
+	Value string
+}
+
+var (
+	requestLine  = regexp.MustCompile(`^([A-Z]+)\s+(\S.*?)(?:\s+HTTP/[\d.]+)?$`)
+	variableLine = regexp.MustCompile(`^@([\w.-]+)\s*=\s*(.*)$`)
+	nameLine     = regexp.MustCompile(`^(?:#|//)\s*@name\s+(\S+)`)
+
+	commentAssertion = regexp.MustCompile(`^(?:#|//)\s*\?\?\s+(.*)$`)
+)
+
+var methods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+	"HEAD": true, "OPTIONS": true, "TRACE": true, "CONNECT": true,
+}
+
+func ParseFile(filename string) (*File, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return file, nil
+}
+
+// Parse reads the REST client format used by VS Code and JetBrains: requests
+// separated by ###, @variables, headers and a body after a blank line.
+// Assertions are `?? ` lines anywhere in a request, or `# ?? ` comments
+// before the request line so that other clients ignore them.
+func Parse(r io.Reader) (*File, error) {
+	file := &File{Variables: make(map[string]string)}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	var current *Request
+	var body []string
+	inHeaders := false
+	inScript := false
+	pendingName := ""
+	var pendingAssertions []Assertion
+	lineNumber := 0
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+			body = body[:len(body)-1]
+		}
+		current.Body = strings.Join(body, "\n")
+		file.Requests = append(file.Requests, current)
+		current = nil
+		body = nil
+	}
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "###") {
+			finish()
+			pendingName = strings.TrimSpace(strings.TrimPrefix(trimmed, "###"))
+			inScript = false
+			continue
+		}
+
+		// Response handler scripts of the JetBrains client are not supported
+		if inScript {
+			if strings.HasSuffix(trimmed, "%}") {
+				inScript = false
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "> {%") {
+			inScript = !strings.HasSuffix(trimmed, "%}")
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "?? ") {
+			assertion, err := parseAssertion(strings.TrimSpace(trimmed[3:]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			if current == nil {
+				return nil, fmt.Errorf("line %d: assertion before any request", lineNumber)
+			}
+			assertion.Line = lineNumber
+			current.Assertions = append(current.Assertions, assertion)
+			continue
+		}
+
+		if current == nil {
+			if trimmed == "" {
+				continue
+			}
+			if match := nameLine.FindStringSubmatch(trimmed); match != nil {
+				pendingName = match[1]
+				continue
+			}
+			if match := commentAssertion.FindStringSubmatch(trimmed); match != nil {
+				assertion, err := parseAssertion(match[1])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+				}
+				assertion.Line = lineNumber
+				pendingAssertions = append(pendingAssertions, assertion)
+				continue
+			}
+			if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+				continue
+			}
+			if match := variableLine.FindStringSubmatch(trimmed); match != nil {
+				file.Variables[match[1]] = strings.TrimSpace(match[2])
+				continue
+			}
+
+			current = &Request{Name: pendingName, Line: lineNumber, Method: "GET", Assertions: pendingAssertions}
+			pendingName = ""
+			pendingAssertions = nil
+			if match := requestLine.FindStringSubmatch(trimmed); match != nil && methods[match[1]] {
+				current.Method = match[1]
+				current.URL = match[2]
+			} else {
+				current.URL = strings.TrimSuffix(trimmed, " HTTP/1.1")
+			}
+			inHeaders = true
+			continue
+		}
+
+		if inHeaders {
+			if trimmed == "" {
+				inHeaders = false
+				continue
+			}
+			if strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&") {
+				current.URL += trimmed
+				continue
+			}
+			if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+				continue
+			}
+			name, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid header %q", lineNumber, trimmed)
+			}
+			current.Headers = append(current.Headers, Header{
+				Name:  strings.TrimSpace(name),
+				Value: strings.TrimSpace(value),
+			})
+			continue
+		}
+
+		body = append(body, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+
+	return file, nil
+}
+
+// DisplayName is used as the subtest name
+func (r *Request) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%s %s", r.Method, r.URL)
+}
@@ -0,0 +1,194 @@
+package httpfile
+
+import (
+	"fmt"
+	"goserve/server"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type runner struct {
+	handler     http.Handler
+	baseURL     string
+	environment string
+	variables   map[string]string
+	client      *http.Client
+}
+
+type Option func(*runner)
+
+// WithEnvironment selects the environment of http-client.env.json
+func WithEnvironment(name string) Option {
+	return func(r *runner) {
+		r.environment = name
+	}
+}
+
+// WithVariable overrides a variable of the file or of the environment
+func WithVariable(name, value string) Option {
+	return func(r *runner) {
+		r.variables[name] = value
+	}
+}
+
+// WithBaseURL sends the requests over HTTP to baseURL instead of calling the
+// server in-process
+func WithBaseURL(baseURL string) Option {
+	return func(r *runner) {
+		r.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// Run executes every request of a .http file against the server, one subtest
+// per request. Absolute URLs are rewritten to target the server, so files
+// written for a manually started instance work unchanged. Requests without
+// assertions fail on 5xx responses.
+func Run(t *testing.T, srv server.HttpServer, filename string, options ...Option) {
+	t.Helper()
+
+	r := &runner{
+		handler:   srv.GetHttpServer().Handler,
+		variables: make(map[string]string),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	file, err := ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variables := newVariables()
+	variables.Set("host", "http://localhost")
+	if r.environment != "" {
+		values, err := LoadEnvironment(filepath.Dir(filename), r.environment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range values {
+			variables.Set(name, value)
+		}
+	}
+	for name, value := range file.Variables {
+		variables.Set(name, value)
+	}
+	for name, value := range r.variables {
+		variables.Set(name, value)
+	}
+
+	base := filepath.Base(filename)
+	for _, request := range file.Requests {
+		request := request
+		t.Run(request.DisplayName(), func(t *testing.T) {
+			response, err := r.execute(request, variables)
+			if err != nil {
+				t.Fatalf("%s:%d: %v", base, request.Line, err)
+			}
+			if request.Name != "" {
+				variables.responses[request.Name] = response
+			}
+
+			if len(request.Assertions) == 0 && response.StatusCode >= 500 {
+				t.Errorf("%s:%d: %s %s answered %d", base, request.Line, request.Method, request.URL, response.StatusCode)
+			}
+			for _, assertion := range request.Assertions {
+				expected, err := variables.Substitute(assertion.Expected)
+				if err != nil {
+					t.Errorf("%s:%d: %v", base, assertion.Line, err)
+					continue
+				}
+				assertion.Expected = expected
+				if err := assertion.Check(response); err != nil {
+					t.Errorf("%s:%d: ?? %s: %v", base, assertion.Line, assertion, err)
+				}
+			}
+		})
+	}
+}
+
+func (r *runner) execute(request *Request, variables *Variables) (*Response, error) {
+	target, err := variables.Substitute(request.URL)
+	if err != nil {
+		return nil, err
+	}
+	body, err := variables.Substitute(request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %v", target, err)
+	}
+	if r.baseURL != "" {
+		target = r.baseURL + parsed.RequestURI()
+	} else {
+		target = parsed.RequestURI()
+	}
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+	httpRequest, err := http.NewRequest(request.Method, target, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range request.Headers {
+		value, err := variables.Substitute(header.Value)
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header.Add(header.Name, value)
+	}
+	if r.baseURL == "" {
+		httpRequest.RemoteAddr = "192.0.2.1:1234"
+		httpRequest.RequestURI = target
+		if parsed.Host != "" {
+			httpRequest.Host = parsed.Host
+		} else {
+			httpRequest.Host = "localhost"
+		}
+	}
+
+	start := time.Now()
+	if r.baseURL != "" {
+		return r.send(httpRequest, start)
+	}
+
+	recorder := httptest.NewRecorder()
+	r.handler.ServeHTTP(recorder, httpRequest)
+	return &Response{
+		StatusCode: recorder.Code,
+		Header:     recorder.Header(),
+		Body:       recorder.Body.Bytes(),
+		Duration:   time.Since(start),
+	}, nil
+}
+
+func (r *runner) send(request *http.Request, start time.Time) (*Response, error) {
+	resp, err := r.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       data,
+		Duration:   time.Since(start),
+	}, nil
+}
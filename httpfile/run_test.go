@@ -0,0 +1,47 @@
+package httpfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"goserve/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testServer() server.HttpServer {
+	return server.New().
+		GET("/hello", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "Hello, %s!", r.URL.Query().Get("name"))
+		}).
+		POST("/login", func(w http.ResponseWriter, r *http.Request) {
+			var credentials struct{ User, Password string }
+			if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil || credentials.Password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"token": "token-of-" + credentials.User})
+		}).
+		GET("/me", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token-of-alice" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"user": "alice", "roles": []string{"user", "admin"}})
+		}).
+		Build()
+}
+
+func TestRun(t *testing.T) {
+	Run(t, testServer(), "testdata/api.http", WithEnvironment("test"))
+}
+
+func TestRunWithBaseURL(t *testing.T) {
+	srv := testServer()
+	remote := httptest.NewServer(srv.GetHttpServer().Handler)
+	defer remote.Close()
+
+	Run(t, srv, "testdata/api.http", WithVariable("password", "secret"), WithBaseURL(remote.URL))
+}
@@ -0,0 +1,155 @@
+package httpfile
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Variables resolves {{name}} placeholders
+type Variables struct {
+	values    map[string]string
+	responses map[string]*Response
+}
+
+func newVariables() *Variables {
+	return &Variables{
+		values:    make(map[string]string),
+		responses: make(map[string]*Response),
+	}
+}
+
+func (v *Variables) Set(name, value string) {
+	v.values[name] = value
+}
+
+// Substitute replaces every placeholder of text. Variable values may
+// themselves contain placeholders.
+func (v *Variables) Substitute(text string) (string, error) {
+	return v.substitute(text, 0)
+}
+
+func (v *Variables) substitute(text string, depth int) (string, error) {
+	if depth > 10 {
+		return "", fmt.Errorf("variables nested too deeply in %q", text)
+	}
+
+	var firstErr error
+	result := placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		value, err := v.lookup(name)
+		if err == nil {
+			value, err = v.substitute(value, depth+1)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return value
+	})
+	return result, firstErr
+}
+
+func (v *Variables) lookup(name string) (string, error) {
+	if strings.HasPrefix(name, "$") {
+		return dynamicVariable(name)
+	}
+	if value, ok := v.values[name]; ok {
+		return value, nil
+	}
+
+	// {{login.response.body.$.token}} or {{login.response.headers.Location}}
+	parts := strings.SplitN(name, ".", 4)
+	if len(parts) >= 3 && parts[1] == "response" {
+		if response, ok := v.responses[parts[0]]; ok {
+			return responseVariable(response, parts[2:])
+		}
+	}
+
+	return "", fmt.Errorf("undefined variable %q", name)
+}
+
+func responseVariable(response *Response, parts []string) (string, error) {
+	switch parts[0] {
+	case "body":
+		if len(parts) == 1 || parts[1] == "*" {
+			return string(response.Body), nil
+		}
+		value, err := response.JSONPath(parts[1])
+		if err != nil {
+			return "", err
+		}
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		data, err := json.Marshal(value)
+		return string(data), err
+	case "headers":
+		if len(parts) == 2 {
+			return response.Header.Get(parts[1]), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported response variable %s", strings.Join(parts, "."))
+}
+
+func dynamicVariable(name string) (string, error) {
+	fields := strings.Fields(name)
+	switch fields[0] {
+	case "$guid", "$uuid", "$random.uuid":
+		var b [16]byte
+		rand.Read(b[:])
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+	case "$timestamp":
+		return fmt.Sprint(time.Now().Unix()), nil
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "$randomInt":
+		n, _ := rand.Int(rand.Reader, big.NewInt(1000))
+		return n.String(), nil
+	case "$processEnv":
+		if len(fields) == 2 {
+			return os.Getenv(fields[1]), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported dynamic variable %q", name)
+}
+
+// LoadEnvironment reads the variables of an environment from the
+// http-client.env.json and http-client.private.env.json files of dir
+func LoadEnvironment(dir, name string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, filename := range []string{"http-client.env.json", "http-client.private.env.json"} {
+		path := filepath.Join(dir, filename)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var environments map[string]map[string]interface{}
+		if err := json.Unmarshal(data, &environments); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, section := range []string{"$shared", name} {
+			for key, value := range environments[section] {
+				if str, ok := value.(string); ok {
+					values[key] = str
+				} else {
+					values[key] = fmt.Sprint(value)
+				}
+			}
+		}
+	}
+	return values, nil
+}
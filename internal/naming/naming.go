@@ -0,0 +1,97 @@
+package naming
+
+import (
+	"strings"
+	"unicode"
+)
+
+var initialisms = map[string]string{
+	"api":  "API",
+	"html": "HTML",
+	"http": "HTTP",
+	"id":   "ID",
+	"ip":   "IP",
+	"json": "JSON",
+	"uri":  "URI",
+	"url":  "URL",
+	"uuid": "UUID",
+}
+
+// GoName converts an identifier in snake, kebab or camel case into an
+// exported Go identifier
+func GoName(name string) string {
+	words := splitWords(name)
+
+	var builder strings.Builder
+	for _, word := range words {
+		lower := strings.ToLower(word)
+		if initialism, ok := initialisms[lower]; ok {
+			builder.WriteString(initialism)
+			continue
+		}
+		builder.WriteString(strings.ToUpper(lower[:1]) + lower[1:])
+	}
+
+	result := builder.String()
+	if result == "" {
+		return "X"
+	}
+	if unicode.IsDigit([]rune(result)[0]) {
+		result = "X" + result
+	}
+	return result
+}
+
+// LowerCamel converts a name into a camel case identifier starting with a
+// lower case letter, as used for JavaScript members
+func LowerCamel(name string) string {
+	goName := GoName(name)
+	for _, initialism := range initialisms {
+		if strings.HasPrefix(goName, initialism) {
+			return strings.ToLower(initialism) + goName[len(initialism):]
+		}
+	}
+	return strings.ToLower(goName[:1]) + goName[1:]
+}
+
+// Kebab converts a name into lower case words joined by dashes, as used for
+// command-line flags: ReadTimeout becomes read-timeout
+func Kebab(name string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "-")
+}
+
+// splitWords cuts a name on separators and case changes, keeping
+// initialisms such as HTTP in one word
+func splitWords(name string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || nextIsLower {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
@@ -2,45 +2,42 @@ package main
 
 import (
 	"fmt"
+	"goserve/cli"
 	"goserve/configuration"
 	"goserve/server"
-	"log"
+	"io"
 	"net/http"
 )
 
 func main() {
-	fmt.Println("Hello, World!")
-
-	configBuilder := configuration.New()
-	configuration, err := configBuilder.LoadConfig()
-	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
-	}
+	cli.New("goserve").
+		WithServer(func(builder server.ServerBuilder, config configuration.Configuration) {
+			builder.
+				WithLogging(true, true).
+				AddRoutes(routes())
+		}).
+		Main()
+}
 
+// routes are the sample routes exercised by test.http
+func routes() []server.RouteInfo {
 	hello := server.
 		CreateRoute(server.GET, "/hello", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("Hello, World!"))
 		})
 
-	builder := server.New().
-		WithConfiguration(configuration).
-		WithLogging(true, true).
-		AddRoutes([]server.RouteInfo{
-			hello,
-			server.CreatePOST("/echo", func(w http.ResponseWriter, r *http.Request) {
-				body := make([]byte, r.ContentLength)
-				r.Body.Read(body)
-
-				result := fmt.Sprintf("Echo: %s", string(body))
-
-				w.Write([]byte(result))
-			}),
-		})
+	return []server.RouteInfo{
+		hello,
+		server.CreatePOST("/echo", func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
-	server := builder.Build()
+			result := fmt.Sprintf("Echo: %s", string(body))
 
-	err = server.Start()
-	if err != nil {
-		fmt.Printf("Error starting server: %v\n", err)
+			w.Write([]byte(result))
+		}),
 	}
 }
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"goserve/goservetest"
+	"goserve/server"
+	"testing"
+)
+
+func TestHTTPFile(t *testing.T) {
+	srv := goservetest.New(t, server.New().AddRoutes(routes()))
+	srv.RunHTTPFile(t, "test.http")
+}
@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"goserve/server"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Parameter looks up an operation parameter by location and name
+func (op *Operation) Parameter(in, name string) *Parameter {
+	for _, param := range op.Parameters {
+		if param.In == in && param.Name == name {
+			return param
+		}
+	}
+	return nil
+}
+
+// DecodeParam converts raw parameter values into target, which must be a
+// pointer to a scalar, a pointer to a pointer to a scalar or a pointer to a
+// slice of scalars
+func DecodeParam(raw []string, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer")
+	}
+	return server.SetFromStrings(value.Elem(), raw)
+}
+
+// DecodeBody decodes a JSON request body into target. An empty body leaves
+// target untouched.
+func DecodeBody(r *http.Request, target interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize))
+	if err != nil {
+		return fmt.Errorf("body: unreadable: %v", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("body: %v", err)
+	}
+	return nil
+}
+
+// WriteJSON writes v as a JSON response with the given status
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
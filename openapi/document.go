@@ -0,0 +1,286 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Subset of the OpenAPI 3 object model used by goserve
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []ServerObject       `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type ServerObject struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas       map[string]*Schema      `json:"schemas,omitempty"`
+	Parameters    map[string]*Parameter   `json:"parameters,omitempty"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies,omitempty"`
+	Responses     map[string]*Response    `json:"responses,omitempty"`
+}
+
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters,omitempty"`
+	Get        *Operation   `json:"get,omitempty"`
+	Put        *Operation   `json:"put,omitempty"`
+	Post       *Operation   `json:"post,omitempty"`
+	Delete     *Operation   `json:"delete,omitempty"`
+	Options    *Operation   `json:"options,omitempty"`
+	Head       *Operation   `json:"head,omitempty"`
+	Patch      *Operation   `json:"patch,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+
+	// Filled by the loader
+	Method string `json:"-"`
+	Path   string `json:"-"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Style       string  `json:"style,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"-"`
+	NoAdditional         bool               `json:"-"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+
+	resolved *Schema
+}
+
+// Operations returns the operations of the path item keyed by HTTP method
+func (p *PathItem) Operations() map[string]*Operation {
+	operations := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		"GET":     p.Get,
+		"PUT":     p.Put,
+		"POST":    p.Post,
+		"DELETE":  p.Delete,
+		"OPTIONS": p.Options,
+		"HEAD":    p.Head,
+		"PATCH":   p.Patch,
+	} {
+		if op != nil {
+			operations[method] = op
+		}
+	}
+	return operations
+}
+
+// SetOperation attaches an operation to the path item for the given method
+func (p *PathItem) SetOperation(method string, op *Operation) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "OPTIONS":
+		p.Options = op
+	case "HEAD":
+		p.Head = op
+	case "PATCH":
+		p.Patch = op
+	}
+}
+
+// JSONSchema returns the schema of the application/json content, if any
+func (r *RequestBody) JSONSchema() *Schema {
+	return jsonSchema(r.Content)
+}
+
+// JSONSchema returns the schema of the application/json content, if any
+func (r *Response) JSONSchema() *Schema {
+	return jsonSchema(r.Content)
+}
+
+func jsonSchema(content map[string]*MediaType) *Schema {
+	for contentType, media := range content {
+		if strings.HasPrefix(contentType, "application/json") && media != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+// Resolve follows $ref links and returns the target schema
+func (s *Schema) Resolve() *Schema {
+	current := s
+	for current != nil && current.resolved != nil {
+		current = current.resolved
+	}
+	return current
+}
+
+// RefName returns the component name of a $ref, or an empty string
+func (s *Schema) RefName() string {
+	return refName(s.Ref)
+}
+
+func refName(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	var raw struct {
+		plain
+		Type                 json.RawMessage `json:"type,omitempty"`
+		AdditionalProperties json.RawMessage `json:"additionalProperties,omitempty"`
+		ExclusiveMinimum     json.RawMessage `json:"exclusiveMinimum,omitempty"`
+		ExclusiveMaximum     json.RawMessage `json:"exclusiveMaximum,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = Schema(raw.plain)
+
+	// OpenAPI 3.1 allows a list of types, e.g. ["string", "null"]
+	if len(raw.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(raw.Type, &single); err == nil {
+			s.Type = single
+		} else {
+			var types []string
+			if err := json.Unmarshal(raw.Type, &types); err != nil {
+				return err
+			}
+			for _, t := range types {
+				if t == "null" {
+					s.Nullable = true
+				} else {
+					s.Type = t
+				}
+			}
+		}
+	}
+
+	if len(raw.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(raw.AdditionalProperties, &allowed); err == nil {
+			s.NoAdditional = !allowed
+		} else {
+			s.AdditionalProperties = &Schema{}
+			if err := json.Unmarshal(raw.AdditionalProperties, s.AdditionalProperties); err != nil {
+				return err
+			}
+		}
+	}
+
+	// OpenAPI 3.0 uses booleans, 3.1 uses numbers
+	var err error
+	if s.ExclusiveMinimum, s.Minimum, err = exclusiveBound(raw.ExclusiveMinimum, s.Minimum); err != nil {
+		return err
+	}
+	if s.ExclusiveMaximum, s.Maximum, err = exclusiveBound(raw.ExclusiveMaximum, s.Maximum); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	var raw struct {
+		*plain
+		AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	}
+	raw.plain = (*plain)(s)
+	if s.AdditionalProperties != nil {
+		raw.AdditionalProperties = s.AdditionalProperties
+	} else if s.NoAdditional {
+		raw.AdditionalProperties = false
+	}
+	return json.Marshal(raw)
+}
+
+func exclusiveBound(data json.RawMessage, bound *float64) (bool, *float64, error) {
+	if len(data) == 0 {
+		return false, bound, nil
+	}
+	var flag bool
+	if err := json.Unmarshal(data, &flag); err == nil {
+		return flag, bound, nil
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return false, bound, err
+	}
+	return true, &value, nil
+}
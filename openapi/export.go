@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"goserve/server"
+	"strings"
+)
+
+// FromRoutes builds a document describing the routes of a server. Routes
+// created with Route keep their operation; the others are described by
+// their method, path parameters, tags and "operationId" and "summary" metas.
+func FromRoutes(info Info, routes []server.RouteInfo) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+	for _, route := range routes {
+		path := openAPIPath(route.GetPath())
+		method := string(route.GetMethod())
+
+		op := routeOperation(route)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		item.SetOperation(method, op)
+	}
+	return doc
+}
+
+func routeOperation(route server.RouteInfo) *Operation {
+	if meta, ok := route.GetMeta("openapi.operation"); ok {
+		if op, ok := meta.(*Operation); ok {
+			return op
+		}
+	}
+
+	op := &Operation{
+		Tags: route.GetTags(),
+		Responses: map[string]*Response{
+			"default": {Description: "Response"},
+		},
+	}
+	if id, ok := route.GetMeta("operationId"); ok {
+		op.OperationID, _ = id.(string)
+	}
+	if summary, ok := route.GetMeta("summary"); ok {
+		op.Summary, _ = summary.(string)
+	}
+	for _, segment := range strings.Split(route.GetPath(), "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     strings.TrimSuffix(strings.Trim(segment, "{}"), "..."),
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return op
+}
+
+// openAPIPath converts a ServeMux pattern: {rest...} becomes {rest} and the
+// {$} end anchor is dropped
+func openAPIPath(path string) string {
+	path = strings.ReplaceAll(path, "{$}", "")
+	return strings.ReplaceAll(path, "...}", "}")
+}
@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Unknown formats are accepted, as the specification requires
+func checkFormat(format, value string) error {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("must be an RFC 3339 date-time")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("must be a full-date (YYYY-MM-DD)")
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			return fmt.Errorf("must be a UUID")
+		}
+	case "email":
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("must be an email address")
+		}
+	case "ipv4":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("must be an IPv4 address")
+		}
+	case "ipv6":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("must be an IPv6 address")
+		}
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return fmt.Errorf("must be an absolute URI")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"goserve/internal/naming"
+	"goserve/openapi"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Options struct {
+	// Package name of the generated file
+	Package string
+	// Name of the source document, mentioned in the generated header
+	Source string
+}
+
+type generator struct {
+	doc      *openapi.Document
+	opts     Options
+	imports  map[string]bool
+	declared map[string]bool
+	types    bytes.Buffer
+}
+
+type operation struct {
+	op      *openapi.Operation
+	name    string
+	tag     string
+	request string
+}
+
+// Generate produces a Go source file with the schema types, one handler
+// interface per tag and a RegisterRoutes function for the given document
+func Generate(spec []byte, opts Options) ([]byte, error) {
+	doc, err := openapi.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Package == "" {
+		opts.Package = "api"
+	}
+
+	g := &generator{
+		doc:      doc,
+		opts:     opts,
+		imports:  map[string]bool{"net/http": true, "goserve/openapi": true, "goserve/server": true},
+		declared: make(map[string]bool),
+	}
+
+	g.generateComponents()
+	operations, err := g.collectOperations()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	g.generateRequests(&body, operations)
+	g.generateHandlers(&body, operations)
+	g.generateRegistration(&body, operations, spec)
+
+	var out bytes.Buffer
+	source := ""
+	if opts.Source != "" {
+		source = " from " + opts.Source
+	}
+	fmt.Fprintf(&out, "// Code generated by goserve-openapi%s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&out, "package %s\n\n", opts.Package)
+	out.WriteString("import (\n")
+	for _, path := range sortedKeys(g.imports) {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n\n")
+	out.Write(g.types.Bytes())
+	out.Write(body.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %v\n%s", err, out.String())
+	}
+	return formatted, nil
+}
+
+func (g *generator) generateComponents() {
+	for _, name := range sortedKeys(g.doc.Components.Schemas) {
+		g.declare(naming.GoName(name), g.doc.Components.Schemas[name])
+	}
+}
+
+// declare emits a named type for the schema
+func (g *generator) declare(name string, schema *openapi.Schema) {
+	if g.declared[name] {
+		return
+	}
+	g.declared[name] = true
+
+	if schema.Description != "" {
+		writeComment(&g.types, "", schema.Description)
+	}
+
+	switch {
+	case schema.Ref != "":
+		fmt.Fprintf(&g.types, "type %s = %s\n\n", name, naming.GoName(schema.RefName()))
+	case isStruct(schema):
+		var fields bytes.Buffer
+		g.writeFields(&fields, name, schema)
+		fmt.Fprintf(&g.types, "type %s struct {\n%s}\n\n", name, fields.String())
+	case schema.Type == "string" && len(schema.Enum) > 0:
+		fmt.Fprintf(&g.types, "type %s string\n\nconst (\n", name)
+		for _, value := range schema.Enum {
+			if str, ok := value.(string); ok {
+				fmt.Fprintf(&g.types, "\t%s%s %s = %q\n", name, naming.GoName(str), name, str)
+			}
+		}
+		g.types.WriteString(")\n\n")
+	default:
+		fmt.Fprintf(&g.types, "type %s %s\n\n", name, g.goType(schema, name))
+	}
+}
+
+func isStruct(schema *openapi.Schema) bool {
+	if len(schema.AllOf) > 0 {
+		return true
+	}
+	return (schema.Type == "object" || schema.Type == "") && len(schema.Properties) > 0
+}
+
+func (g *generator) writeFields(buf *bytes.Buffer, owner string, schema *openapi.Schema) {
+	for _, part := range schema.AllOf {
+		if part.Ref != "" {
+			fmt.Fprintf(buf, "\t%s\n", naming.GoName(part.RefName()))
+		} else {
+			g.writeFields(buf, owner, part)
+		}
+	}
+
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	for _, property := range sortedKeys(schema.Properties) {
+		propertySchema := schema.Properties[property]
+		fieldName := naming.GoName(property)
+		fieldType := g.goType(propertySchema, owner+fieldName)
+
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		resolved := propertySchema.Resolve()
+		if (!required[property] || (resolved != nil && resolved.Nullable)) && pointerable(fieldType) {
+			fieldType = "*" + fieldType
+		}
+
+		if propertySchema.Description != "" {
+			writeComment(buf, "\t", propertySchema.Description)
+		}
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s\"`\n", fieldName, fieldType, tag)
+	}
+}
+
+func pointerable(goType string) bool {
+	return !strings.HasPrefix(goType, "[]") &&
+		!strings.HasPrefix(goType, "map[") &&
+		goType != "interface{}" &&
+		goType != "json.RawMessage"
+}
+
+// goType returns the Go type expression for a schema, declaring inline
+// structures under the given context name
+func (g *generator) goType(schema *openapi.Schema, context string) string {
+	if schema == nil {
+		return "interface{}"
+	}
+	if schema.Ref != "" {
+		return naming.GoName(schema.RefName())
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+	if isStruct(schema) {
+		g.declare(context, schema)
+		return context
+	}
+
+	switch schema.Type {
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + g.goType(schema.AdditionalProperties, context+"Value")
+		}
+		return "map[string]interface{}"
+	case "array":
+		return "[]" + g.goType(schema.Items, context+"Item")
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+	return "interface{}"
+}
+
+func (g *generator) collectOperations() ([]operation, error) {
+	var operations []operation
+	names := make(map[string]string)
+
+	for _, op := range g.doc.SortedOperations() {
+		name := naming.GoName(op.OperationID)
+		if op.OperationID == "" {
+			name = naming.GoName(strings.ToLower(op.Method) + " " + op.Path)
+		}
+		if previous, ok := names[name]; ok {
+			return nil, fmt.Errorf("operations %s and %s %s both map to %s", previous, op.Method, op.Path, name)
+		}
+		names[name] = op.Method + " " + op.Path
+
+		tag := "Default"
+		if len(op.Tags) > 0 {
+			tag = naming.GoName(op.Tags[0])
+		}
+
+		operations = append(operations, operation{
+			op:      op,
+			name:    name,
+			tag:     tag,
+			request: name + "Request",
+		})
+	}
+	return operations, nil
+}
+
+func (g *generator) generateRequests(buf *bytes.Buffer, operations []operation) {
+	for _, o := range operations {
+		fmt.Fprintf(buf, "// %s holds the decoded parameters and body of %s %s\n", o.request, o.op.Method, o.op.Path)
+		fmt.Fprintf(buf, "type %s struct {\n", o.request)
+		for _, param := range o.op.Parameters {
+			fieldType := g.goType(param.Schema, o.name+naming.GoName(param.Name))
+			if !param.Required && pointerable(fieldType) {
+				fieldType = "*" + fieldType
+			}
+			fmt.Fprintf(buf, "\t%s %s // %s %s\n", paramField(param), fieldType, param.In, param.Name)
+		}
+		if body := o.op.RequestBody; body != nil {
+			if schema := body.JSONSchema(); schema != nil {
+				fieldType := g.goType(schema, o.name+"Body")
+				if !body.Required && pointerable(fieldType) {
+					fieldType = "*" + fieldType
+				}
+				fmt.Fprintf(buf, "\tBody %s\n", fieldType)
+			}
+		}
+		buf.WriteString("}\n\n")
+
+		for _, code := range sortedKeys(o.op.Responses) {
+			schema := o.op.Responses[code].JSONSchema()
+			if schema == nil {
+				continue
+			}
+			g.declare(o.name+"Response"+responseSuffix(code), schema)
+		}
+
+		g.generateDecoder(buf, o)
+	}
+}
+
+func responseSuffix(code string) string {
+	if _, err := strconv.Atoi(code); err == nil {
+		return code
+	}
+	return naming.GoName(code)
+}
+
+func paramField(param *openapi.Parameter) string {
+	name := naming.GoName(param.Name)
+	if name == "Body" {
+		return naming.GoName(param.In) + name
+	}
+	return name
+}
+
+func (g *generator) generateDecoder(buf *bytes.Buffer, o operation) {
+	fmt.Fprintf(buf, "func decode%s(r *http.Request, op *openapi.Operation) (%s, error) {\n", o.request, o.request)
+	fmt.Fprintf(buf, "\tvar req %s\n", o.request)
+	for _, param := range o.op.Parameters {
+		g.imports["fmt"] = true
+		fmt.Fprintf(buf, "\tif raw, ok := openapi.RawParam(r, op.Parameter(%q, %q)); ok {\n", param.In, param.Name)
+		fmt.Fprintf(buf, "\t\tif err := openapi.DecodeParam(raw, &req.%s); err != nil {\n", paramField(param))
+		fmt.Fprintf(buf, "\t\t\treturn req, fmt.Errorf(\"%s.%s: %%v\", err)\n", param.In, param.Name)
+		buf.WriteString("\t\t}\n\t}\n")
+	}
+	if o.op.RequestBody != nil && o.op.RequestBody.JSONSchema() != nil {
+		buf.WriteString("\tif err := openapi.DecodeBody(r, &req.Body); err != nil {\n\t\treturn req, err\n\t}\n")
+	}
+	buf.WriteString("\treturn req, nil\n}\n\n")
+}
+
+func (g *generator) generateHandlers(buf *bytes.Buffer, operations []operation) {
+	byTag := make(map[string][]operation)
+	for _, o := range operations {
+		byTag[o.tag] = append(byTag[o.tag], o)
+	}
+
+	for _, tag := range sortedKeys(byTag) {
+		fmt.Fprintf(buf, "// %sHandler implements the operations tagged %q\n", tag, tag)
+		fmt.Fprintf(buf, "type %sHandler interface {\n", tag)
+		for _, o := range byTag[tag] {
+			summary := o.op.Summary
+			if summary == "" {
+				summary = o.op.Method + " " + o.op.Path
+			}
+			writeComment(buf, "\t", summary)
+			fmt.Fprintf(buf, "\t%s(w http.ResponseWriter, r *http.Request, req %s)\n", o.name, o.request)
+		}
+		buf.WriteString("}\n\n")
+	}
+
+	buf.WriteString("// Handlers groups the implementation of every tag. Routes of a nil handler\n// are not registered.\n")
+	buf.WriteString("type Handlers struct {\n")
+	for _, tag := range sortedKeys(byTag) {
+		fmt.Fprintf(buf, "\t%s %sHandler\n", tag, tag)
+	}
+	buf.WriteString("}\n\n")
+}
+
+func (g *generator) generateRegistration(buf *bytes.Buffer, operations []operation, spec []byte) {
+	buf.WriteString("// RegisterRoutes adds every operation of the document to the builder.\n")
+	buf.WriteString("// Requests are validated against the schema before reaching the handlers.\n")
+	buf.WriteString("func RegisterRoutes(b server.ServerBuilder, h Handlers) server.ServerBuilder {\n")
+	buf.WriteString("\tdoc := openapi.MustParse([]byte(specJSON))\n")
+	buf.WriteString("\troutes := make([]server.RouteInfo, 0)\n\n")
+
+	for _, o := range operations {
+		fmt.Fprintf(buf, "\tif h.%s != nil {\n", o.tag)
+		fmt.Fprintf(buf, "\t\troutes = append(routes, openapi.Route(doc, %q, %q, func(w http.ResponseWriter, r *http.Request, op *openapi.Operation) {\n", o.op.Method, o.op.Path)
+		fmt.Fprintf(buf, "\t\t\treq, err := decode%s(r, op)\n", o.request)
+		buf.WriteString("\t\t\tif err != nil {\n\t\t\t\topenapi.WriteValidationProblem(w, err)\n\t\t\t\treturn\n\t\t\t}\n")
+		fmt.Fprintf(buf, "\t\t\th.%s.%s(w, r, req)\n", o.tag, o.name)
+		buf.WriteString("\t\t}))\n\t}\n")
+	}
+
+	buf.WriteString("\n\treturn b.AddRoutes(routes)\n}\n\n")
+	fmt.Fprintf(buf, "const specJSON = %s\n", quote(spec))
+}
+
+func quote(data []byte) string {
+	text := string(data)
+	if !strings.Contains(text, "`") {
+		return "`" + text + "`"
+	}
+	return strconv.Quote(text)
+}
+
+func writeComment(buf *bytes.Buffer, indent, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		fmt.Fprintf(buf, "%s// %s\n", indent, strings.TrimSpace(line))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestGenerate(t *testing.T) {
+	spec, err := os.ReadFile("../testdata/orders.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := Generate(spec, Options{Package: "orders", Source: "orders.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "orders.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(generated, expected) {
+		t.Errorf("generated code differs from %s, run go test -update and review the diff\n%s", golden, generated)
+	}
+
+	again, _ := Generate(spec, Options{Package: "orders", Source: "orders.json"})
+	if !bytes.Equal(generated, again) {
+		t.Error("expected the output to be deterministic")
+	}
+}
+
+func TestGenerateInvalidDocument(t *testing.T) {
+	tests := map[string]string{
+		"not JSON":      `openapi: 3.0.0`,
+		"swagger 2":     `{"swagger": "2.0", "paths": {}}`,
+		"unknown $ref":  `{"openapi": "3.0.3", "paths": {"/a": {"get": {"responses": {"200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Missing"}}}}}}}}}`,
+		"external $ref": `{"openapi": "3.0.3", "paths": {}, "components": {"schemas": {"A": {"$ref": "other.json#/A"}}}}`,
+	}
+	for name, spec := range tests {
+		if _, err := Generate([]byte(spec), Options{Package: "api"}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
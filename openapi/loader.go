@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Load reads and resolves an OpenAPI 3 JSON document
+func Load(filename string) (*Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", filename, err)
+	}
+
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %s: %v", filename, err)
+	}
+	return doc, nil
+}
+
+// Parse decodes an OpenAPI 3 JSON document and resolves its local references
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q", doc.OpenAPI)
+	}
+	if err := doc.resolve(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// MustParse is like Parse but panics on error, for embedded documents
+func MustParse(data []byte) *Document {
+	doc, err := Parse(data)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: %v", err))
+	}
+	return doc
+}
+
+// Operation looks up an operation by method and path template
+func (d *Document) Operation(method, path string) *Operation {
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil
+	}
+	return item.Operations()[strings.ToUpper(method)]
+}
+
+// SortedOperations returns every operation ordered by path then method
+func (d *Document) SortedOperations() []*Operation {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var operations []*Operation
+	for _, path := range paths {
+		byMethod := d.Paths[path].Operations()
+		methods := make([]string, 0, len(byMethod))
+		for method := range byMethod {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			operations = append(operations, byMethod[method])
+		}
+	}
+	return operations
+}
+
+func (d *Document) resolve() error {
+	if d.Components == nil {
+		d.Components = &Components{}
+	}
+
+	for _, schema := range d.Components.Schemas {
+		if err := d.resolveSchema(schema, map[*Schema]bool{}); err != nil {
+			return err
+		}
+	}
+
+	for path, item := range d.Paths {
+		if item == nil {
+			return fmt.Errorf("path %s has no definition", path)
+		}
+		shared, err := d.resolveParameters(item.Parameters)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		item.Parameters = shared
+
+		for method, op := range item.Operations() {
+			op.Method = method
+			op.Path = path
+
+			params, err := d.resolveParameters(op.Parameters)
+			if err != nil {
+				return fmt.Errorf("%s %s: %v", method, path, err)
+			}
+			op.Parameters = mergeParameters(shared, params)
+
+			if op.RequestBody != nil && op.RequestBody.Ref != "" {
+				target, ok := d.Components.RequestBodies[refName(op.RequestBody.Ref)]
+				if !ok {
+					return fmt.Errorf("%s %s: unknown request body %s", method, path, op.RequestBody.Ref)
+				}
+				op.RequestBody = target
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if err := d.resolveSchema(media.Schema, map[*Schema]bool{}); err != nil {
+						return fmt.Errorf("%s %s: %v", method, path, err)
+					}
+				}
+			}
+
+			for code, response := range op.Responses {
+				if response.Ref != "" {
+					target, ok := d.Components.Responses[refName(response.Ref)]
+					if !ok {
+						return fmt.Errorf("%s %s: unknown response %s", method, path, response.Ref)
+					}
+					op.Responses[code] = target
+					response = target
+				}
+				for _, media := range response.Content {
+					if err := d.resolveSchema(media.Schema, map[*Schema]bool{}); err != nil {
+						return fmt.Errorf("%s %s: %v", method, path, err)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) resolveParameters(params []*Parameter) ([]*Parameter, error) {
+	resolved := make([]*Parameter, 0, len(params))
+	for _, param := range params {
+		if param.Ref != "" {
+			target, ok := d.Components.Parameters[refName(param.Ref)]
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %s", param.Ref)
+			}
+			param = target
+		}
+		if param.In == "path" {
+			param.Required = true
+		}
+		if err := d.resolveSchema(param.Schema, map[*Schema]bool{}); err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, param)
+	}
+	return resolved, nil
+}
+
+// Operation parameters override path level ones with the same name and location
+func mergeParameters(shared, own []*Parameter) []*Parameter {
+	merged := make([]*Parameter, 0, len(shared)+len(own))
+	for _, param := range shared {
+		overridden := false
+		for _, candidate := range own {
+			if candidate.Name == param.Name && candidate.In == param.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, param)
+		}
+	}
+	return append(merged, own...)
+}
+
+func (d *Document) resolveSchema(schema *Schema, seen map[*Schema]bool) error {
+	if schema == nil || seen[schema] {
+		return nil
+	}
+	seen[schema] = true
+
+	if schema.Ref != "" {
+		if !strings.HasPrefix(schema.Ref, "#/components/schemas/") {
+			return fmt.Errorf("unsupported reference %s", schema.Ref)
+		}
+		target, ok := d.Components.Schemas[schema.RefName()]
+		if !ok {
+			return fmt.Errorf("unknown schema %s", schema.Ref)
+		}
+		schema.resolved = target
+		return nil
+	}
+
+	children := []*Schema{schema.Items, schema.AdditionalProperties}
+	for _, property := range schema.Properties {
+		children = append(children, property)
+	}
+	children = append(children, schema.AllOf...)
+	children = append(children, schema.OneOf...)
+	children = append(children, schema.AnyOf...)
+
+	for _, child := range children {
+		if err := d.resolveSchema(child, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
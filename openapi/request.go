@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"goserve/server"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Maximum request body size read for validation
+var MaxBodySize int64 = 10 << 20
+
+// RawParam returns the raw values of a parameter. Arrays sent with
+// explode=false are split on commas.
+func RawParam(r *http.Request, param *Parameter) ([]string, bool) {
+	var values []string
+	switch param.In {
+	case "path":
+		if value := r.PathValue(param.Name); value != "" {
+			values = []string{value}
+		}
+	case "query":
+		values = r.URL.Query()[param.Name]
+	case "header":
+		values = r.Header.Values(param.Name)
+	case "cookie":
+		if cookie, err := r.Cookie(param.Name); err == nil {
+			values = []string{cookie.Value}
+		}
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	schema := param.Schema.Resolve()
+	exploded := param.Explode == nil || *param.Explode
+	if schema != nil && schema.Type == "array" && (!exploded || param.In != "query") {
+		var split []string
+		for _, value := range values {
+			split = append(split, strings.Split(value, ",")...)
+		}
+		values = split
+	}
+	return values, true
+}
+
+// ParseParam converts raw parameter values into a JSON-like value according
+// to the parameter schema
+func ParseParam(param *Parameter, raw []string) (interface{}, error) {
+	schema := param.Schema.Resolve()
+	if schema == nil {
+		return raw[0], nil
+	}
+	if schema.Type == "array" {
+		items := make([]interface{}, len(raw))
+		for i, value := range raw {
+			item, err := parseScalar(schema.Items.Resolve(), value)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return parseScalar(schema, raw[0])
+}
+
+func parseScalar(schema *Schema, raw string) (interface{}, error) {
+	if schema == nil {
+		return raw, nil
+	}
+	switch schema.Type {
+	case "integer":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return float64(value), nil
+	case "number":
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return value, nil
+	case "boolean":
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return value, nil
+	}
+	return raw, nil
+}
+
+// ValidateRequest checks the parameters and JSON body of a request against
+// the operation. The body is left readable for the handler.
+func (op *Operation) ValidateRequest(r *http.Request) error {
+	result := &ValidationError{}
+
+	for _, param := range op.Parameters {
+		path := fmt.Sprintf("%s.%s", param.In, param.Name)
+		raw, ok := RawParam(r, param)
+		if !ok {
+			if param.Required {
+				result.add(path, "is required")
+			}
+			continue
+		}
+		value, err := ParseParam(param, raw)
+		if err != nil {
+			result.add(path, "%v", err)
+			continue
+		}
+		if param.Schema != nil {
+			validateValue(param.Schema, value, path, result)
+		}
+	}
+
+	if op.RequestBody != nil {
+		validateBody(op.RequestBody, r, result)
+	}
+
+	return result.orNil()
+}
+
+func validateBody(body *RequestBody, r *http.Request, result *ValidationError) {
+	schema := body.JSONSchema()
+
+	var data []byte
+	if r.Body != nil {
+		var err error
+		data, err = io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+		r.Body.Close()
+		if err != nil {
+			result.add("body", "unreadable: %v", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	if int64(len(data)) > MaxBodySize {
+		result.add("body", "exceeds %d bytes", MaxBodySize)
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			result.add("body", "is required")
+		}
+		return
+	}
+	if schema == nil {
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && !strings.HasSuffix(mediaType, "json") {
+		result.add("body", "unsupported content type %s", mediaType)
+		return
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		result.add("body", "invalid JSON: %v", err)
+		return
+	}
+	validateValue(schema, value, "body", result)
+}
+
+// Validator returns a middleware rejecting requests that do not match the
+// operation with a 400 problem document listing every violation
+func Validator(op *Operation) server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := op.ValidateRequest(r); err != nil {
+				WriteValidationProblem(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteValidationProblem renders a validation or decoding error as a 400
+func WriteValidationProblem(w http.ResponseWriter, err error) {
+	problem := server.NewProblem(http.StatusBadRequest, "request does not match the API schema")
+	if validationErr, ok := err.(*ValidationError); ok {
+		problem.With("errors", validationErr.Errors)
+	} else {
+		problem.Detail = err.Error()
+	}
+	server.WriteProblem(w, problem)
+}
@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const tenant = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestValidateRequest(t *testing.T) {
+	doc := loadOrders(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		target string
+		header map[string]string
+		body   string
+		errors string
+	}{
+		{
+			name:   "valid query",
+			method: "GET", path: "/orders",
+			target: "/orders?limit=10&status=open&tags=a,b",
+			header: map[string]string{"X-Tenant": tenant},
+		},
+		{
+			name:   "missing header",
+			method: "GET", path: "/orders",
+			target: "/orders",
+			errors: "header.X-Tenant: is required",
+		},
+		{
+			name:   "header format",
+			method: "GET", path: "/orders",
+			target: "/orders",
+			header: map[string]string{"X-Tenant": "acme"},
+			errors: "header.X-Tenant: must be a UUID",
+		},
+		{
+			name:   "query types",
+			method: "GET", path: "/orders",
+			target: "/orders?limit=ten&status=lost&tags=a,b,c",
+			header: map[string]string{"X-Tenant": tenant},
+			errors: "query.limit: must be an integer; query.status: must be one of [open shipped]; query.tags: must contain at most 2 items",
+		},
+		{
+			name:   "query bounds",
+			method: "GET", path: "/orders",
+			target: "/orders?limit=0",
+			header: map[string]string{"X-Tenant": tenant},
+			errors: "query.limit: must be greater than or equal to 1",
+		},
+		{
+			name:   "path parameter",
+			method: "GET", path: "/orders/{id}",
+			target: "/orders/42?expand=maybe",
+			errors: "path.id: must be a UUID; query.expand: must be a boolean",
+		},
+		{
+			name:   "valid path parameter",
+			method: "GET", path: "/orders/{id}",
+			target: "/orders/" + tenant + "?expand=true",
+		},
+		{
+			name:   "valid body",
+			method: "POST", path: "/orders",
+			target: "/orders",
+			body:   `{"customer": "alice@example.com", "items": [{"sku": "ABC-1", "quantity": 1}]}`,
+		},
+		{
+			name:   "missing body",
+			method: "POST", path: "/orders",
+			target: "/orders",
+			errors: "body: is required",
+		},
+		{
+			name:   "invalid body",
+			method: "POST", path: "/orders",
+			target: "/orders",
+			body:   `{"customer": "alice", "items": [{"sku": "ABC-1"}]}`,
+			errors: "body.customer: must be an email address; body.items[0].quantity: is required",
+		},
+		{
+			name:   "malformed body",
+			method: "POST", path: "/orders",
+			target: "/orders",
+			body:   `{"customer": `,
+			errors: "body: invalid JSON: unexpected end of JSON input",
+		},
+		{
+			name:   "content type",
+			method: "POST", path: "/orders",
+			target: "/orders",
+			header: map[string]string{"Content-Type": "text/plain"},
+			body:   `customer=alice`,
+			errors: "body: unsupported content type text/plain",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op := doc.Operation(test.method, test.path)
+			var validated error
+			mux := http.NewServeMux()
+			mux.HandleFunc(test.method+" "+test.path, func(w http.ResponseWriter, r *http.Request) {
+				validated = op.ValidateRequest(r)
+			})
+
+			r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			r.Header.Set("Content-Type", "application/json")
+			for key, value := range test.header {
+				r.Header.Set(key, value)
+			}
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			switch {
+			case test.errors == "" && validated != nil:
+				t.Errorf("unexpected error %v", validated)
+			case test.errors != "" && (validated == nil || validated.Error() != test.errors):
+				t.Errorf("expected %q, got %v", test.errors, validated)
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	doc := loadOrders(t)
+	route := Route(doc, "POST", "/orders", func(w http.ResponseWriter, r *http.Request, op *Operation) {
+		// The validator leaves the body readable
+		var order struct {
+			Customer string `json:"customer"`
+		}
+		if err := DecodeBody(r, &order); err != nil {
+			WriteValidationProblem(w, err)
+			return
+		}
+		WriteJSON(w, http.StatusCreated, map[string]string{"operation": op.OperationID, "customer": order.Customer})
+	})
+	if id, _ := route.GetMeta("operationId"); id != "createOrder" {
+		t.Errorf("expected the operationId meta, got %v", id)
+	}
+	srv := goservetest.New(t, server.New().AddRoutes([]server.RouteInfo{route}))
+
+	srv.POST("/orders").WithJSON(map[string]interface{}{
+		"customer": "alice@example.com",
+		"items":    []map[string]interface{}{{"sku": "ABC-1", "quantity": 1}},
+	}).Expect(t).Status(http.StatusCreated).Body(`{"customer":"alice@example.com","operation":"createOrder"}` + "\n")
+
+	problem := srv.POST("/orders").WithJSON(map[string]interface{}{"items": []interface{}{}}).Expect(t).Status(http.StatusBadRequest).Problem()
+	if problem.Detail != "request does not match the API schema" {
+		t.Errorf("unexpected detail %q", problem.Detail)
+	}
+	errors, _ := problem.Extra["errors"].([]interface{})
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", problem.Extra["errors"])
+	}
+	first, _ := errors[0].(map[string]interface{})
+	if first["path"] != "body.customer" || first["message"] != "is required" {
+		t.Errorf("unexpected error %v", first)
+	}
+}
+
+func TestRouteUnknownOperation(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an operation missing from the document")
+		}
+	}()
+	Route(loadOrders(t), "DELETE", "/orders", func(http.ResponseWriter, *http.Request, *Operation) {})
+}
@@ -0,0 +1,35 @@
+package openapi
+
+import (
+	"fmt"
+	"goserve/server"
+	"net/http"
+)
+
+// OperationHandler receives the matched operation along with the request
+type OperationHandler func(w http.ResponseWriter, r *http.Request, op *Operation)
+
+// Route creates a server route for an operation of the document. Requests are
+// validated against the operation before reaching the handler.
+func Route(doc *Document, method, path string, handler OperationHandler) server.RouteInfo {
+	op := doc.Operation(method, path)
+	if op == nil {
+		panic(fmt.Sprintf("openapi: no operation %s %s in document", method, path))
+	}
+
+	route := server.CreateRoute(server.Http_Method(op.Method), op.Path, func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, op)
+	})
+
+	route.WithTags(op.Tags...).
+		WithMiddleware(Validator(op)).
+		WithMeta("openapi.operation", op)
+	if op.OperationID != "" {
+		route.WithMeta("operationId", op.OperationID)
+	}
+	if op.Summary != "" {
+		route.WithMeta("summary", op.Summary)
+	}
+
+	return route
+}
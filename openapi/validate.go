@@ -0,0 +1,285 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldError describes a single schema violation
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError aggregates every violation found in a value
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		if fieldErr.Path == "" {
+			messages[i] = fieldErr.Message
+		} else {
+			messages[i] = fmt.Sprintf("%s: %s", fieldErr.Path, fieldErr.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into an
+// interface{}) against the schema
+func (s *Schema) Validate(value interface{}) error {
+	result := &ValidationError{}
+	validateValue(s, value, "", result)
+	return result.orNil()
+}
+
+var patternCache sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := patternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+func validateValue(schema *Schema, value interface{}, path string, result *ValidationError) {
+	schema = schema.Resolve()
+	if schema == nil {
+		return
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			result.add(path, "must not be null")
+		}
+		return
+	}
+
+	for _, part := range schema.AllOf {
+		validateValue(part, value, path, result)
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, option := range schema.OneOf {
+			if option.Validate(value) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			result.add(path, "must match exactly one schema in oneOf (matched %d)", matches)
+		}
+	}
+	if len(schema.AnyOf) > 0 {
+		matched := false
+		for _, option := range schema.AnyOf {
+			if option.Validate(value) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result.add(path, "must match at least one schema in anyOf")
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		result.add(path, "must be one of %v", schema.Enum)
+	}
+
+	switch schema.Type {
+	case "":
+		if len(schema.Properties) > 0 {
+			if object, ok := value.(map[string]interface{}); ok {
+				validateObject(schema, object, path, result)
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			result.add(path, "must be an object")
+			return
+		}
+		validateObject(schema, object, path, result)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			result.add(path, "must be an array")
+			return
+		}
+		validateArray(schema, items, path, result)
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			result.add(path, "must be a string")
+			return
+		}
+		validateString(schema, str, path, result)
+	case "integer":
+		number, ok := toFloat(value)
+		if !ok || number != math.Trunc(number) {
+			result.add(path, "must be an integer")
+			return
+		}
+		validateNumber(schema, number, path, result)
+	case "number":
+		number, ok := toFloat(value)
+		if !ok {
+			result.add(path, "must be a number")
+			return
+		}
+		validateNumber(schema, number, path, result)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			result.add(path, "must be a boolean")
+		}
+	}
+}
+
+func validateObject(schema *Schema, object map[string]interface{}, path string, result *ValidationError) {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			result.add(joinPath(path, name), "is required")
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := object[key]
+		if property, ok := schema.Properties[key]; ok {
+			validateValue(property, child, joinPath(path, key), result)
+		} else if schema.AdditionalProperties != nil {
+			validateValue(schema.AdditionalProperties, child, joinPath(path, key), result)
+		} else if schema.NoAdditional {
+			result.add(joinPath(path, key), "is not allowed")
+		}
+	}
+}
+
+func validateArray(schema *Schema, items []interface{}, path string, result *ValidationError) {
+	if schema.MinItems != nil && len(items) < *schema.MinItems {
+		result.add(path, "must contain at least %d items", *schema.MinItems)
+	}
+	if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+		result.add(path, "must contain at most %d items", *schema.MaxItems)
+	}
+	if schema.UniqueItems {
+		for i := 0; i < len(items); i++ {
+			for j := i + 1; j < len(items); j++ {
+				if reflect.DeepEqual(items[i], items[j]) {
+					result.add(path, "items must be unique")
+					i = len(items)
+					break
+				}
+			}
+		}
+	}
+	if schema.Items != nil {
+		for i, item := range items {
+			validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), result)
+		}
+	}
+}
+
+func validateString(schema *Schema, str string, path string, result *ValidationError) {
+	length := len([]rune(str))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		result.add(path, "must be at least %d characters", *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		result.add(path, "must be at most %d characters", *schema.MaxLength)
+	}
+	if schema.Pattern != "" {
+		re, err := compilePattern(schema.Pattern)
+		if err != nil {
+			result.add(path, "invalid pattern %q in schema", schema.Pattern)
+		} else if !re.MatchString(str) {
+			result.add(path, "must match pattern %s", schema.Pattern)
+		}
+	}
+	if err := checkFormat(schema.Format, str); err != nil {
+		result.add(path, "%v", err)
+	}
+}
+
+func validateNumber(schema *Schema, number float64, path string, result *ValidationError) {
+	if schema.Minimum != nil {
+		if schema.ExclusiveMinimum && number <= *schema.Minimum {
+			result.add(path, "must be greater than %v", *schema.Minimum)
+		} else if number < *schema.Minimum {
+			result.add(path, "must be greater than or equal to %v", *schema.Minimum)
+		}
+	}
+	if schema.Maximum != nil {
+		if schema.ExclusiveMaximum && number >= *schema.Maximum {
+			result.add(path, "must be less than %v", *schema.Maximum)
+		} else if number > *schema.Maximum {
+			result.add(path, "must be less than or equal to %v", *schema.Maximum)
+		}
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+		left, leftOk := toFloat(candidate)
+		right, rightOk := toFloat(value)
+		if leftOk && rightOk && left == right {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func loadOrders(t *testing.T) *Document {
+	t.Helper()
+	doc, err := Load("testdata/orders.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestSchemaValidate(t *testing.T) {
+	order := loadOrders(t).Components.Schemas["Order"]
+
+	tests := []struct {
+		name string
+		body string
+		// Expected violations, none when empty
+		errors string
+	}{
+		{
+			name: "valid",
+			body: `{"customer": "alice@example.com", "items": [{"sku": "ABC-1", "quantity": 2}], "total": 12.5, "note": null,
+				"createdAt": "2026-10-19T12:00:00Z", "deliveryDate": "2026-10-21", "id": "123e4567-e89b-12d3-a456-426614174000"}`,
+		},
+		{
+			name:   "required fields",
+			body:   `{}`,
+			errors: "customer: is required; items: is required",
+		},
+		{
+			name:   "formats",
+			body:   `{"customer": "alice", "items": [{"sku": "ABC-1", "quantity": 1}], "createdAt": "2026-10-19", "deliveryDate": "19/10/2026", "id": "42"}`,
+			errors: "createdAt: must be an RFC 3339 date-time; customer: must be an email address; deliveryDate: must be a full-date (YYYY-MM-DD); id: must be a UUID",
+		},
+		{
+			name:   "nested items",
+			body:   `{"customer": "alice@example.com", "items": [{"sku": "abc", "quantity": 0.5, "color": "red"}, {"quantity": 0}]}`,
+			errors: "items[0].color: is not allowed; items[0].quantity: must be an integer; items[0].sku: must match pattern ^[A-Z]{3}-[0-9]+$; items[1].sku: is required; items[1].quantity: must be greater than or equal to 1",
+		},
+		{
+			name:   "bounds and lengths",
+			body:   `{"customer": "alice@example.com", "items": [], "total": 0, "note": "far too long"}`,
+			errors: "items: must contain at least 1 items; note: must be at most 10 characters; total: must be greater than 0",
+		},
+		{
+			name:   "types",
+			body:   `{"customer": 42, "items": {}, "total": "12"}`,
+			errors: "customer: must be a string; items: must be an array; total: must be a number",
+		},
+		{
+			name:   "not an object",
+			body:   `[]`,
+			errors: "must be an object",
+		},
+		{
+			name:   "null",
+			body:   `null`,
+			errors: "must not be null",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(test.body), &value); err != nil {
+				t.Fatal(err)
+			}
+			err := order.Validate(value)
+			switch {
+			case test.errors == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case test.errors != "" && (err == nil || err.Error() != test.errors):
+				t.Errorf("expected %q, got %v", test.errors, err)
+			}
+		})
+	}
+}
+
+func TestCombinedSchemas(t *testing.T) {
+	var schema Schema
+	err := json.Unmarshal([]byte(`{
+		"oneOf": [{"type": "string"}, {"type": "integer"}, {"type": "number", "maximum": 10}],
+		"anyOf": [{"type": "string", "minLength": 2}, {"type": "number"}],
+		"allOf": [{"enum": ["ok", 1, 20]}]
+	}`), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value  interface{}
+		errors string
+	}{
+		{"ok", ""},
+		{20.0, ""},
+		{"x", "must be one of [ok 1 20]; must match at least one schema in anyOf"},
+		// 1 is both an integer and a number below 10
+		{1.0, "must match exactly one schema in oneOf (matched 2)"},
+		{true, "must be one of [ok 1 20]; must match exactly one schema in oneOf (matched 0); must match at least one schema in anyOf"},
+	}
+	for _, test := range tests {
+		err := schema.Validate(test.value)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.errors {
+			t.Errorf("%v: expected %q, got %q", test.value, test.errors, got)
+		}
+	}
+
+	var unique Schema
+	json.Unmarshal([]byte(`{"type": "array", "uniqueItems": true, "maxItems": 2}`), &unique)
+	if err := unique.Validate([]interface{}{1.0, 2.0, 1.0}); err == nil || !strings.Contains(err.Error(), "items must be unique") || !strings.Contains(err.Error(), "at most 2 items") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Package scaffold generates goserve projects and code from templates:
+//
+//	project     a new service: main, configuration per environment, a route
+//	            group with a typed handler, tests and a sample .http file
+//	group       a package of routes sharing a path prefix and a tag
+//	handler     a typed handler with its route and test, in a group
+//	middleware  a middleware with its test
+//
+// Templates are text/template files ending in .tmpl, laid out as the files
+// they produce under a directory per kind. File paths are templates too.
+// A directory given with WithTemplates, or named by GOSERVE_TEMPLATES,
+// replaces the built-in templates of the kinds it contains, so a platform
+// team can publish its own project layout.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"goserve/internal/naming"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplatesEnv names a directory of templates overriding the built-in ones
+const TemplatesEnv = "GOSERVE_TEMPLATES"
+
+//go:embed all:templates
+var builtin embed.FS
+
+// Data is available to the templates
+type Data struct {
+	// Name as given on the command line, e.g. orders or request-id
+	Name string
+	// Module path of the project
+	Module string
+	// Group is the package receiving a handler
+	Group string
+	// GoServe is the local path of goserve, added as a replace directive to
+	// go.mod when set
+	GoServe string
+}
+
+// GoName is the exported Go identifier of the name: request-id gives RequestID
+func (d Data) GoName() string {
+	return naming.GoName(d.Name)
+}
+
+// Package is the name as a Go package: request-id gives requestid
+func (d Data) Package() string {
+	return strings.ReplaceAll(naming.Kebab(d.Name), "-", "")
+}
+
+// File is the name as a Go file name without extension: request_id
+func (d Data) File() string {
+	return strings.ReplaceAll(naming.Kebab(d.Name), "-", "_")
+}
+
+// Path is the name as a URL segment: request-id
+func (d Data) Path() string {
+	return naming.Kebab(d.Name)
+}
+
+var funcs = template.FuncMap{
+	"goName":     naming.GoName,
+	"lowerCamel": naming.LowerCamel,
+	"kebab":      naming.Kebab,
+}
+
+type Generator struct {
+	builtin   fs.FS
+	overrides fs.FS
+}
+
+// New creates a generator with the built-in templates, overridden by the
+// directory of GOSERVE_TEMPLATES when set
+func New() *Generator {
+	templates, _ := fs.Sub(builtin, "templates")
+	g := &Generator{builtin: templates}
+	if dir := os.Getenv(TemplatesEnv); dir != "" {
+		g.WithTemplates(dir)
+	}
+	return g
+}
+
+// WithTemplates overrides the built-in templates with the kinds found in
+// dir: dir/project replaces the whole project layout, dir/handler the
+// handler files, and so on
+func (g *Generator) WithTemplates(dir string) *Generator {
+	g.overrides = os.DirFS(dir)
+	return g
+}
+
+// Kinds lists what can be generated
+func (g *Generator) Kinds() []string {
+	seen := make(map[string]bool)
+	for _, fsys := range []fs.FS{g.builtin, g.overrides} {
+		if fsys == nil {
+			continue
+		}
+		entries, _ := fs.ReadDir(fsys, ".")
+		for _, entry := range entries {
+			if entry.IsDir() {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+	kinds := make([]string, 0, len(seen))
+	for kind := range seen {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (g *Generator) templates(kind string) (fs.FS, error) {
+	if g.overrides != nil {
+		if info, err := fs.Stat(g.overrides, kind); err == nil && info.IsDir() {
+			return fs.Sub(g.overrides, kind)
+		}
+	}
+	if info, err := fs.Stat(g.builtin, kind); err == nil && info.IsDir() {
+		return fs.Sub(g.builtin, kind)
+	}
+	return nil, fmt.Errorf("unknown template %q, expected one of %s", kind, strings.Join(g.Kinds(), ", "))
+}
+
+// Generate writes the files of kind under dir and returns their paths.
+// Nothing is written when one of the files already exists.
+func (g *Generator) Generate(kind, dir string, data Data) ([]string, error) {
+	templates, err := g.templates(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	err = fs.WalkDir(templates, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		target, content, err := render(templates, name, data)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join(dir, filepath.FromSlash(target))] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if _, err := os.Stat(name); err == nil {
+			return nil, fmt.Errorf("%s already exists", name)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(name, files[name], 0644); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// render returns the path and content produced by a template file. Files
+// without the .tmpl extension are copied, Go files are formatted.
+func render(templates fs.FS, name string, data Data) (string, []byte, error) {
+	raw, err := fs.ReadFile(templates, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	target, err := execute(name, name, data)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasSuffix(target, ".tmpl") {
+		return target, raw, nil
+	}
+	target = strings.TrimSuffix(target, ".tmpl")
+
+	content, err := execute(name, string(raw), data)
+	if err != nil {
+		return "", nil, err
+	}
+	if path.Ext(target) == ".go" {
+		formatted, err := format.Source([]byte(content))
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return target, formatted, nil
+	}
+	return target, []byte(content), nil
+}
+
+func execute(name, text string, data Data) (string, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// ModulePath reads the module path of the go.mod in dir or its parents
+func ModulePath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					return strings.Trim(strings.TrimSpace(module), `"`), nil
+				}
+			}
+			return "", fmt.Errorf("%s: missing module directive", filepath.Join(dir, "go.mod"))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
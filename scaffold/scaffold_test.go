@@ -0,0 +1,159 @@
+package scaffold
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDataNames(t *testing.T) {
+	tests := []struct {
+		name                       string
+		goName, pkg, file, urlPath string
+	}{
+		{"orders", "Orders", "orders", "orders", "orders"},
+		{"request-id", "RequestID", "requestid", "request_id", "request-id"},
+		{"userProfile", "UserProfile", "userprofile", "user_profile", "user-profile"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := Data{Name: test.name}
+			got := []string{data.GoName(), data.Package(), data.File(), data.Path()}
+			expected := []string{test.goName, test.pkg, test.file, test.urlPath}
+			if strings.Join(got, " ") != strings.Join(expected, " ") {
+				t.Errorf("expected %v, got %v", expected, got)
+			}
+		})
+	}
+}
+
+// TestGeneratedProject generates a project with a group, a handler and a
+// middleware, and runs its tests, written with goservetest, against this
+// checkout of goserve
+func TestGeneratedProject(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a generated project")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	goserve, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "orders")
+	generator := New()
+	steps := []struct {
+		kind string
+		data Data
+	}{
+		{"project", Data{Name: "orders", Module: "example.com/orders", GoServe: goserve}},
+		{"group", Data{Name: "billing", Module: "example.com/orders"}},
+		{"handler", Data{Name: "invoice-status", Group: "billing", Module: "example.com/orders"}},
+		{"middleware", Data{Name: "request-id", Module: "example.com/orders"}},
+	}
+	for _, step := range steps {
+		files, err := generator.Generate(step.kind, dir, step.data)
+		if err != nil {
+			t.Fatalf("%s: %v", step.kind, err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("%s: no file generated", step.kind)
+		}
+	}
+	for _, file := range []string{"go.mod", "main.go", "api.http", "internal/billing/routes.go", "internal/billing/invoice_status.go", "internal/middleware/request_id_test.go"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("expected %s: %v", file, err)
+		}
+	}
+
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "./..."}} {
+		command := exec.Command(goTool, args...)
+		command.Dir = dir
+		command.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+}
+
+func TestGenerateKeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := Data{Name: "request-id", Module: "example.com/orders"}
+	if _, err := New().Generate("middleware", dir, data); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "internal", "middleware", "request_id.go")
+	if err := os.WriteFile(filename, []byte("package middleware\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New().Generate("middleware", dir, data)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an already exists error, got %v", err)
+	}
+	if content, _ := os.ReadFile(filename); string(content) != "package middleware\n" {
+		t.Errorf("expected the existing file to be kept, got %q", content)
+	}
+}
+
+func TestTemplateOverrides(t *testing.T) {
+	templates := t.TempDir()
+	handler := filepath.Join(templates, "handler", "{{.Group}}")
+	if err := os.MkdirAll(handler, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(handler, "{{.File}}.go.tmpl"), []byte("package {{.Group}}\n\n// {{.GoName}} is custom\nfunc {{.GoName}}() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(handler, "README"), []byte("{{.Name}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(TemplatesEnv, templates)
+
+	dir := t.TempDir()
+	files, err := New().Generate("handler", dir, Data{Name: "get-user", Group: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "users", "README"), filepath.Join(dir, "users", "get_user.go")}
+	if strings.Join(files, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected only the override files %v, got %v", expected, files)
+	}
+	content, _ := os.ReadFile(expected[1])
+	if !strings.Contains(string(content), "// GetUser is custom") {
+		t.Errorf("unexpected content %q", content)
+	}
+	// Files without .tmpl are copied as they are
+	if readme, _ := os.ReadFile(expected[0]); string(readme) != "{{.Name}}" {
+		t.Errorf("unexpected README %q", readme)
+	}
+
+	// The kinds not overridden keep the built-in templates
+	if _, err := New().Generate("middleware", t.TempDir(), Data{Name: "auth"}); err != nil {
+		t.Error(err)
+	}
+	_, err = New().Generate("worker", dir, Data{Name: "x"})
+	if err == nil || !strings.Contains(err.Error(), `unknown template "worker", expected one of group, handler, middleware, project`) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module \"example.com/orders\"\n\ngo 1.25\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "internal", "billing")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	module, err := ModulePath(nested)
+	if err != nil || module != "example.com/orders" {
+		t.Errorf("expected example.com/orders, got %q (%v)", module, err)
+	}
+}
@@ -0,0 +1,44 @@
+package secure
+
+import "testing"
+
+func TestCSPBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		csp      *CSP
+		nonce    string
+		expected string
+	}{
+		{"empty", NewCSP(), "", ""},
+		{"set", NewCSP().Set("default-src", "'self'").Set("img-src", "'self'", "data:"), "", "default-src 'self'; img-src 'self' data:"},
+		{"set replaces", NewCSP().Set("img-src", "'self'").Set("img-src", "'none'"), "", "img-src 'none'"},
+		{"add", NewCSP().Set("img-src", "'self'").Add("img-src", "https://cdn.example.com").Add("font-src", "'self'"), "", "img-src 'self' https://cdn.example.com; font-src 'self'"},
+		{"remove", NewCSP().Set("default-src", "'self'").Set("img-src", "'self'").Remove("default-src"), "", "img-src 'self'"},
+		{"without sources", NewCSP().Set("default-src", "'self'").Set("upgrade-insecure-requests"), "", "default-src 'self'; upgrade-insecure-requests"},
+		{"nonce", NewCSP().Set("script-src", "'self'").WithNonce(), "abc", "script-src 'self' 'nonce-abc'"},
+		{"nonce without sources", NewCSP().Set("style-src").WithNonce("style-src"), "abc", "style-src 'nonce-abc'"},
+		{"nonce of a missing directive", NewCSP().Set("default-src", "'self'").WithNonce("script-src"), "abc", "default-src 'self'"},
+		{"no nonce", NewCSP().Set("script-src", "'self'").WithNonce(), "", "script-src 'self'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := test.csp.Build(test.nonce); value != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestCSPNonceDoesNotLeak(t *testing.T) {
+	csp := NewCSP().Set("script-src", "'self'").WithNonce("script-src", "script-src")
+	csp.Build("first")
+	if value := csp.Build("second"); value != "script-src 'self' 'nonce-second'" {
+		t.Errorf("expected the nonce of the second request only, got %q", value)
+	}
+	if csp.String() != "script-src 'self'" {
+		t.Errorf("expected String without a nonce, got %q", csp.String())
+	}
+	if !csp.UsesNonce() || NewCSP().UsesNonce() {
+		t.Error("expected UsesNonce to follow WithNonce")
+	}
+}
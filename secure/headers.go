@@ -0,0 +1,183 @@
+// Package secure sets the security headers of the responses:
+//
+//	headers := secure.New(secure.Defaults(config))
+//	builder.
+//		AddGlobalMiddleware("secure", headers.Middleware()).
+//		AddRoutes([]server.RouteInfo{headers.ReportRoute("/csp-reports")})
+//
+// The defaults depend on the environment: production enforces the
+// Content-Security-Policy and sends HSTS, other environments only report
+// CSP violations, to the logs when ReportRoute is used, and never pin the
+// host to HTTPS.
+package secure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"goserve/configuration"
+	"goserve/server"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options are the headers sent with every response; empty ones are not sent
+type Options struct {
+	// Strict-Transport-Security max-age, not sent when 0. Only sent over
+	// TLS, browsers ignore it otherwise.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// Takes X-Forwarded-Proto into account for HSTS, behind a TLS
+	// terminating proxy only
+	TrustForwardedProto bool
+
+	// X-Frame-Options, DENY or SAMEORIGIN
+	FrameOptions string
+	// X-Content-Type-Options: nosniff unless disabled
+	DisableNoSniff    bool
+	ReferrerPolicy    string
+	PermissionsPolicy string
+	// Cross-Origin-Opener-Policy and Cross-Origin-Embedder-Policy
+	CrossOriginOpenerPolicy   string
+	CrossOriginEmbedderPolicy string
+
+	// Content-Security-Policy, not sent when nil
+	CSP *CSP
+	// Sends Content-Security-Policy-Report-Only: violations are reported
+	// but nothing is blocked
+	CSPReportOnly bool
+}
+
+// Defaults returns secure headers for the environment of config. COEP is
+// left out everywhere since it blocks cross-origin resources not opting in.
+func Defaults(config configuration.Configuration) Options {
+	options := Options{
+		FrameOptions:            "DENY",
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		PermissionsPolicy:       "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy: "same-origin",
+		CSP:                     DefaultCSP(),
+		CSPReportOnly:           true,
+	}
+	if config != nil && config.IsProduction() {
+		options.HSTSMaxAge = 365 * 24 * time.Hour
+		options.HSTSIncludeSubdomains = true
+		options.CSPReportOnly = false
+	}
+	return options
+}
+
+// Headers sets the headers of Options and collects CSP reports
+type Headers struct {
+	options Options
+	hsts    string
+	// Path of the report endpoint, set by ReportRoute
+	reportPath string
+
+	mu      sync.Mutex
+	reports []Report
+	// Directives and blocked URLs already logged
+	logged map[string]bool
+}
+
+func New(options Options) *Headers {
+	h := &Headers{options: options}
+	if options.HSTSMaxAge > 0 {
+		h.hsts = fmt.Sprintf("max-age=%d", int(options.HSTSMaxAge.Seconds()))
+		if options.HSTSIncludeSubdomains {
+			h.hsts += "; includeSubDomains"
+		}
+		if options.HSTSPreload {
+			h.hsts += "; preload"
+		}
+	}
+	return h
+}
+
+// Middleware sets the headers before the handler, which can still change
+// them, and the nonce of the request when the CSP uses one
+func (h *Headers) Middleware() server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			if h.hsts != "" && h.isTLS(r) {
+				header.Set("Strict-Transport-Security", h.hsts)
+			}
+			if !h.options.DisableNoSniff {
+				header.Set("X-Content-Type-Options", "nosniff")
+			}
+			setIfAny(header, "X-Frame-Options", h.options.FrameOptions)
+			setIfAny(header, "Referrer-Policy", h.options.ReferrerPolicy)
+			setIfAny(header, "Permissions-Policy", h.options.PermissionsPolicy)
+			setIfAny(header, "Cross-Origin-Opener-Policy", h.options.CrossOriginOpenerPolicy)
+			setIfAny(header, "Cross-Origin-Embedder-Policy", h.options.CrossOriginEmbedderPolicy)
+
+			if csp := h.options.CSP; csp != nil {
+				var nonce string
+				if csp.UsesNonce() {
+					nonce = newNonce()
+					r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce))
+				}
+				value := csp.Build(nonce)
+				if h.reportPath != "" {
+					value += "; report-uri " + h.reportPath + "; report-to csp"
+					header.Set("Reporting-Endpoints", fmt.Sprintf("csp=%q", h.reportPath))
+				}
+				if h.options.CSPReportOnly {
+					header.Set("Content-Security-Policy-Report-Only", value)
+				} else {
+					header.Set("Content-Security-Policy", value)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *Headers) isTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return h.options.TrustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+func setIfAny(header http.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	}
+}
+
+func newNonce() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return base64.RawURLEncoding.EncodeToString(random)
+}
+
+type nonceKey struct{}
+
+// Nonce returns the CSP nonce of the request, empty without one
+func Nonce(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	nonce, _ := r.Context().Value(nonceKey{}).(string)
+	return nonce
+}
+
+// TemplateFuncs returns cspNonce for the templates rendering the request.
+// Templates are parsed with the functions of a nil request and cloned per
+// request:
+//
+//	<script nonce="{{ cspNonce }}">...</script>
+//
+//	template.Must(page.Clone()).Funcs(secure.TemplateFuncs(r)).Execute(w, data)
+func TemplateFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"cspNonce": func() string { return Nonce(r) },
+	}
+}
@@ -0,0 +1,181 @@
+package secure
+
+import (
+	"crypto/tls"
+	"goserve/configuration"
+	"goserve/configuration/env"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(h *Headers, r *http.Request, handler http.HandlerFunc) http.Header {
+	if handler == nil {
+		handler = func(w http.ResponseWriter, r *http.Request) {}
+	}
+	recorder := httptest.NewRecorder()
+	h.Middleware()(handler).ServeHTTP(recorder, r)
+	return recorder.Header()
+}
+
+func loadEnvironment(t *testing.T, environment env.Environment) configuration.Configuration {
+	t.Helper()
+	config, err := configuration.New().Load(func(c *configuration.Config) { c.Server.Environment = environment })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestHSTS(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		tls      bool
+		proto    string
+		expected string
+	}{
+		{"over TLS", Options{HSTSMaxAge: time.Hour}, true, "", "max-age=3600"},
+		{"without TLS", Options{HSTSMaxAge: time.Hour}, false, "", ""},
+		{"untrusted proxy", Options{HSTSMaxAge: time.Hour}, false, "https", ""},
+		{"trusted proxy", Options{HSTSMaxAge: time.Hour, TrustForwardedProto: true}, false, "HTTPS", "max-age=3600"},
+		{"trusted proxy over HTTP", Options{HSTSMaxAge: time.Hour, TrustForwardedProto: true}, false, "http", ""},
+		{"disabled", Options{}, true, "", ""},
+		{"subdomains and preload", Options{HSTSMaxAge: 2 * time.Hour, HSTSIncludeSubdomains: true, HSTSPreload: true}, true, "", "max-age=7200; includeSubDomains; preload"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if test.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", test.proto)
+			}
+			if hsts := serve(New(test.options), r, nil).Get("Strict-Transport-Security"); hsts != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, hsts)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	common := map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "strict-origin-when-cross-origin",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "",
+	}
+	tests := []struct {
+		environment env.Environment
+		hsts        string
+		enforced    bool
+	}{
+		{env.Production, "max-age=31536000; includeSubDomains", true},
+		{env.Staging, "", false},
+		{env.Development, "", false},
+	}
+	for _, test := range tests {
+		t.Run(string(test.environment), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = &tls.ConnectionState{}
+			header := serve(New(Defaults(loadEnvironment(t, test.environment))), r, nil)
+
+			for name, expected := range common {
+				if value := header.Get(name); value != expected {
+					t.Errorf("expected %s %q, got %q", name, expected, value)
+				}
+			}
+			if hsts := header.Get("Strict-Transport-Security"); hsts != test.hsts {
+				t.Errorf("expected HSTS %q, got %q", test.hsts, hsts)
+			}
+			enforced, reported := header.Get("Content-Security-Policy"), header.Get("Content-Security-Policy-Report-Only")
+			if test.enforced && (enforced == "" || reported != "") {
+				t.Errorf("expected an enforced policy, got %q and report-only %q", enforced, reported)
+			}
+			if !test.enforced && (enforced != "" || reported == "") {
+				t.Errorf("expected a report-only policy, got %q and report-only %q", enforced, reported)
+			}
+		})
+	}
+
+	if options := Defaults(nil); options.HSTSMaxAge != 0 || !options.CSPReportOnly {
+		t.Errorf("expected the development defaults without configuration, got %+v", options)
+	}
+}
+
+func TestReportOnly(t *testing.T) {
+	headers := New(Options{CSP: NewCSP().Set("default-src", "'self'"), CSPReportOnly: true})
+	header := serve(headers, httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	if value := header.Get("Content-Security-Policy-Report-Only"); value != "default-src 'self'" {
+		t.Errorf("unexpected report-only policy %q", value)
+	}
+	if header.Get("Content-Security-Policy") != "" || header.Get("Reporting-Endpoints") != "" {
+		t.Errorf("expected only the report-only header, got %v", header)
+	}
+
+	headers.ReportRoute("/csp-reports")
+	header = serve(headers, httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	expected := "default-src 'self'; report-uri /csp-reports; report-to csp"
+	if value := header.Get("Content-Security-Policy-Report-Only"); value != expected {
+		t.Errorf("expected %q, got %q", expected, value)
+	}
+	if endpoints := header.Get("Reporting-Endpoints"); endpoints != `csp="/csp-reports"` {
+		t.Errorf("unexpected Reporting-Endpoints %q", endpoints)
+	}
+}
+
+var noncePattern = regexp.MustCompile(`script-src 'self' 'nonce-([A-Za-z0-9_-]{22})'`)
+
+func TestNoncePerRequest(t *testing.T) {
+	headers := New(Options{CSP: DefaultCSP()})
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		var seen string
+		header := serve(headers, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+			seen = Nonce(r)
+		})
+
+		policy := header.Get("Content-Security-Policy")
+		match := noncePattern.FindStringSubmatch(policy)
+		if match == nil {
+			t.Fatalf("expected a script-src nonce in %q", policy)
+		}
+		if match[1] != seen {
+			t.Errorf("expected the handler to see the nonce %q of the header, got %q", match[1], seen)
+		}
+		if !strings.Contains(policy, "style-src 'self' 'nonce-"+seen+"'") || strings.Contains(policy, "img-src 'self' data: 'nonce-") {
+			t.Errorf("expected the nonce in script-src and style-src only, got %q", policy)
+		}
+		nonces = append(nonces, seen)
+	}
+	if nonces[0] == nonces[1] {
+		t.Errorf("expected a nonce per request, got %q twice", nonces[0])
+	}
+
+	if nonce := Nonce(httptest.NewRequest(http.MethodGet, "/", nil)); nonce != "" {
+		t.Errorf("expected no nonce outside the middleware, got %q", nonce)
+	}
+	without := serve(New(Options{CSP: NewCSP().Set("default-src", "'self'")}), httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+		if nonce := Nonce(r); nonce != "" {
+			t.Errorf("expected no nonce for a policy without one, got %q", nonce)
+		}
+	})
+	if policy := without.Get("Content-Security-Policy"); policy != "default-src 'self'" {
+		t.Errorf("unexpected policy %q", policy)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	serve(New(Options{CSP: DefaultCSP()}), r, func(w http.ResponseWriter, r *http.Request) {
+		nonce := TemplateFuncs(r)["cspNonce"].(func() string)()
+		if nonce == "" || nonce != Nonce(r) {
+			t.Errorf("expected cspNonce to return the nonce of the request, got %q", nonce)
+		}
+	})
+}
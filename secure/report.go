@@ -0,0 +1,169 @@
+package secure
+
+import (
+	"encoding/json"
+	"goserve/server"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxReports is the number of CSP reports kept by Headers
+const maxReports = 100
+
+// maxReportSize limits the body of a report request
+const maxReportSize = 64 << 10
+
+// maxLogged is the number of distinct violations remembered so that each is
+// logged once; the memory is cleared when it is reached
+const maxLogged = 1000
+
+// Report is a CSP violation sent by a browser
+type Report struct {
+	Time        time.Time `json:"time"`
+	DocumentURL string    `json:"documentURL"`
+	BlockedURL  string    `json:"blockedURL"`
+	Directive   string    `json:"directive"`
+	// "enforce" or "report"
+	Disposition string `json:"disposition,omitempty"`
+	SourceFile  string `json:"sourceFile,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Sample      string `json:"sample,omitempty"`
+	UserAgent   string `json:"userAgent,omitempty"`
+}
+
+// ReportRoute returns the endpoint collecting the CSP reports of browsers
+// and makes the policy send them there. The first report of a directive
+// and blocked URL is logged, so a page violating the policy on every view
+// does not flood the logs, and the last reports are kept for Reports. The route is exempt from CSRF checks since
+// browsers send reports without a token.
+func (h *Headers) ReportRoute(path string) server.RouteInfo {
+	h.reportPath = path
+	return server.CreatePOST(path, h.collect).WithMeta(server.MetaCSRF, false)
+}
+
+// Reports returns the last CSP reports received, newest first
+func (h *Headers) Reports() []Report {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	reports := slices.Clone(h.reports)
+	slices.Reverse(reports)
+	return reports
+}
+
+func (h *Headers) collect(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+	if err != nil {
+		server.WriteProblem(w, server.NewProblem(http.StatusRequestEntityTooLarge, "report too large"))
+		return
+	}
+	reports, err := parseReports(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		server.WriteProblem(w, server.NewProblem(http.StatusBadRequest, "invalid CSP report: "+err.Error()))
+		return
+	}
+
+	now := time.Now().UTC()
+	h.mu.Lock()
+	for _, report := range reports {
+		report.Time = now
+		if report.UserAgent == "" {
+			report.UserAgent = r.UserAgent()
+		}
+		h.logOnce(report)
+		h.reports = append(h.reports, report)
+	}
+	if extra := len(h.reports) - maxReports; extra > 0 {
+		h.reports = slices.Delete(h.reports, 0, extra)
+	}
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logOnce logs the first report of a directive and blocked URL, h.mu held
+func (h *Headers) logOnce(report Report) {
+	key := report.Directive + " " + report.BlockedURL
+	if h.logged[key] {
+		return
+	}
+	if h.logged == nil || len(h.logged) >= maxLogged {
+		h.logged = make(map[string]bool)
+	}
+	h.logged[key] = true
+	// The fields come from the client, quoted so that they cannot forge log
+	// lines
+	log.Printf("CSP violation (%q): %q blocked %q on %q", report.Disposition, report.Directive, report.BlockedURL, report.DocumentURL)
+}
+
+// parseReports reads the application/csp-report body of report-uri, or the
+// application/reports+json body of the Reporting API used by report-to
+func parseReports(contentType string, body []byte) ([]Report, error) {
+	if strings.HasPrefix(contentType, "application/reports+json") {
+		var batch []struct {
+			Type      string `json:"type"`
+			UserAgent string `json:"user_agent"`
+			Body      struct {
+				DocumentURL        string `json:"documentURL"`
+				BlockedURL         string `json:"blockedURL"`
+				EffectiveDirective string `json:"effectiveDirective"`
+				Disposition        string `json:"disposition"`
+				SourceFile         string `json:"sourceFile"`
+				LineNumber         int    `json:"lineNumber"`
+				Sample             string `json:"sample"`
+			} `json:"body"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		reports := make([]Report, 0, len(batch))
+		for _, item := range batch {
+			if item.Type != "csp-violation" {
+				continue
+			}
+			reports = append(reports, Report{
+				DocumentURL: item.Body.DocumentURL,
+				BlockedURL:  item.Body.BlockedURL,
+				Directive:   item.Body.EffectiveDirective,
+				Disposition: item.Body.Disposition,
+				SourceFile:  item.Body.SourceFile,
+				Line:        item.Body.LineNumber,
+				Sample:      item.Body.Sample,
+				UserAgent:   item.UserAgent,
+			})
+		}
+		return reports, nil
+	}
+
+	var legacy struct {
+		Report struct {
+			DocumentURI        string `json:"document-uri"`
+			BlockedURI         string `json:"blocked-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"source-file"`
+			LineNumber         int    `json:"line-number"`
+			ScriptSample       string `json:"script-sample"`
+		} `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	report := legacy.Report
+	directive := report.EffectiveDirective
+	if directive == "" {
+		directive = report.ViolatedDirective
+	}
+	return []Report{{
+		DocumentURL: report.DocumentURI,
+		BlockedURL:  report.BlockedURI,
+		Directive:   directive,
+		Disposition: report.Disposition,
+		SourceFile:  report.SourceFile,
+		Line:        report.LineNumber,
+		Sample:      report.ScriptSample,
+	}}, nil
+}
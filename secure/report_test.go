@@ -0,0 +1,86 @@
+package secure
+
+import (
+	"bytes"
+	"fmt"
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReportRoute(t *testing.T) {
+	headers := New(Options{CSP: DefaultCSP(), CSPReportOnly: true})
+	route := headers.ReportRoute("/csp-reports")
+	if exempt, _ := route.GetMeta(server.MetaCSRF); exempt != false {
+		t.Errorf("expected the report route to be exempt from CSRF checks, got %v", exempt)
+	}
+	srv := goservetest.New(t, server.New().
+		AddGlobalMiddleware("secure", headers.Middleware()).
+		AddRoutes([]server.RouteInfo{route}))
+
+	legacy := `{"csp-report": {"document-uri": "https://example.com/", "blocked-uri": "https://evil.example.net/x.js\nCSP violation (\"enforce\"): forged", "violated-directive": "script-src", "disposition": "report"}}`
+	srv.POST("/csp-reports").WithBody("application/csp-report", []byte(legacy)).Expect(t).Status(http.StatusNoContent)
+
+	batch := `[{"type": "csp-violation", "user_agent": "test", "body": {"documentURL": "https://example.com/a", "blockedURL": "inline", "effectiveDirective": "style-src", "disposition": "enforce"}}, {"type": "deprecation"}]`
+	srv.POST("/csp-reports").WithBody("application/reports+json", []byte(batch)).Expect(t).Status(http.StatusNoContent)
+
+	reports := headers.Reports()
+	if len(reports) != 2 || reports[0].Directive != "style-src" || reports[0].UserAgent != "test" || reports[1].Directive != "script-src" {
+		t.Errorf("unexpected reports %+v", reports)
+	}
+
+	for _, line := range strings.Split(srv.Logs(), "\n") {
+		if strings.HasPrefix(line, `CSP violation ("enforce"): forged`) {
+			t.Errorf("a report forged a log line: %q", line)
+		}
+	}
+	if !strings.Contains(srv.Logs(), `"https://evil.example.net/x.js\nCSP violation`) {
+		t.Errorf("expected the blocked URL to be quoted in %q", srv.Logs())
+	}
+
+	srv.POST("/csp-reports").WithBody("application/csp-report", []byte("{")).Expect(t).Status(http.StatusBadRequest)
+	large := append([]byte(`{"csp-report": {"blocked-uri": "`), bytes.Repeat([]byte("x"), maxReportSize)...)
+	srv.POST("/csp-reports").WithBody("application/csp-report", large).Expect(t).Status(http.StatusRequestEntityTooLarge)
+}
+
+func TestReportsAreCapped(t *testing.T) {
+	headers := New(Options{})
+	srv := goservetest.New(t, server.New().AddRoutes([]server.RouteInfo{headers.ReportRoute("/csp-reports")}))
+	for i := 0; i < maxReports+5; i++ {
+		srv.POST("/csp-reports").WithBody("application/csp-report", []byte(`{"csp-report": {"violated-directive": "img-src"}}`)).Expect(t).Status(http.StatusNoContent)
+	}
+	if n := len(headers.Reports()); n != maxReports {
+		t.Errorf("expected the last %d reports, got %d", maxReports, n)
+	}
+}
+
+func TestReportsAreLoggedOnce(t *testing.T) {
+	headers := New(Options{})
+	srv := goservetest.New(t, server.New().AddRoutes([]server.RouteInfo{headers.ReportRoute("/csp-reports")}))
+	send := func(directive, blocked string) {
+		report := fmt.Sprintf(`{"csp-report": {"violated-directive": %q, "blocked-uri": %q}}`, directive, blocked)
+		srv.POST("/csp-reports").WithBody("application/csp-report", []byte(report)).Expect(t).Status(http.StatusNoContent)
+	}
+	for i := 0; i < 10; i++ {
+		send("script-src", "https://cdn.example.net/a.js")
+	}
+	send("script-src", "https://cdn.example.net/b.js")
+	send("img-src", "https://cdn.example.net/a.js")
+
+	logs := srv.Logs()
+	if n := strings.Count(logs, "CSP violation"); n != 3 {
+		t.Errorf("expected one log line per directive and blocked URL, got %d in %q", n, logs)
+	}
+	if n := len(headers.Reports()); n != 12 {
+		t.Errorf("expected every report to be kept, got %d", n)
+	}
+
+	for i := 0; i < maxLogged; i++ {
+		send("img-src", fmt.Sprintf("https://cdn.example.net/%d.png", i))
+	}
+	if n := len(headers.logged); n > maxLogged {
+		t.Errorf("expected at most %d logged violations remembered, got %d", maxLogged, n)
+	}
+}
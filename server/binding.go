@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind fills target, a pointer to a struct, from the request. The JSON body
+// is decoded first, then fields tagged `path:"name"`, `query:"name"` and
+// `header:"Name"` are set from the corresponding request values.
+func Bind(r *http.Request, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a pointer to a struct, got %T", target)
+	}
+
+	if err := bindBody(r, target); err != nil {
+		return err
+	}
+	return bindParams(r, value.Elem())
+}
+
+func bindBody(r *http.Request, target interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.HasSuffix(mediaType, "json") {
+			return fmt.Errorf("unsupported content type %q", contentType)
+		}
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("unreadable body: %v", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+func bindParams(r *http.Request, value reflect.Value) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		in, name := ParamTag(fieldType)
+		if in == "" {
+			if fieldType.Anonymous && fieldType.Type.Kind() == reflect.Struct {
+				if err := bindParams(r, value.Field(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		var raw []string
+		switch in {
+		case "path":
+			if v := r.PathValue(name); v != "" {
+				raw = []string{v}
+			}
+		case "query":
+			raw = r.URL.Query()[name]
+		case "header":
+			raw = r.Header.Values(name)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		if err := SetFromStrings(value.Field(i), raw); err != nil {
+			return fmt.Errorf("%s parameter %s: %v", in, name, err)
+		}
+	}
+	return nil
+}
+
+// ParamTag returns the location ("path", "query" or "header") and name of a
+// request parameter field, or an empty location for body fields
+func ParamTag(field reflect.StructField) (string, string) {
+	for _, in := range []string{"path", "query", "header"} {
+		if name, ok := field.Tag.Lookup(in); ok {
+			if name == "" {
+				name = field.Name
+			}
+			return in, name
+		}
+	}
+	return "", ""
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// SetFromStrings converts raw string values into field. Pointers are
+// allocated, slices receive every value and scalars the first one.
+func SetFromStrings(field reflect.Value, raw []string) error {
+	if field.Kind() == reflect.Ptr {
+		fresh := reflect.New(field.Type().Elem())
+		if err := SetFromStrings(fresh.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(fresh)
+		return nil
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(raw), len(raw))
+		for i, item := range raw {
+			if err := SetFromString(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	if len(raw) == 0 {
+		return nil
+	}
+	return SetFromString(field, raw[0])
+}
+
+// SetFromString converts a single raw value into a scalar field
+func SetFromString(field reflect.Value, raw string) error {
+	// time.Time is a TextUnmarshaler with a less helpful error
+	switch field.Type() {
+	case timeType:
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return fmt.Errorf("must be an RFC 3339 date-time")
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	case durationType:
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration")
+		}
+		field.SetInt(int64(parsed))
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		// []byte
+		field.SetBytes([]byte(raw))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Paging struct {
+	Page  int `query:"page"`
+	Limit *int
+}
+
+type listRequest struct {
+	Paging
+	ID      string        `path:"id"`
+	Tags    []string      `query:"tag"`
+	Since   *time.Time    `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Active  bool          `query:"active"`
+	Tenant  string        `header:"X-Tenant"`
+	Name    string        `json:"name"`
+	hidden  string        `query:"hidden"`
+}
+
+func TestBind(t *testing.T) {
+	since := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	limit := 5
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		expected    listRequest
+		err         string
+	}{
+		{
+			name:     "parameters",
+			target:   "/items/7?page=2&tag=a&tag=b&since=2026-10-19T12:00:00Z&timeout=2s&active=true&hidden=x",
+			expected: listRequest{Paging: Paging{Page: 2}, ID: "7", Tags: []string{"a", "b"}, Since: &since, Timeout: 2 * time.Second, Active: true, Tenant: "acme"},
+		},
+		{
+			name:        "body",
+			target:      "/items/7",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name": "first", "Limit": 5}`,
+			expected:    listRequest{Paging: Paging{Limit: &limit}, ID: "7", Tenant: "acme", Name: "first"},
+		},
+		{
+			name:     "parameters win over the body",
+			target:   "/items/7?page=3",
+			body:     `{"Page": 1, "ID": "8"}`,
+			expected: listRequest{Paging: Paging{Page: 3}, ID: "7", Tenant: "acme"},
+		},
+		{
+			name:   "integer",
+			target: "/items/7?page=two",
+			err:    "query parameter page: must be an integer",
+		},
+		{
+			name:   "date-time",
+			target: "/items/7?since=yesterday",
+			err:    "query parameter since: must be an RFC 3339 date-time",
+		},
+		{
+			name:   "duration",
+			target: "/items/7?timeout=soon",
+			err:    "query parameter timeout: must be a duration",
+		},
+		{
+			name:        "content type",
+			target:      "/items/7",
+			contentType: "text/plain",
+			body:        "name=first",
+			err:         `unsupported content type "text/plain"`,
+		},
+		{
+			name:   "invalid JSON",
+			target: "/items/7",
+			body:   `{"name":`,
+			err:    "invalid JSON body: unexpected end of JSON input",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(test.body))
+			r.SetPathValue("id", "7")
+			r.Header.Set("X-Tenant", "acme")
+			if test.contentType != "" {
+				r.Header.Set("Content-Type", test.contentType)
+			}
+
+			var req listRequest
+			err := Bind(r, &req)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(req, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, req)
+			}
+		})
+	}
+}
+
+func TestBindTarget(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	var value int
+	if err := Bind(r, &value); err == nil || !strings.Contains(err.Error(), "pointer to a struct") {
+		t.Errorf("expected an error for %T, got %v", &value, err)
+	}
+	if err := Bind(r, listRequest{}); err == nil {
+		t.Error("expected an error for a struct value")
+	}
+}
+
+func TestParamTag(t *testing.T) {
+	typ := reflect.TypeOf(struct {
+		ID    string `path:""`
+		Page  int    `query:"page"`
+		Token string `header:"X-Token"`
+		Name  string `json:"name"`
+	}{})
+	expected := [][2]string{{"path", "ID"}, {"query", "page"}, {"header", "X-Token"}, {"", ""}}
+	for i, want := range expected {
+		in, name := ParamTag(typ.Field(i))
+		if in != want[0] || name != want[1] {
+			t.Errorf("%s: expected %v, got %s %s", typ.Field(i).Name, want, in, name)
+		}
+	}
+}
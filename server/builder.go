@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"goserve/configuration"
+	"goserve/configuration/env"
 	"log"
 	"net/http"
 	"time"
@@ -18,6 +19,7 @@ type builder struct {
 	readTimeout  int
 	writeTimeout int
 	idleTimeout  int
+	profile      env.Profile
 }
 
 // Constructor
@@ -69,10 +71,22 @@ func (s *builder) PUT(path string, handler HandlerFunc) ServerBuilder {
 	return s.addRoute(CreatePUT(path, handler))
 }
 
+func (s *builder) PATCH(path string, handler HandlerFunc) ServerBuilder {
+	return s.addRoute(CreatePATCH(path, handler))
+}
+
 func (s *builder) DELETE(path string, handler HandlerFunc) ServerBuilder {
 	return s.addRoute(CreateDELETE(path, handler))
 }
 
+func (s *builder) GetRoutes() []RouteInfo {
+	return s.routes
+}
+
+func (s *builder) GetMiddlewares() []MiddlewareInfo {
+	return s.middlewares
+}
+
 func (s *builder) addRoute(route RouteInfo) ServerBuilder {
 	s.routes = append(s.routes, route)
 	return s
@@ -86,18 +100,49 @@ func (s *builder) AddGlobalMiddleware(name string, middleware MiddlewareFunc) Se
 	return s
 }
 
+func (s *builder) ReplaceGlobalMiddleware(name string, middleware MiddlewareFunc) ServerBuilder {
+	for i, mw := range s.middlewares {
+		if mw.Name == name {
+			s.middlewares[i].Middleware = middleware
+			return s
+		}
+	}
+	return s.AddGlobalMiddleware(name, middleware)
+}
+
+func (s *builder) RemoveGlobalMiddleware(name string) ServerBuilder {
+	kept := make([]MiddlewareInfo, 0, len(s.middlewares))
+	for _, mw := range s.middlewares {
+		if mw.Name != name {
+			kept = append(kept, mw)
+		}
+	}
+	s.middlewares = kept
+	return s
+}
+
 func (s *builder) WithLogging(logRequests, logResponses bool) ServerBuilder {
 	if logRequests || logResponses {
 		s.AddGlobalMiddleware("Logging", func(next http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Pretty logs are for humans, the others for log collectors
+				pretty := ProfileFromContext(r.Context()).PrettyLogs
 				if logRequests {
-					log.Printf("Request: %s %s", r.Method, r.URL.Path)
+					if pretty {
+						log.Printf("Request: %s %s", r.Method, r.URL.Path)
+					} else {
+						log.Printf("event=request method=%s path=%q", r.Method, r.URL.Path)
+					}
 				}
 
 				if logResponses {
 					lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 					next.ServeHTTP(lrw, r)
-					log.Printf("Response: %s %s - %d", r.Method, r.URL.Path, lrw.statusCode)
+					if pretty {
+						log.Printf("Response: %s %s - %d", r.Method, r.URL.Path, lrw.statusCode)
+					} else {
+						log.Printf("event=response method=%s path=%q status=%d", r.Method, r.URL.Path, lrw.statusCode)
+					}
 				} else {
 					next.ServeHTTP(w, r)
 				}
@@ -107,18 +152,17 @@ func (s *builder) WithLogging(logRequests, logResponses bool) ServerBuilder {
 	return s
 }
 
+// registerRoute mounts the route on a "METHOD path" pattern, so that
+// several methods can share a path; the mux answers 405 for the others, with
+// an Allow header. A GET pattern also matches HEAD requests, which run the
+// GET handler while the server discards the body, as the Allow header of the
+// mux advertises.
 func (s *builder) registerRoute(route RouteInfo) {
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != string(route.GetMethod()) {
-			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		route.GetHandler().handler(w, r)
-	})
+	handler := http.HandlerFunc(route.GetHandler().handler)
 
-	finalHandler := s.applyMiddlewares(handler, route)
+	finalHandler := withRoute(route, s.applyMiddlewares(handler, route))
 
-	s.mux.Handle(route.GetPath(), finalHandler)
+	s.mux.Handle(fmt.Sprintf("%s %s", route.GetMethod(), route.GetPath()), finalHandler)
 }
 
 func (s *builder) applyMiddlewares(handler http.Handler, route RouteInfo) http.Handler {
@@ -141,31 +185,38 @@ func (s *builder) Build() HttpServer {
 	if s.config != nil {
 		s.applyConfiguration()
 	}
-
-	mux := http.NewServeMux()
+	if err := checkEnforcement(s.routes, s.middlewares); err != nil {
+		panic(err)
+	}
 
 	for _, route := range s.routes {
 		s.registerRoute(route)
 	}
 
+	if s.profile.Pprof {
+		registerPprof(s.mux)
+		log.Printf("Profiling enabled under /debug/pprof/")
+	}
+
 	s.logServerConfig()
 
-	httpServer := &http.Server{
+	built := &Server{
+		mux:     s.mux,
+		config:  s.config,
+		profile: s.profile,
+	}
+	built.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.address, s.port),
-		Handler:      s.mux,
+		Handler:      withProfile(s.profile, built.applyTimeouts(s.mux)),
 		ReadTimeout:  time.Duration(s.readTimeout) * time.Second,
 		WriteTimeout: time.Duration(s.writeTimeout) * time.Second,
 		IdleTimeout:  time.Duration(s.idleTimeout) * time.Second,
 	}
-
-	return &Server{
-		server: httpServer,
-		mux:    mux,
-		config: s.config,
-	}
+	return built
 }
 
 func (s *builder) applyConfiguration() {
+	s.profile = s.config.GetProfile()
 	if s.config.GetPort() != 0 {
 		s.port = s.config.GetPort()
 	}
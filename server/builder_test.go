@@ -0,0 +1,93 @@
+package server_test
+
+import (
+	"goserve/goservetest"
+	"goserve/server"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	srv := goservetest.New(t, server.New().
+		GET("/items", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "items")
+		}).
+		POST("/items", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}).
+		DELETE("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	srv.GET("/items").Expect(t).Status(http.StatusOK).Body("items")
+	srv.POST("/items").Expect(t).Status(http.StatusCreated)
+	srv.DELETE("/items/1").Expect(t).Status(http.StatusNoContent)
+
+	tests := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{"PUT", "/items", "GET, HEAD, POST"},
+		{"PATCH", "/items", "GET, HEAD, POST"},
+		{"GET", "/items/1", "DELETE"},
+		{"HEAD", "/items/1", "DELETE"},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			srv.Request(test.method, test.path).Expect(t).
+				Status(http.StatusMethodNotAllowed).
+				Header("Allow", test.allow)
+		})
+	}
+
+	srv.GET("/missing").Expect(t).Status(http.StatusNotFound)
+}
+
+func TestHeadServesGETRoutes(t *testing.T) {
+	srv := goservetest.New(t, server.New().GET("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "items")
+	}))
+
+	resp, err := http.Head(srv.URL() + "/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain" || len(body) != 0 {
+		t.Errorf("expected the GET headers without a body, got %d %v %q", resp.StatusCode, resp.Header, body)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) server.MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	route := server.CreateGET("/items", func(w http.ResponseWriter, r *http.Request) {
+		current, ok := server.RouteFromContext(r.Context())
+		if !ok || current.GetPath() != "/items" {
+			t.Errorf("expected the route in the context, got %v", current)
+		}
+		calls = append(calls, "handler")
+	}).WithMiddleware(trace("route"))
+
+	srv := goservetest.New(t, server.New().
+		AddGlobalMiddleware("first", trace("first")).
+		AddGlobalMiddleware("second", trace("second")).
+		AddRoutes([]server.RouteInfo{route}))
+	srv.GET("/items").Expect(t).Status(http.StatusOK)
+
+	if got := strings.Join(calls, ","); got != "route,first,second,handler" {
+		t.Errorf("expected route middlewares to wrap the global ones, got %s", got)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MetaEnforcer is implemented by the handlers of middlewares enforcing
+// route meta, such as authentication, so Build can tell which ones are
+// registered
+type MetaEnforcer interface {
+	// EnforcesMeta tells whether the handler enforces the meta value on the
+	// routes declaring it
+	EnforcesMeta(key string, value interface{}) bool
+}
+
+// EnforcedHandler is a handler enforcing route meta, returned by the
+// middlewares implementing MetaEnforcer
+type EnforcedHandler struct {
+	http.Handler
+	Enforces func(key string, value interface{}) bool
+}
+
+func (h EnforcedHandler) EnforcesMeta(key string, value interface{}) bool {
+	return h.Enforces(key, value)
+}
+
+var enforced struct {
+	mu     sync.RWMutex
+	checks map[string]func(value interface{}) error
+}
+
+// RequireEnforcement registers a route meta that a middleware must enforce:
+// Build panics on a route declaring it when none of its middlewares does,
+// instead of serving the route unprotected, and when check rejects its
+// value. Packages defining such meta register it in their init function.
+func RequireEnforcement(key string, check func(value interface{}) error) {
+	enforced.mu.Lock()
+	defer enforced.mu.Unlock()
+	if enforced.checks == nil {
+		enforced.checks = make(map[string]func(value interface{}) error)
+	}
+	enforced.checks[key] = check
+}
+
+// checkEnforcement returns the problems of the routes declaring meta that is
+// invalid or that none of their middlewares enforces
+func checkEnforcement(routes []RouteInfo, middlewares []MiddlewareInfo) error {
+	enforced.mu.RLock()
+	defer enforced.mu.RUnlock()
+	if len(enforced.checks) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(enforced.checks))
+	for key := range enforced.checks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	global := enforcers(nil, middlewares...)
+	var problems []string
+	for _, route := range routes {
+		var local []MetaEnforcer
+		for _, middleware := range route.GetHandler().GetMiddlewares() {
+			local = enforcers(local, MiddlewareInfo{Middleware: middleware})
+		}
+		for _, key := range keys {
+			value, ok := route.GetMeta(key)
+			if !ok {
+				continue
+			}
+			if check := enforced.checks[key]; check != nil {
+				if err := check(value); err != nil {
+					problems = append(problems, fmt.Sprintf("%s %s: meta %s: %v", route.GetMethod(), route.GetPath(), key, err))
+					continue
+				}
+			}
+			if !enforcedBy(key, value, global) && !enforcedBy(key, value, local) {
+				problems = append(problems, fmt.Sprintf("%s %s: no middleware enforces meta %s=%v", route.GetMethod(), route.GetPath(), key, value))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("routes would be served unprotected:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// enforcers appends the middlewares whose handlers implement MetaEnforcer,
+// found by wrapping an empty handler
+func enforcers(list []MetaEnforcer, middlewares ...MiddlewareInfo) []MetaEnforcer {
+	for _, middleware := range middlewares {
+		if enforcer, ok := middleware.Middleware(http.NotFoundHandler()).(MetaEnforcer); ok {
+			list = append(list, enforcer)
+		}
+	}
+	return list
+}
+
+func enforcedBy(key string, value interface{}, list []MetaEnforcer) bool {
+	for _, enforcer := range list {
+		if enforcer.EnforcesMeta(key, value) {
+			return true
+		}
+	}
+	return false
+}
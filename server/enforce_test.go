@@ -0,0 +1,98 @@
+package server_test
+
+import (
+	"errors"
+	"goserve/server"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const metaGuarded = "test.guarded"
+
+func init() {
+	server.RequireEnforcement(metaGuarded, func(value interface{}) error {
+		if value == "" {
+			return errors.New("must not be empty")
+		}
+		return nil
+	})
+}
+
+// guard enforces metaGuarded on the given value
+func guard(value string) server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return server.EnforcedHandler{
+			Handler: next,
+			Enforces: func(key string, v interface{}) bool {
+				return key == metaGuarded && v == value
+			},
+		}
+	}
+}
+
+func buildPanic(builder server.ServerBuilder) (message string) {
+	defer func() {
+		if r := recover(); r != nil {
+			message = r.(error).Error()
+		}
+	}()
+	builder.Build()
+	return ""
+}
+
+func TestEnforcement(t *testing.T) {
+	guarded := func(value string) server.RouteInfo {
+		return server.CreateGET("/guarded", func(http.ResponseWriter, *http.Request) {}).WithMeta(metaGuarded, value)
+	}
+
+	tests := []struct {
+		name    string
+		builder server.ServerBuilder
+		panic   string
+	}{
+		{
+			name:    "global middleware",
+			builder: server.New().AddGlobalMiddleware("guard", guard("admin")).AddRoutes([]server.RouteInfo{guarded("admin")}),
+		},
+		{
+			name:    "route middleware",
+			builder: server.New().AddRoutes([]server.RouteInfo{guarded("admin").WithMiddleware(guard("admin"))}),
+		},
+		{
+			name:    "undeclared meta",
+			builder: server.New().GET("/open", func(http.ResponseWriter, *http.Request) {}),
+		},
+		{
+			name:    "no middleware",
+			builder: server.New().AddRoutes([]server.RouteInfo{guarded("admin")}),
+			panic:   "GET /guarded: no middleware enforces meta test.guarded=admin",
+		},
+		{
+			name:    "other value",
+			builder: server.New().AddGlobalMiddleware("guard", guard("user")).AddRoutes([]server.RouteInfo{guarded("admin")}),
+			panic:   "GET /guarded: no middleware enforces meta test.guarded=admin",
+		},
+		{
+			name:    "removed middleware",
+			builder: server.New().AddGlobalMiddleware("guard", guard("admin")).RemoveGlobalMiddleware("guard").AddRoutes([]server.RouteInfo{guarded("admin")}),
+			panic:   "no middleware enforces meta test.guarded=admin",
+		},
+		{
+			name:    "rejected value",
+			builder: server.New().AddGlobalMiddleware("guard", guard("")).AddRoutes([]server.RouteInfo{guarded("")}),
+			panic:   "GET /guarded: meta test.guarded: must not be empty",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := buildPanic(test.builder)
+			switch {
+			case test.panic == "" && message != "":
+				t.Errorf("unexpected panic %q", message)
+			case test.panic != "" && !strings.Contains(message, test.panic):
+				t.Errorf("expected %q in %q", test.panic, message)
+			}
+		})
+	}
+}
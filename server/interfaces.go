@@ -13,6 +13,8 @@ type RouteInfo interface {
 	GetPath() string
 	GetMethod() Http_Method
 	GetHandler() *RouteHandler
+	GetTags() []string
+	GetMeta(key string) (interface{}, bool)
 }
 
 type ServerBuilder interface {
@@ -23,6 +25,11 @@ type ServerBuilder interface {
 	SetPort(port int) ServerBuilder
 
 	AddGlobalMiddleware(name string, middleware MiddlewareFunc) ServerBuilder
+	// Replace the global middleware registered under name, keeping its position
+	// (added last if there is none)
+	ReplaceGlobalMiddleware(name string, middleware MiddlewareFunc) ServerBuilder
+	// Remove the global middleware registered under name
+	RemoveGlobalMiddleware(name string) ServerBuilder
 	WithLogging(logRequests, logResponses bool) ServerBuilder
 
 	// Add a single route to the server
@@ -35,10 +42,19 @@ type ServerBuilder interface {
 	POST(path string, handler HandlerFunc) ServerBuilder
 	// Create and add a PUT route to the server
 	PUT(path string, handler HandlerFunc) ServerBuilder
+	// Create and add a PATCH route to the server
+	PATCH(path string, handler HandlerFunc) ServerBuilder
 	// Create and add a DELETE route to the server
 	DELETE(path string, handler HandlerFunc) ServerBuilder
 
-	// Build and return the configured http server
+	// Get the routes added so far
+	GetRoutes() []RouteInfo
+	// Get the global middlewares, in the order they wrap the routes
+	GetMiddlewares() []MiddlewareInfo
+
+	// Build and return the configured http server. Like the mux on
+	// conflicting routes, it panics on routes declaring meta that no
+	// middleware enforces, see RequireEnforcement.
 	Build() HttpServer
 }
 
@@ -48,6 +64,9 @@ type HttpServer interface {
 	// Get the underlying http.ServeMux instance
 	GetMux() *http.ServeMux
 
+	// Apply a reloaded configuration to the running server
+	Reconfigure(config configuration.Configuration)
+
 	// Start the HTTP server
 	Start() error
 }
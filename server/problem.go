@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error document
+type Problem struct {
+	Type     string                 `json:"type,omitempty"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail,omitempty"`
+	Instance string                 `json:"instance,omitempty"`
+	Extra    map[string]interface{} `json:"-"`
+}
+
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+// With adds an extension member to the problem document
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extra == nil {
+		p.Extra = make(map[string]interface{})
+	}
+	p.Extra[key] = value
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(p.Extra)+5)
+	for key, value := range p.Extra {
+		fields[key] = value
+	}
+	if p.Type != "" {
+		fields["type"] = p.Type
+	}
+	fields["title"] = p.Title
+	fields["status"] = p.Status
+	if p.Detail != "" {
+		fields["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		fields["instance"] = p.Instance
+	}
+	return json.Marshal(fields)
+}
+
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type plain Problem
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(fields, key)
+	}
+	if len(fields) > 0 {
+		decoded.Extra = fields
+	}
+
+	*p = Problem(decoded)
+	return nil
+}
+
+// WriteProblem renders the problem as application/problem+json
+func WriteProblem(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// WriteError renders err as a problem document. Errors that are not a
+// *Problem are reported as a 500 without exposing their message.
+func WriteError(w http.ResponseWriter, err error) {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		WriteProblem(w, problem)
+		return
+	}
+	WriteProblem(w, NewProblem(http.StatusInternalServerError, ""))
+}
+
+// writeError is WriteError exposing the message of unexpected errors when
+// the environment profile asks for verbose errors
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var problem *Problem
+	if !errors.As(err, &problem) && ProfileFromContext(r.Context()).VerboseErrors {
+		WriteProblem(w, NewProblem(http.StatusInternalServerError, err.Error()))
+		return
+	}
+	WriteError(w, err)
+}
@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goserve/configuration"
+	"goserve/configuration/env"
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemJSON(t *testing.T) {
+	problem := server.NewProblem(http.StatusConflict, "the name is taken").With("field", "name")
+	problem.Instance = "/items/1"
+
+	data, err := json.Marshal(problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"detail":"the name is taken","field":"name","instance":"/items/1","status":409,"title":"Conflict","type":"about:blank"}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	var decoded server.Problem
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Status != 409 || decoded.Detail != "the name is taken" || decoded.Extra["field"] != "name" || len(decoded.Extra) != 1 {
+		t.Errorf("unexpected problem %+v", decoded)
+	}
+
+	// Extensions cannot override the standard members
+	data, _ = json.Marshal(server.NewProblem(http.StatusBadRequest, "").With("status", 200))
+	if string(data) != `{"status":400,"title":"Bad Request","type":"about:blank"}` {
+		t.Errorf("unexpected document %s", data)
+	}
+
+	if problem.Error() != "Conflict: the name is taken" || server.NewProblem(http.StatusNotFound, "").Error() != "Not Found" {
+		t.Errorf("unexpected messages %q", problem.Error())
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{"problem", server.NewProblem(http.StatusNotFound, "no such item"), http.StatusNotFound, "no such item"},
+		{"wrapped problem", fmt.Errorf("loading: %w", server.NewProblem(http.StatusForbidden, "not yours")), http.StatusForbidden, "not yours"},
+		{"other error", errors.New("connection refused"), http.StatusInternalServerError, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.WriteError(w, test.err)
+			if w.Code != test.status || w.Header().Get("Content-Type") != server.ProblemContentType {
+				t.Errorf("expected a %d problem, got %d %q", test.status, w.Code, w.Header().Get("Content-Type"))
+			}
+			var problem server.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Detail != test.detail {
+				t.Errorf("expected %q, got %q", test.detail, problem.Detail)
+			}
+		})
+	}
+}
+
+func TestVerboseErrors(t *testing.T) {
+	failing := server.CreateTyped(server.GET, "/fail", func(ctx context.Context, req struct{}) (server.NoContent, error) {
+		return server.NoContent{}, errors.New("connection refused")
+	})
+
+	tests := []struct {
+		environment env.Environment
+		detail      string
+	}{
+		{env.Development, "connection refused"},
+		{env.Production, ""},
+	}
+	for _, test := range tests {
+		t.Run(string(test.environment), func(t *testing.T) {
+			srv := goservetest.New(t, server.New().AddRoutes([]server.RouteInfo{failing}),
+				goservetest.WithConfig(func(c *configuration.Config) { c.Server.Environment = test.environment }))
+			problem := srv.GET("/fail").Expect(t).Status(http.StatusInternalServerError).Problem()
+			if problem.Detail != test.detail {
+				t.Errorf("expected %q, got %q", test.detail, problem.Detail)
+			}
+		})
+	}
+}
@@ -1,18 +1,30 @@
 package server
 
 import (
+	"context"
 	"net/http"
 )
 
 type Http_Method string
 
+// GET routes also answer HEAD requests without a body. OPTIONS is only
+// registered for routes declaring it, such as the operations of an OpenAPI
+// document.
 const (
-	GET    Http_Method = "GET"
-	POST   Http_Method = "POST"
-	PUT    Http_Method = "PUT"
-	DELETE Http_Method = "DELETE"
+	GET     Http_Method = "GET"
+	POST    Http_Method = "POST"
+	PUT     Http_Method = "PUT"
+	PATCH   Http_Method = "PATCH"
+	DELETE  Http_Method = "DELETE"
+	HEAD    Http_Method = "HEAD"
+	OPTIONS Http_Method = "OPTIONS"
 )
 
+// MetaCSRF is the route meta exempting a route from the CSRF checks of
+// package csrf when false, declared here so that packages adding such
+// routes, e.g. report endpoints, need not import it
+const MetaCSRF = "csrf"
+
 type Route struct {
 	method      Http_Method
 	path        string
@@ -55,6 +67,10 @@ func CreatePUT(path string, handler HandlerFunc) RouteInfo {
 	return CreateRoute(PUT, path, handler)
 }
 
+func CreatePATCH(path string, handler HandlerFunc) RouteInfo {
+	return CreateRoute(PATCH, path, handler)
+}
+
 func CreateDELETE(path string, handler HandlerFunc) RouteInfo {
 	return CreateRoute(DELETE, path, handler)
 }
@@ -71,6 +87,20 @@ func (r *Route) GetHandler() *RouteHandler {
 	return &r.handler
 }
 
+// GetMiddlewares returns the middlewares of the route, outermost first
+func (h *RouteHandler) GetMiddlewares() []MiddlewareFunc {
+	return h.middlewares
+}
+
+func (r *Route) GetTags() []string {
+	return r.tags
+}
+
+func (r *Route) GetMeta(key string) (interface{}, bool) {
+	value, ok := r.handler.meta[key]
+	return value, ok
+}
+
 // WithMeta implements RouteInfo.
 func (r *Route) WithMeta(key string, value interface{}) RouteInfo {
 	if r.handler.meta == nil {
@@ -90,3 +120,18 @@ func (r *Route) WithMiddleware(middlewares ...MiddlewareFunc) RouteInfo {
 	r.handler.middlewares = append(r.handler.middlewares, middlewares...)
 	return r
 }
+
+type routeKey struct{}
+
+// RouteFromContext returns the route matched by the request, so that global
+// middlewares can read its tags and meta
+func RouteFromContext(ctx context.Context) (RouteInfo, bool) {
+	route, ok := ctx.Value(routeKey{}).(RouteInfo)
+	return route, ok
+}
+
+func withRoute(route RouteInfo, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+	})
+}
@@ -2,12 +2,16 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"goserve/configuration"
+	"goserve/configuration/env"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -15,7 +19,16 @@ import (
 type Server struct {
 	server *http.Server
 	mux    *http.ServeMux
-	config configuration.Configuration
+
+	mu      sync.Mutex
+	config  configuration.Configuration
+	profile env.Profile
+
+	// Timeouts set per request once Reconfigure changed them, since the
+	// http.Server fields cannot be updated while it is serving
+	reconfigured atomic.Bool
+	readTimeout  atomic.Int64
+	writeTimeout atomic.Int64
 }
 
 type loggingResponseWriter struct {
@@ -23,6 +36,11 @@ type loggingResponseWriter struct {
 	statusCode int
 }
 
+func (lrw *loggingResponseWriter) WriteHeader(statusCode int) {
+	lrw.statusCode = statusCode
+	lrw.ResponseWriter.WriteHeader(statusCode)
+}
+
 func (s *Server) GetHttpServer() *http.Server {
 	return s.server
 }
@@ -31,6 +49,68 @@ func (s *Server) GetMux() *http.ServeMux {
 	return s.mux
 }
 
+// Reconfigure applies a reloaded configuration: read and write timeouts are
+// used from the next request, the other server settings are logged as
+// needing a restart. The server has no log level nor rate limit settings;
+// applications apply their own with ConfigurationBuilder.OnChange, and API
+// key rate limits are read from the key store on every request. Typically
+// wired to the configuration loader:
+//
+//	loader.Watch(func(_, config configuration.Configuration) { srv.Reconfigure(config) })
+func (s *Server) Reconfigure(config configuration.Configuration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.config
+	s.config = config
+	if old == nil || config == nil {
+		return
+	}
+
+	if old.GetReadTimeout() != config.GetReadTimeout() || old.GetWriteTimeout() != config.GetWriteTimeout() {
+		s.readTimeout.Store(int64(time.Duration(config.GetReadTimeout()) * time.Second))
+		s.writeTimeout.Store(int64(time.Duration(config.GetWriteTimeout()) * time.Second))
+		s.reconfigured.Store(true)
+		log.Printf("Timeouts updated: read %ds, write %ds", config.GetReadTimeout(), config.GetWriteTimeout())
+	}
+
+	restart := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"server.host", old.GetAddress(), config.GetAddress()},
+		{"server.port", old.GetPort(), config.GetPort()},
+		{"server.environment", old.GetEnvironment(), config.GetEnvironment()},
+		{"server.idle-timeout", old.GetIdleTimeout(), config.GetIdleTimeout()},
+	}
+	for _, setting := range restart {
+		if setting.old != setting.new {
+			log.Printf("%s changed from %v to %v, restart the server to apply it", setting.name, setting.old, setting.new)
+		}
+	}
+}
+
+// applyTimeouts sets the reconfigured deadlines on the connection before
+// handling the request
+func (s *Server) applyTimeouts(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.reconfigured.Load() {
+			controller := http.NewResponseController(w)
+			now := time.Now()
+			controller.SetReadDeadline(deadline(now, s.readTimeout.Load()))
+			controller.SetWriteDeadline(deadline(now, s.writeTimeout.Load()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deadline returns the zero time, meaning no deadline, for a zero timeout
+func deadline(now time.Time, timeout int64) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(timeout))
+}
+
 func (s *Server) Start() error {
 	if s.server == nil {
 		return fmt.Errorf("server not built, call Build() before Start()")
@@ -39,9 +119,24 @@ func (s *Server) Start() error {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	if s.profile.DevTLS {
+		certificate, err := devCertificate()
+		if err != nil {
+			return fmt.Errorf("cannot generate the development certificate: %v", err)
+		}
+		s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	}
+
 	go func() {
-		log.Printf("Start listening on %v:%d", s.config.GetAddress(), s.config.GetPort())
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.server.TLSConfig != nil {
+			log.Printf("Start listening on %v with TLS", s.server.Addr)
+			err = s.server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Start listening on %v", s.server.Addr)
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Could not listen on port %v: %v", s.server.Addr, err)
 		}
 	}()
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+// TypedHandler receives a bound request and returns the value to encode as
+// the JSON response. Returning a *Problem controls the error response.
+type TypedHandler[Req any, Res any] func(ctx context.Context, req Req) (Res, error)
+
+// NoContent as a response type makes a typed route answer 204
+type NoContent struct{}
+
+// RouteTypes describes the request and response types of a typed route
+type RouteTypes struct {
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+const routeTypesKey = "goserve.types"
+
+// CreateTyped creates a route whose request is bound with Bind and whose
+// response is encoded as JSON. The types are recorded on the route so that
+// clients and documentation can be generated from the route table.
+func CreateTyped[Req any, Res any](method Http_Method, path string, handler TypedHandler[Req, Res]) RouteInfo {
+	types := RouteTypes{
+		Request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		Response: reflect.TypeOf((*Res)(nil)).Elem(),
+	}
+
+	return CreateRoute(method, path, func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if types.Request.Kind() == reflect.Struct {
+			if err := Bind(r, &req); err != nil {
+				WriteProblem(w, NewProblem(http.StatusBadRequest, err.Error()))
+				return
+			}
+		}
+
+		res, err := handler(r.Context(), req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if types.Response == reflect.TypeOf(NoContent{}) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(res)
+	}).WithMeta(routeTypesKey, types)
+}
+
+// GetRouteTypes returns the types of a route created with CreateTyped
+func GetRouteTypes(route RouteInfo) (RouteTypes, bool) {
+	value, ok := route.GetMeta(routeTypesKey)
+	if !ok {
+		return RouteTypes{}, false
+	}
+	types, ok := value.(RouteTypes)
+	return types, ok
+}
@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"context"
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type getItem struct {
+	ID      int  `path:"id"`
+	Verbose bool `query:"verbose"`
+}
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedRoutes(t *testing.T) {
+	get := server.CreateTyped(server.GET, "/items/{id}", func(ctx context.Context, req getItem) (item, error) {
+		if req.ID != 1 {
+			return item{}, server.NewProblem(http.StatusNotFound, "no such item")
+		}
+		if _, ok := server.RouteFromContext(ctx); !ok {
+			t.Error("expected the request context")
+		}
+		return item{ID: req.ID, Name: "first"}, nil
+	})
+	remove := server.CreateTyped(server.DELETE, "/items/{id}", func(ctx context.Context, req getItem) (server.NoContent, error) {
+		return server.NoContent{}, nil
+	})
+	names := server.CreateTyped(server.GET, "/names", func(ctx context.Context, req string) ([]string, error) {
+		return []string{"first"}, nil
+	})
+
+	types, ok := server.GetRouteTypes(get)
+	if !ok || types.Request != reflect.TypeOf(getItem{}) || types.Response != reflect.TypeOf(item{}) {
+		t.Errorf("unexpected route types %+v", types)
+	}
+	if _, ok := server.GetRouteTypes(server.CreateGET("/", nil)); ok {
+		t.Error("expected no types on an untyped route")
+	}
+
+	srv := goservetest.New(t, server.New().AddRoutes([]server.RouteInfo{get, remove, names}))
+
+	var got item
+	srv.GET("/items/1").Expect(t).Status(http.StatusOK).Header("Content-Type", "application/json").JSON(&got)
+	if got != (item{ID: 1, Name: "first"}) {
+		t.Errorf("unexpected item %+v", got)
+	}
+	problem := srv.GET("/items/2").Expect(t).Status(http.StatusNotFound).Problem()
+	if problem.Detail != "no such item" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	problem = srv.GET("/items/one").Expect(t).Status(http.StatusBadRequest).Problem()
+	if problem.Detail != "path parameter id: must be an integer" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	srv.DELETE("/items/1").Expect(t).Status(http.StatusNoContent).Body("")
+	// Requests that are not structs are not bound
+	srv.GET("/names").Expect(t).Status(http.StatusOK).Body(`["first"]` + "\n")
+}
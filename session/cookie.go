@@ -0,0 +1,112 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxCookieSize is the size browsers accept for a cookie, name included
+const maxCookieSize = 4096
+
+// CookieStore keeps the whole session in the cookie, encrypted and
+// authenticated with AES-GCM. The first key encrypts; every key decrypts,
+// so a new key is rotated in by putting it first and the old one is
+// removed once the sessions it sealed have expired.
+//
+// The store keeps no state, so it cannot revoke a cookie: after Destroy or
+// RenewID, a copy of the previous cookie stays valid until it expires,
+// at most the absolute timeout. Logging out only clears the cookie of the
+// browser. Use a server side store (MemoryStore or any Store deleting the
+// session) when a stolen cookie must stop working on logout.
+type CookieStore struct {
+	name  string
+	aeads []cipher.AEAD
+}
+
+// NewCookieStore creates a store for the cookie name with AES keys of 16,
+// 24 or 32 bytes
+func NewCookieStore(name string, keys ...[]byte) (*CookieStore, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("session: cookie store needs a key")
+	}
+	store := &CookieStore{name: name}
+	for i, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %d: %v", i+1, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		store.aeads = append(store.aeads, aead)
+	}
+	return store, nil
+}
+
+func (s *CookieStore) Load(ctx context.Context, token string) (*Data, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	for _, aead := range s.aeads {
+		if len(sealed) < aead.NonceSize() {
+			return nil, ErrNotFound
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		// The cookie name is authenticated so that a value cannot be moved
+		// to another cookie sealed with the same key
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(s.name))
+		if err != nil {
+			continue
+		}
+		var stored struct {
+			Data
+			Expires time.Time `json:"expires"`
+		}
+		if err := json.Unmarshal(plaintext, &stored); err != nil {
+			return nil, ErrNotFound
+		}
+		if !time.Now().Before(stored.Expires) {
+			return nil, ErrNotFound
+		}
+		return &stored.Data, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Save seals data with its expiry, which is checked again on Load since
+// the browser may keep the cookie longer
+func (s *CookieStore) Save(ctx context.Context, data *Data, expires time.Time) (string, error) {
+	plaintext, err := json.Marshal(struct {
+		*Data
+		Expires time.Time `json:"expires"`
+	}{data, expires})
+	if err != nil {
+		return "", err
+	}
+
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, []byte(s.name)))
+	if len(s.name)+len(token)+1 > maxCookieSize {
+		return "", fmt.Errorf("session: %d bytes do not fit in a cookie, use a server side store", len(token))
+	}
+	return token, nil
+}
+
+// Delete has nothing to remove: the cookie is cleared by the manager, but
+// copies of it stay valid until they expire
+func (s *CookieStore) Delete(ctx context.Context, token string) error {
+	return nil
+}
@@ -0,0 +1,243 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"goserve/configuration"
+	"goserve/server"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config configures the session cookie and timeouts, usually bound from
+// the configuration:
+//
+//	builder.Section("app.session", &sessionConfig)
+type Config struct {
+	CookieName string `json:"cookie-name" default:"session" description:"name of the session cookie"`
+	// Lifetime of a session without requests
+	IdleTimeout time.Duration `json:"idle-timeout" default:"30m" description:"lifetime of a session without requests"`
+	// Lifetime of a session however active, the user logs in again after it
+	AbsoluteTimeout time.Duration `json:"absolute-timeout" default:"12h" description:"maximum lifetime of a session"`
+	// Sends the cookie over plain HTTP too, for local development
+	Insecure bool   `json:"insecure" description:"send the cookie over plain HTTP too"`
+	SameSite string `json:"same-site" default:"lax" description:"SameSite attribute of the cookie: lax, strict or none"`
+	Path     string `json:"path" default:"/" description:"path of the cookie"`
+	Domain   string `json:"domain" description:"domain of the cookie, the host when empty"`
+	// Base64 AES keys of the cookie store, the first one encrypts
+	Keys []configuration.Secret `json:"keys" env:"SESSION_KEYS" secret:"true" description:"base64 AES keys of the cookie store, the first one encrypts"`
+}
+
+// Manager loads the session of each request from a store and saves it
+type Manager struct {
+	store    Store
+	config   Config
+	sameSite http.SameSite
+	now      func() time.Time
+}
+
+// NewManager creates a manager saving the sessions in store, or in
+// encrypted cookies with config.Keys when store is nil
+func NewManager(store Store, config Config) (*Manager, error) {
+	if config.CookieName == "" {
+		config.CookieName = "session"
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30 * time.Minute
+	}
+	if config.AbsoluteTimeout <= 0 {
+		config.AbsoluteTimeout = 12 * time.Hour
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+
+	m := &Manager{store: store, config: config, now: time.Now}
+	switch strings.ToLower(config.SameSite) {
+	case "", "lax":
+		m.sameSite = http.SameSiteLaxMode
+	case "strict":
+		m.sameSite = http.SameSiteStrictMode
+	case "none":
+		m.sameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("session: invalid same-site %q, expected lax, strict or none", config.SameSite)
+	}
+
+	if m.store == nil {
+		if len(config.Keys) == 0 {
+			return nil, errors.New("session: keys are required to store the sessions in cookies")
+		}
+		keys := make([][]byte, 0, len(config.Keys))
+		for i, key := range config.Keys {
+			decoded, err := base64.StdEncoding.DecodeString(key.Reveal())
+			if err != nil {
+				return nil, fmt.Errorf("session: key %d is not base64: %v", i+1, err)
+			}
+			keys = append(keys, decoded)
+		}
+		cookies, err := NewCookieStore(config.CookieName, keys...)
+		if err != nil {
+			return nil, err
+		}
+		m.store = cookies
+	}
+	return m, nil
+}
+
+// Middleware makes the session available to handlers with FromContext.
+// Sessions are only saved once a handler changes them, so visitors who
+// never use one do not get a cookie.
+func (m *Manager) Middleware() server.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := m.load(r)
+			writer := &sessionWriter{ResponseWriter: w, request: r, manager: m, session: session}
+			next.ServeHTTP(writer, r.WithContext(withSession(r.Context(), session)))
+			writer.commit()
+		})
+	}
+}
+
+// load returns the session of the cookie, or a new one when it is missing,
+// invalid or expired
+func (m *Manager) load(r *http.Request) *Session {
+	now := m.now()
+	cookie, err := r.Cookie(m.config.CookieName)
+	if err != nil || cookie.Value == "" {
+		return newSession(now)
+	}
+
+	data, err := m.store.Load(r.Context(), cookie.Value)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Loading the session: %v", err)
+		}
+		return expired(cookie.Value, now)
+	}
+	if !now.Before(m.expires(data)) {
+		m.store.Delete(r.Context(), cookie.Value)
+		return expired(cookie.Value, now)
+	}
+
+	session := &Session{data: data, token: cookie.Value}
+	// Refreshed at most once a minute, which is enough for the idle timeout
+	// without writing the store on every request
+	if now.Sub(data.LastSeen) >= min(time.Minute, m.config.IdleTimeout/10) {
+		data.LastSeen = now
+		session.modified = true
+	}
+	return session
+}
+
+// expired returns a new session which replaces the cookie of an expired
+// one, even if it stays unchanged
+func expired(token string, now time.Time) *Session {
+	session := newSession(now)
+	session.token = token
+	return session
+}
+
+// expires is the end of the session: after the idle timeout, at most the
+// absolute timeout after its creation
+func (m *Manager) expires(data *Data) time.Time {
+	idle := data.LastSeen.Add(m.config.IdleTimeout)
+	absolute := data.CreatedAt.Add(m.config.AbsoluteTimeout)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+// save writes the changes of the session to the store and the cookie
+func (m *Manager) save(w http.ResponseWriter, r *http.Request, session *Session) {
+	if session.destroyed {
+		if session.token != "" {
+			if err := m.store.Delete(r.Context(), session.token); err != nil {
+				log.Printf("Deleting the session: %v", err)
+			}
+		}
+		http.SetCookie(w, m.cookie("", time.Unix(0, 0)))
+		return
+	}
+	if !session.modified {
+		if session.isNew && session.token != "" {
+			// Stale cookie of an expired session
+			http.SetCookie(w, m.cookie("", time.Unix(0, 0)))
+		}
+		return
+	}
+
+	if session.renewed && session.token != "" {
+		if err := m.store.Delete(r.Context(), session.token); err != nil {
+			log.Printf("Deleting the previous session: %v", err)
+		}
+	}
+	expires := m.expires(session.data)
+	token, err := m.store.Save(r.Context(), session.data, expires)
+	if err != nil {
+		log.Printf("Saving the session: %v", err)
+		return
+	}
+	// Sent again with an unchanged token too, its expiry follows the idle
+	// timeout
+	http.SetCookie(w, m.cookie(token, expires))
+}
+
+func (m *Manager) cookie(value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    value,
+		Path:     m.config.Path,
+		Domain:   m.config.Domain,
+		Expires:  expires,
+		Secure:   !m.config.Insecure,
+		HttpOnly: true,
+		SameSite: m.sameSite,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// sessionWriter saves the session before the headers are written, the last
+// moment a cookie can be set
+type sessionWriter struct {
+	http.ResponseWriter
+	request   *http.Request
+	manager   *Manager
+	session   *Session
+	committed bool
+}
+
+func (w *sessionWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	w.manager.save(w.ResponseWriter, w.request, w.session)
+}
+
+func (w *sessionWriter) WriteHeader(statusCode int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	w.commit()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,146 @@
+// Package session keeps per-visitor state between requests of server
+// rendered pages:
+//
+//	manager, err := session.NewManager(session.NewMemoryStore(), config)
+//	builder.AddGlobalMiddleware("session", manager.Middleware())
+//
+//	func login(w http.ResponseWriter, r *http.Request) {
+//		s := session.FromContext(r.Context())
+//		s.RenewID()
+//		s.Set("user", userID)
+//		s.AddFlash("Welcome back")
+//		http.Redirect(w, r, "/", http.StatusSeeOther)
+//	}
+//
+// The state lives in an encrypted cookie (CookieStore) or server side, the
+// cookie holding the session ID only (MemoryStore or any Store). Only server
+// side stores revoke a session on logout, see CookieStore. Values are JSON
+// encoded: numbers come back as float64.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// Data is the state saved by the stores
+type Data struct {
+	ID        string                 `json:"id"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+	Flashes   []string               `json:"flashes,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+	LastSeen  time.Time              `json:"lastSeen"`
+}
+
+// Session is the session of a request, read and changed by handlers. The
+// changes are saved before the response headers are written.
+type Session struct {
+	data *Data
+	// Token the session was loaded from, empty for new sessions
+	token     string
+	isNew     bool
+	modified  bool
+	renewed   bool
+	destroyed bool
+}
+
+func newID() string {
+	random := make([]byte, 32)
+	rand.Read(random)
+	return base64.RawURLEncoding.EncodeToString(random)
+}
+
+func newSession(now time.Time) *Session {
+	return &Session{
+		data:  &Data{ID: newID(), Values: make(map[string]interface{}), CreatedAt: now, LastSeen: now},
+		isNew: true,
+	}
+}
+
+// ID identifies the session, changed by RenewID
+func (s *Session) ID() string {
+	return s.data.ID
+}
+
+// IsNew tells whether the session was created by this request
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+func (s *Session) CreatedAt() time.Time {
+	return s.data.CreatedAt
+}
+
+func (s *Session) Get(key string) (interface{}, bool) {
+	value, ok := s.data.Values[key]
+	return value, ok
+}
+
+// GetString returns a string value, empty when missing or of another type
+func (s *Session) GetString(key string) string {
+	value, _ := s.data.Values[key].(string)
+	return value
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	if s.data.Values == nil {
+		s.data.Values = make(map[string]interface{})
+	}
+	s.data.Values[key] = value
+	s.modified = true
+}
+
+func (s *Session) Delete(key string) {
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.modified = true
+	}
+}
+
+// AddFlash keeps a message for the next page displaying the flashes
+func (s *Session) AddFlash(message string) {
+	s.data.Flashes = append(s.data.Flashes, message)
+	s.modified = true
+}
+
+// Flashes returns the pending messages and removes them
+func (s *Session) Flashes() []string {
+	flashes := s.data.Flashes
+	if len(flashes) > 0 {
+		s.data.Flashes = nil
+		s.modified = true
+	}
+	return flashes
+}
+
+// RenewID gives the session a new ID, keeping its values. Call it when the
+// privileges change, e.g. on login, so that an ID known before cannot be
+// used to hijack the session. With CookieStore, the previous cookie stays
+// valid until it expires.
+func (s *Session) RenewID() {
+	s.data.ID = newID()
+	s.renewed = true
+	s.modified = true
+}
+
+// Destroy removes the session from the store and the cookie from the
+// browser, e.g. on logout. CookieStore has nothing to remove: a copy of the
+// cookie stays valid until it expires.
+func (s *Session) Destroy() {
+	s.destroyed = true
+}
+
+type sessionKey struct{}
+
+// FromContext returns the session of the request, nil without the session
+// middleware
+func FromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionKey{}).(*Session)
+	return session
+}
+
+func withSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
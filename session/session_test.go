@@ -0,0 +1,178 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"goserve/configuration"
+	"goserve/goservetest"
+	"goserve/server"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestCookieStore(t *testing.T) {
+	store, err := NewCookieStore("session", oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	data := &Data{ID: "id", Values: map[string]interface{}{"user": "alice"}, Flashes: []string{"hello"}}
+	token, err := store.Save(ctx, data, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(token, "alice") {
+		t.Fatal("the cookie is not encrypted")
+	}
+
+	loaded, err := store.Load(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != "id" || loaded.Values["user"] != "alice" || len(loaded.Flashes) != 1 {
+		t.Errorf("unexpected data %+v", loaded)
+	}
+
+	sealed, _ := base64.RawURLEncoding.DecodeString(token)
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	other, _ := NewCookieStore("other", oldKey)
+	rotated, _ := NewCookieStore("session", newKey, oldKey)
+	replaced, _ := NewCookieStore("session", newKey)
+	expired, err := store.Save(ctx, data, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		store *CookieStore
+		token string
+		valid bool
+	}{
+		{"old key still accepted", rotated, token, true},
+		{"removed key", replaced, token, false},
+		{"tampered", store, base64.RawURLEncoding.EncodeToString(tampered), false},
+		{"truncated", store, base64.RawURLEncoding.EncodeToString(sealed[:8]), false},
+		{"not base64", store, "%%%", false},
+		{"other cookie name", other, token, false},
+		{"expired", store, expired, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.store.Load(ctx, test.token)
+			switch {
+			case test.valid && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case !test.valid && !errors.Is(err, ErrNotFound):
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+
+	big := &Data{ID: "id", Values: map[string]interface{}{"blob": strings.Repeat("x", maxCookieSize)}}
+	if _, err := store.Save(ctx, big, time.Now().Add(time.Hour)); err == nil || !strings.Contains(err.Error(), "do not fit in a cookie") {
+		t.Errorf("expected an oversized session to fail, got %v", err)
+	}
+	if _, err := NewCookieStore("session", []byte("short")); err == nil {
+		t.Error("expected an invalid AES key to fail")
+	}
+}
+
+// newTestServer serves the session routes of the tests
+func newTestServer(t *testing.T, store Store) *goservetest.Server {
+	t.Helper()
+	config := Config{Keys: []configuration.Secret{configuration.Secret(base64.StdEncoding.EncodeToString(newKey))}}
+	manager, err := NewManager(store, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := server.New().
+		AddGlobalMiddleware("session", manager.Middleware()).
+		AddRoutes([]server.RouteInfo{
+			server.CreatePOST("/login", func(w http.ResponseWriter, r *http.Request) {
+				s := FromContext(r.Context())
+				s.RenewID()
+				s.Set("user", r.URL.Query().Get("user"))
+				s.AddFlash("Welcome back")
+				w.WriteHeader(http.StatusSeeOther)
+			}),
+			server.CreateGET("/", func(w http.ResponseWriter, r *http.Request) {
+				s := FromContext(r.Context())
+				fmt.Fprintf(w, "user=%s flashes=%v", s.GetString("user"), s.Flashes())
+			}),
+			server.CreatePOST("/logout", func(w http.ResponseWriter, r *http.Request) {
+				FromContext(r.Context()).Destroy()
+			}),
+		})
+	return goservetest.New(t, builder)
+}
+
+// sessionCookie returns the session cookie set by the response, nil without
+// one
+func sessionCookie(response *goservetest.Response) *http.Cookie {
+	for _, cookie := range response.Raw().Cookies() {
+		if cookie.Name == "session" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestManager(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		store Store
+	}{
+		{"cookie store", nil},
+		{"memory store", NewMemoryStore()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			srv := newTestServer(t, test.store)
+
+			anonymous := srv.GET("/").Expect(t).Status(http.StatusOK).Body("user= flashes=[]")
+			if cookie := sessionCookie(anonymous); cookie != nil {
+				t.Errorf("expected no cookie for an unused session, got %v", cookie)
+			}
+
+			// A session fixed before login is replaced
+			fixed := sessionCookie(srv.POST("/login?user=mallory").Expect(t).Status(http.StatusSeeOther))
+			login := srv.POST("/login?user=alice").WithCookie(fixed).Expect(t).Status(http.StatusSeeOther)
+			cookie := sessionCookie(login)
+			if cookie == nil || cookie.Value == fixed.Value || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("unexpected session cookie %v", cookie)
+			}
+
+			// Flashes are shown once, the fixed session brought one
+			shown := srv.GET("/").WithCookie(cookie).Expect(t).Body("user=alice flashes=[Welcome back Welcome back]")
+			cookie = sessionCookie(shown)
+			srv.GET("/").WithCookie(cookie).Expect(t).Body("user=alice flashes=[]")
+
+			logout := sessionCookie(srv.POST("/logout").WithCookie(cookie).Expect(t).Status(http.StatusOK))
+			if logout == nil || logout.MaxAge >= 0 || logout.Value != "" {
+				t.Errorf("expected logout to clear the cookie, got %v", logout)
+			}
+
+			// Only server side stores revoke the session, a copy of the cookie
+			// stays valid with the cookie store until it expires
+			replayed := "user= flashes=[]"
+			if test.store == nil {
+				replayed = "user=alice flashes=[]"
+			}
+			srv.GET("/").WithCookie(cookie).Expect(t).Body(replayed)
+			if test.store != nil {
+				srv.GET("/").WithCookie(fixed).Expect(t).Body("user= flashes=[]")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by stores for unknown, expired or invalid tokens
+var ErrNotFound = errors.New("session not found")
+
+// Store saves sessions. The token is the value of the cookie: the session
+// ID for server side stores, the encrypted data for CookieStore.
+type Store interface {
+	Load(ctx context.Context, token string) (*Data, error)
+	// Save stores data until expires and returns the token of the cookie
+	Save(ctx context.Context, data *Data, expires time.Time) (string, error)
+	Delete(ctx context.Context, token string) error
+}
+
+// MemoryStore keeps the sessions in the process: they are lost on restart
+// and not shared between instances
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	lastPurge time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, token string) (*Data, error) {
+	s.mu.Lock()
+	entry, ok := s.sessions[token]
+	s.mu.Unlock()
+	if !ok || !s.now().Before(entry.expires) {
+		return nil, ErrNotFound
+	}
+	var data Data
+	if err := json.Unmarshal(entry.data, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Save copies the data as JSON, like the other stores, so that handlers
+// see the same value types whatever the store
+func (s *MemoryStore) Save(ctx context.Context, data *Data, expires time.Time) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[data.ID] = memoryEntry{data: encoded, expires: expires}
+	s.purge()
+	return data.ID, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+	return nil
+}
+
+// purge removes the expired sessions, at most once a minute
+func (s *MemoryStore) purge() {
+	now := s.now()
+	if now.Sub(s.lastPurge) < time.Minute {
+		return
+	}
+	s.lastPurge = now
+	for token, entry := range s.sessions {
+		if !now.Before(entry.expires) {
+			delete(s.sessions, token)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package traffic
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// Entry is one recorded request/response pair, stored as a JSONL line
+type Entry struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Route     string  `json:"route,omitempty"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Request   Message `json:"request"`
+	Status    int     `json:"status"`
+	Response  Message `json:"response"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// Message is the recorded side of an exchange
+type Message struct {
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Body      string              `json:"body,omitempty"`
+	Encoding  string              `json:"encoding,omitempty"`
+	Truncated bool                `json:"truncated,omitempty"`
+	// The body was left out: of a content type not recorded, or that could
+	// not be parsed to redact its fields
+	Omitted bool `json:"omitted,omitempty"`
+}
+
+func (m *Message) setBody(body []byte) {
+	if utf8.Valid(body) {
+		m.Body = string(body)
+	} else {
+		m.Body = base64.StdEncoding.EncodeToString(body)
+		m.Encoding = "base64"
+	}
+}
+
+// BodyBytes returns the decoded body
+func (m Message) BodyBytes() ([]byte, error) {
+	if m.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(m.Body)
+	}
+	return []byte(m.Body), nil
+}
+
+// ReadEntries decodes a JSONL recording
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func ReadFile(filename string) ([]Entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := ReadEntries(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,213 @@
+package traffic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultFilename = "traffic.jsonl"
+
+var defaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Csrf-Token",
+}
+
+var defaultRedactedQuery = []string{"access_token", "api_key", "password", "token"}
+
+type Options struct {
+	// Output file (default: traffic.jsonl)
+	Filename string
+	// Bodies are cut after this many bytes (default: 64 KiB)
+	MaxBodySize int
+	// The file is rotated once it reaches this size (default: 100 MiB)
+	MaxFileSize int64
+	// Number of rotated files kept (default: 5)
+	MaxBackups int
+	// Headers and query parameters replaced by [REDACTED], in addition to
+	// the defaults (Authorization, Cookie, token, ...)
+	RedactHeaders []string
+	RedactQuery   []string
+	// JSON and form fields replaced by [REDACTED] at any depth, in addition
+	// to the defaults (password, token, api_key, ...); case insensitive
+	RedactFields []string
+	// Content types whose bodies are recorded as is, in addition to JSON and
+	// forms; the bodies of other types are left out
+	BodyContentTypes []string
+	// Skip excludes requests from the recording, e.g. health checks
+	Skip func(*http.Request) bool
+}
+
+// Recorder is a middleware appending every exchange to a JSONL file
+type Recorder struct {
+	opts            Options
+	file            *RotatingFile
+	redactedHeaders map[string]bool
+	redactedQuery   map[string]bool
+	redactedFields  map[string]bool
+	bodyTypes       map[string]bool
+}
+
+func NewRecorder(opts Options) *Recorder {
+	if opts.Filename == "" {
+		opts.Filename = DefaultFilename
+	}
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	if opts.MaxFileSize == 0 {
+		opts.MaxFileSize = 100 << 20
+	}
+	if opts.MaxBackups == 0 {
+		opts.MaxBackups = 5
+	}
+
+	recorder := &Recorder{
+		opts:            opts,
+		file:            NewRotatingFile(opts.Filename, opts.MaxFileSize, opts.MaxBackups),
+		redactedHeaders: make(map[string]bool),
+		redactedQuery:   make(map[string]bool),
+		redactedFields:  make(map[string]bool),
+		bodyTypes:       make(map[string]bool),
+	}
+	for _, header := range append(defaultRedactedHeaders, opts.RedactHeaders...) {
+		recorder.redactedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, param := range append(defaultRedactedQuery, opts.RedactQuery...) {
+		recorder.redactedQuery[param] = true
+	}
+	for _, field := range append(defaultRedactedFields, opts.RedactFields...) {
+		recorder.redactedFields[strings.ToLower(field)] = true
+	}
+	for _, contentType := range append(defaultBodyTypes, opts.BodyContentTypes...) {
+		recorder.bodyTypes[strings.ToLower(contentType)] = true
+	}
+	return recorder
+}
+
+func (rec *Recorder) Close() error {
+	return rec.file.Close()
+}
+
+// Middleware matches server.MiddlewareFunc:
+//
+//	builder.AddGlobalMiddleware("Recorder", recorder.Middleware)
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec.opts.Skip != nil && rec.opts.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var requestBody []byte
+		if r.Body != nil && r.Body != http.NoBody {
+			requestBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(rec.opts.MaxBodySize)+1))
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+
+		capture := &captureWriter{ResponseWriter: w, status: http.StatusOK, limit: rec.opts.MaxBodySize}
+		start := time.Now()
+		next.ServeHTTP(capture, r)
+		latency := time.Since(start)
+
+		entry := Entry{
+			Time:      start.UTC().Format(time.RFC3339Nano),
+			Method:    r.Method,
+			Route:     r.Pattern,
+			Path:      r.URL.Path,
+			Query:     rec.redactQuery(r.URL.Query()),
+			Request:   rec.message(r.Header, requestBody, len(requestBody) > rec.opts.MaxBodySize),
+			Status:    capture.status,
+			Response:  rec.message(w.Header(), capture.body.Bytes(), capture.overflow),
+			LatencyMs: float64(latency.Microseconds()) / 1000,
+		}
+
+		line, err := json.Marshal(entry)
+		if err == nil {
+			err = rec.file.WriteLine(line)
+		}
+		if err != nil {
+			log.Printf("Traffic recording failed: %v", err)
+		}
+	})
+}
+
+// message records one side of the exchange, redacted, with the body cut
+// after MaxBodySize
+func (rec *Recorder) message(header http.Header, body []byte, truncated bool) Message {
+	message := Message{Headers: rec.redactHeaders(header)}
+	if len(body) == 0 {
+		return message
+	}
+	contentType := header.Get("Content-Type")
+	if !rec.recordsBody(contentType) {
+		message.Omitted = true
+		return message
+	}
+	if len(body) > rec.opts.MaxBodySize {
+		body = body[:rec.opts.MaxBodySize]
+	}
+	message.Truncated = truncated
+	body, ok := rec.redactBody(contentType, body)
+	if !ok {
+		message.Omitted = true
+		return message
+	}
+	message.setBody(body)
+	return message
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int
+	overflow    bool
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status = status
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	c.wroteHeader = true
+	if room := c.limit - c.body.Len(); room > 0 {
+		if len(p) > room {
+			c.body.Write(p[:room])
+			c.overflow = true
+		} else {
+			c.body.Write(p)
+		}
+	} else if len(p) > 0 {
+		c.overflow = true
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *captureWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *captureWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,203 @@
+package traffic
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// record sends one request through a recorder and returns its entry
+func record(t *testing.T, opts Options, handler http.HandlerFunc, r *http.Request) Entry {
+	t.Helper()
+	opts.Filename = filepath.Join(t.TempDir(), "traffic.jsonl")
+	recorder := NewRecorder(opts)
+	recorder.Middleware(handler).ServeHTTP(httptest.NewRecorder(), r)
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadFile(opts.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry, got %d", len(entries))
+	}
+	return entries[0]
+}
+
+func echo(contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write(body)
+	}
+}
+
+func TestHeaderAndQueryRedaction(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/orders?token=abc&page=2&tenant=acme", nil)
+	r.Header.Set("Authorization", "Bearer abc")
+	r.Header.Set("X-Tenant-Key", "k")
+	r.Header.Set("Accept", "application/json")
+	entry := record(t, Options{RedactHeaders: []string{"x-tenant-key"}, RedactQuery: []string{"tenant"}}, echo("application/json"), r)
+
+	query, _ := url.ParseQuery(entry.Query)
+	if query.Get("token") != redacted || query.Get("tenant") != redacted || query.Get("page") != "2" {
+		t.Errorf("unexpected query %q", entry.Query)
+	}
+	for _, name := range []string{"Authorization", "X-Tenant-Key"} {
+		if values := entry.Request.Headers[name]; len(values) != 1 || values[0] != redacted {
+			t.Errorf("%s: expected %s, got %v", name, redacted, values)
+		}
+	}
+	if values := entry.Request.Headers["Accept"]; len(values) != 1 || values[0] != "application/json" {
+		t.Errorf("expected Accept to be kept, got %v", values)
+	}
+	if values := entry.Response.Headers["Set-Cookie"]; len(values) != 1 || values[0] != redacted {
+		t.Errorf("expected Set-Cookie to be redacted, got %v", values)
+	}
+}
+
+func TestBodyRedaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        Options
+		// Expected recorded body, and whether it is left out
+		recorded string
+		omitted  bool
+	}{
+		{
+			name:        "json fields at any depth",
+			contentType: "application/json",
+			body:        `{"user":"alice","Password":"hunter2","tokens":[{"refresh_token":"r"}],"amount":12.50}`,
+			recorded:    `{"amount":12.50,"tokens":[{"refresh_token":"[REDACTED]"}],"Password":"[REDACTED]","user":"alice"}`,
+		},
+		{
+			name:        "custom json field",
+			contentType: "application/vnd.api+json; charset=utf-8",
+			body:        `{"card":{"cvc":"123"}}`,
+			opts:        Options{RedactFields: []string{"CVC"}},
+			recorded:    `{"card":{"cvc":"[REDACTED]"}}`,
+		},
+		{
+			name:        "json without secrets is kept as sent",
+			contentType: "application/json",
+			body:        `{ "user": "alice" }`,
+			recorded:    `{ "user": "alice" }`,
+		},
+		{
+			name:        "form fields",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user=alice&password=hunter2&csrf_token=t",
+			recorded:    "csrf_token=%5BREDACTED%5D&password=%5BREDACTED%5D&user=alice",
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        `{"password":"hunter2"`,
+			omitted:     true,
+		},
+		{
+			name:        "other content types",
+			contentType: "text/plain",
+			body:        "password=hunter2",
+			omitted:     true,
+		},
+		{
+			name:        "opted in content type",
+			contentType: "text/plain; charset=utf-8",
+			body:        "hello",
+			opts:        Options{BodyContentTypes: []string{"text/plain"}},
+			recorded:    "hello",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+			entry := record(t, test.opts, echo(test.contentType), r)
+
+			for side, message := range map[string]Message{"request": entry.Request, "response": entry.Response} {
+				if message.Omitted != test.omitted {
+					t.Errorf("%s: expected omitted=%v, got %+v", side, test.omitted, message)
+				}
+				if test.omitted {
+					if message.Body != "" {
+						t.Errorf("%s: expected no body, got %q", side, message.Body)
+					}
+					continue
+				}
+				got := message.Body
+				if strings.HasPrefix(test.contentType, "application/json") && got != test.body {
+					// Redacted documents are encoded again, compare them decoded
+					var left, right interface{}
+					json.Unmarshal([]byte(got), &left)
+					json.Unmarshal([]byte(test.recorded), &right)
+					gotJSON, _ := json.Marshal(left)
+					expectedJSON, _ := json.Marshal(right)
+					got, test.recorded = string(gotJSON), string(expectedJSON)
+				}
+				if got != test.recorded {
+					t.Errorf("%s: expected %q, got %q", side, test.recorded, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	body := strings.Repeat("a", 20)
+	r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/plain")
+	var received string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("b", 6)))
+		w.Write([]byte(strings.Repeat("b", 6)))
+	}
+	entry := record(t, Options{MaxBodySize: 10, BodyContentTypes: []string{"text/plain"}}, handler, r)
+
+	if received != body {
+		t.Errorf("the handler must read the whole body, got %q", received)
+	}
+	if entry.Request.Body != strings.Repeat("a", 10) || !entry.Request.Truncated {
+		t.Errorf("unexpected request %+v", entry.Request)
+	}
+	if entry.Response.Body != strings.Repeat("b", 10) || !entry.Response.Truncated {
+		t.Errorf("unexpected response %+v", entry.Response)
+	}
+
+	// A truncated JSON document cannot be redacted, so it is left out
+	r = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"alice","password":"hunter2"}`))
+	r.Header.Set("Content-Type", "application/json")
+	entry = record(t, Options{MaxBodySize: 20}, echo("application/json"), r)
+	if !entry.Request.Omitted || !entry.Request.Truncated || entry.Request.Body != "" {
+		t.Errorf("unexpected request %+v", entry.Request)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "traffic.jsonl")
+	recorder := NewRecorder(Options{Filename: filename, Skip: func(r *http.Request) bool { return r.URL.Path == "/health" }})
+	handler := recorder.Middleware(echo("application/json"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+	recorder.Close()
+
+	entries, err := ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Path != "/orders" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
@@ -0,0 +1,123 @@
+package traffic
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var defaultRedactedFields = []string{
+	"password",
+	"passwd",
+	"secret",
+	"client_secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"api_key",
+	"apiKey",
+	"csrf_token",
+}
+
+// defaultBodyTypes are the content types whose bodies are recorded, the
+// ones whose fields can be redacted
+var defaultBodyTypes = []string{"application/json", "application/x-www-form-urlencoded"}
+
+// recordsBody tells whether bodies of contentType are recorded: JSON, forms
+// and the types of Options.BodyContentTypes
+func (rec *Recorder) recordsBody(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return rec.bodyTypes[mediaType] || strings.HasSuffix(mediaType, "+json")
+}
+
+// redactBody masks the redacted fields of JSON and form bodies. Bodies
+// that cannot be parsed, such as truncated ones, are not recorded since
+// their fields cannot be found.
+func (rec *Recorder) redactBody(contentType string, body []byte) ([]byte, bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var document interface{}
+		if err := decoder.Decode(&document); err != nil || decoder.More() {
+			return nil, false
+		}
+		if !rec.redactJSON(document) {
+			return body, true
+		}
+		redactedBody, err := json.Marshal(document)
+		return redactedBody, err == nil
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, false
+		}
+		changed := false
+		for key := range form {
+			if rec.redactedFields[strings.ToLower(key)] {
+				form[key] = []string{redacted}
+				changed = true
+			}
+		}
+		if !changed {
+			return body, true
+		}
+		return []byte(form.Encode()), true
+	}
+	return body, true
+}
+
+// redactJSON masks the redacted fields at any depth and tells whether one
+// was found
+func (rec *Recorder) redactJSON(value interface{}) bool {
+	changed := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if rec.redactedFields[strings.ToLower(key)] {
+				value[key] = redacted
+				changed = true
+			} else if rec.redactJSON(field) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if rec.redactJSON(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (rec *Recorder) redactHeaders(header http.Header) map[string][]string {
+	headers := make(map[string][]string, len(header))
+	for key, values := range header {
+		if rec.redactedHeaders[http.CanonicalHeaderKey(key)] {
+			headers[key] = []string{redacted}
+		} else {
+			headers[key] = values
+		}
+	}
+	return headers
+}
+
+func (rec *Recorder) redactQuery(query url.Values) string {
+	for key := range query {
+		if rec.redactedQuery[key] {
+			query[key] = []string{redacted}
+		}
+	}
+	return query.Encode()
+}
@@ -0,0 +1,278 @@
+package traffic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type ReplayOptions struct {
+	// In-process target; takes precedence over BaseURL
+	Handler http.Handler
+	// Target reached over HTTP, e.g. http://localhost:8080
+	BaseURL string
+	Client  *http.Client
+	// Headers sent with every request, typically real credentials replacing
+	// the redacted ones
+	Headers http.Header
+	// Response headers compared (default: Content-Type)
+	CompareHeaders []string
+	// JSON paths excluded from the body comparison, e.g. "id" or
+	// "items[*].createdAt"
+	IgnorePaths []string
+}
+
+// Difference is a mismatch between the recorded and the replayed response
+type Difference struct {
+	Field    string
+	Recorded string
+	Replayed string
+}
+
+type Result struct {
+	Entry       Entry
+	Status      int
+	Differences []Difference
+	Err         error
+}
+
+func (r Result) OK() bool {
+	return r.Err == nil && len(r.Differences) == 0
+}
+
+var skippedHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+	"Accept-Encoding":   true,
+}
+
+// Replay sends every entry to the target and compares the responses with
+// the recorded ones
+func Replay(entries []Entry, opts ReplayOptions) []Result {
+	if len(opts.CompareHeaders) == 0 {
+		opts.CompareHeaders = []string{"Content-Type"}
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	ignored := make([][]string, len(opts.IgnorePaths))
+	for i, path := range opts.IgnorePaths {
+		ignored[i] = splitPath(path)
+	}
+
+	results := make([]Result, 0, len(entries))
+	for _, entry := range entries {
+		result := Result{Entry: entry}
+		status, header, body, err := send(entry, opts)
+		if err != nil {
+			result.Err = err
+		} else {
+			result.Status = status
+			result.Differences = compareResponse(entry, status, header, body, opts.CompareHeaders, ignored)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func send(entry Entry, opts ReplayOptions) (int, http.Header, []byte, error) {
+	body, err := entry.Request.BodyBytes()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if entry.Request.Truncated {
+		return 0, nil, nil, fmt.Errorf("request body was truncated when recorded")
+	}
+	if entry.Request.Omitted {
+		return 0, nil, nil, fmt.Errorf("request body was not recorded")
+	}
+
+	target := entry.Path
+	if entry.Query != "" {
+		target += "?" + entry.Query
+	}
+
+	request, err := http.NewRequest(entry.Method, strings.TrimRight(opts.BaseURL, "/")+target, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	for key, values := range entry.Request.Headers {
+		if skippedHeaders[http.CanonicalHeaderKey(key)] || (len(values) == 1 && values[0] == redacted) {
+			continue
+		}
+		request.Header[key] = values
+	}
+	for key, values := range opts.Headers {
+		request.Header[key] = values
+	}
+
+	if opts.Handler != nil {
+		request.RequestURI = target
+		request.RemoteAddr = "192.0.2.1:1234"
+		recorder := httptest.NewRecorder()
+		opts.Handler.ServeHTTP(recorder, request)
+		return recorder.Code, recorder.Header(), recorder.Body.Bytes(), nil
+	}
+
+	resp, err := opts.Client.Do(request)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, data, err
+}
+
+func compareResponse(entry Entry, status int, header http.Header, body []byte, headers []string, ignored [][]string) []Difference {
+	var differences []Difference
+	if status != entry.Status {
+		differences = append(differences, Difference{"status", fmt.Sprint(entry.Status), fmt.Sprint(status)})
+	}
+
+	for _, name := range headers {
+		recorded := strings.Join(lookupHeader(entry.Response.Headers, name), ", ")
+		replayed := strings.Join(header.Values(name), ", ")
+		if recorded != replayed && recorded != redacted {
+			differences = append(differences, Difference{"header " + name, recorded, replayed})
+		}
+	}
+
+	if entry.Response.Truncated || entry.Response.Omitted {
+		return differences
+	}
+	recordedBody, err := entry.Response.BodyBytes()
+	if err != nil {
+		return append(differences, Difference{"body", "undecodable recording", ""})
+	}
+
+	var recordedJSON, replayedJSON interface{}
+	if json.Unmarshal(recordedBody, &recordedJSON) == nil && json.Unmarshal(body, &replayedJSON) == nil {
+		diffJSON("body", nil, recordedJSON, replayedJSON, ignored, &differences)
+	} else if !bytes.Equal(recordedBody, body) {
+		differences = append(differences, Difference{"body", abbreviate(string(recordedBody)), abbreviate(string(body))})
+	}
+	return differences
+}
+
+func lookupHeader(headers map[string][]string, name string) []string {
+	for key, values := range headers {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func diffJSON(label string, path []string, recorded, replayed interface{}, ignored [][]string, differences *[]Difference) {
+	for _, pattern := range ignored {
+		if matchPath(pattern, path) {
+			return
+		}
+	}
+	// Values redacted when recorded cannot be compared
+	if recorded == redacted {
+		return
+	}
+
+	switch left := recorded.(type) {
+	case map[string]interface{}:
+		right, ok := replayed.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for key := range left {
+			keys[key] = true
+		}
+		for key := range right {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			diffJSON(label+"."+key, append(path[:len(path):len(path)], key), left[key], right[key], ignored, differences)
+		}
+		return
+	case []interface{}:
+		right, ok := replayed.([]interface{})
+		if !ok || len(left) != len(right) {
+			break
+		}
+		for i := range left {
+			diffJSON(fmt.Sprintf("%s[%d]", label, i), append(path[:len(path):len(path)], fmt.Sprint(i)), left[i], right[i], ignored, differences)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		*differences = append(*differences, Difference{label, jsonText(recorded), jsonText(replayed)})
+	}
+}
+
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "$.")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	return strings.Split(strings.Trim(path, "."), ".")
+}
+
+func matchPath(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonText(value interface{}) string {
+	if value == nil {
+		return "<missing>"
+	}
+	data, _ := json.Marshal(value)
+	return abbreviate(string(data))
+}
+
+func abbreviate(text string) string {
+	if len(text) > 120 {
+		return text[:120] + "..."
+	}
+	return text
+}
+
+// Report formats the results, listing every difference of failed entries
+func Report(results []Result) string {
+	var buf strings.Builder
+	failed := 0
+	for _, result := range results {
+		if result.OK() {
+			continue
+		}
+		failed++
+		fmt.Fprintf(&buf, "%s %s", result.Entry.Method, result.Entry.Path)
+		if result.Entry.Query != "" {
+			fmt.Fprintf(&buf, "?%s", result.Entry.Query)
+		}
+		buf.WriteString("\n")
+		if result.Err != nil {
+			fmt.Fprintf(&buf, "  error: %v\n", result.Err)
+		}
+		for _, difference := range result.Differences {
+			fmt.Fprintf(&buf, "  %s: recorded %s, replayed %s\n", difference.Field, difference.Recorded, difference.Replayed)
+		}
+	}
+	fmt.Fprintf(&buf, "%d/%d exchanges matched\n", len(results)-failed, len(results))
+	return buf.String()
+}
@@ -0,0 +1,94 @@
+package traffic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	version := "v2"
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer real" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/orders":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":      "new-id",
+				"version": version,
+				"items":   []map[string]string{{"sku": "a", "createdAt": "now"}},
+				"token":   "fresh",
+				"page":    r.URL.Query().Get("page"),
+			})
+		case "/echo":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(body)
+		default:
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer backend.Close()
+
+	recording := strings.Join([]string{
+		`{"method":"GET","path":"/orders","query":"page=2","request":{"headers":{"Authorization":["[REDACTED]"]}},"status":200,` +
+			`"response":{"headers":{"Content-Type":["application/json"]},"body":"{\"id\":\"old-id\",\"version\":\"v1\",\"items\":[{\"sku\":\"a\",\"createdAt\":\"then\"}],\"token\":\"[REDACTED]\",\"page\":\"2\"}"}}`,
+		`{"method":"POST","path":"/echo","request":{"headers":{"Content-Type":["application/json"]},"body":"{\"sku\":\"b\"}"},"status":200,` +
+			`"response":{"headers":{"Content-Type":["application/json"]},"body":"{\"sku\":\"b\"}"}}`,
+		`{"method":"POST","path":"/upload","request":{"headers":{"Content-Type":["image/png"]},"omitted":true},"status":201,"response":{}}`,
+		`{"method":"GET","path":"/missing","request":{},"status":200,"response":{"omitted":true}}`,
+	}, "\n")
+	entries, err := ReadEntries(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := Replay(entries, ReplayOptions{
+		BaseURL:     backend.URL + "/",
+		Headers:     http.Header{"Authorization": {"Bearer real"}},
+		IgnorePaths: []string{"id", "items[*].createdAt"},
+	})
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	orders := results[0]
+	if orders.Err != nil || len(orders.Differences) != 1 || orders.Differences[0] != (Difference{"body.version", `"v1"`, `"v2"`}) {
+		t.Errorf("expected only the version to differ, got %+v", orders)
+	}
+	if !results[1].OK() {
+		t.Errorf("unexpected result %+v", results[1])
+	}
+	if err := results[2].Err; err == nil || err.Error() != "request body was not recorded" {
+		t.Errorf("expected an omitted request body to fail, got %v", err)
+	}
+	if missing := results[3]; missing.Err != nil || len(missing.Differences) != 1 || missing.Differences[0].Field != "status" {
+		t.Errorf("expected only the status to differ, got %+v", missing)
+	}
+
+	report := Report(results)
+	for _, expected := range []string{"GET /orders?page=2\n  body.version: recorded \"v1\", replayed \"v2\"", "error: request body was not recorded", "1/4 exchanges matched"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected %q in %q", expected, report)
+		}
+	}
+
+	// The same recording replayed in process
+	version = "v1"
+	handled := Replay(entries[:2], ReplayOptions{
+		Handler:     backend.Config.Handler,
+		Headers:     http.Header{"Authorization": {"Bearer real"}},
+		IgnorePaths: []string{"id", "items[*].createdAt"},
+	})
+	for _, result := range handled {
+		if !result.OK() {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package traffic
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile appends lines to a file, renaming it to name.1, name.2, ...
+// once it reaches MaxSize bytes
+type RotatingFile struct {
+	Filename   string
+	MaxSize    int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func NewRotatingFile(filename string, maxSize int64, maxBackups int) *RotatingFile {
+	return &RotatingFile{
+		Filename:   filename,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}
+}
+
+// WriteLine writes a complete line atomically with regard to other writers
+func (f *RotatingFile) WriteLine(line []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(line))+1 > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(append(line, '\n'))
+	f.size += int64(n)
+	return err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if f.MaxBackups <= 0 {
+		if err := os.Remove(f.Filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return f.open()
+	}
+
+	os.Remove(backupName(f.Filename, f.MaxBackups))
+	for i := f.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(f.Filename, i), backupName(f.Filename, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.Filename, backupName(f.Filename, 1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func backupName(filename string, index int) string {
+	return fmt.Sprintf("%s.%d", filename, index)
+}
@@ -0,0 +1,71 @@
+package traffic
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readLines(t *testing.T, filename string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestRotatingFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "traffic.jsonl")
+	// Two 4 byte lines per file
+	file := NewRotatingFile(filename, 10, 2)
+	for _, line := range []string{"aaa", "bbb", "ccc", "ddd", "eee", "fff", "ggg"} {
+		if err := file.WriteLine([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		filename:        "ggg",
+		filename + ".1": "eee fff",
+		filename + ".2": "ccc ddd",
+	}
+	for name, lines := range expected {
+		if got := strings.Join(readLines(t, name), " "); got != lines {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(name), lines, got)
+		}
+	}
+	if _, err := os.Stat(filename + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups, got %v", err)
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "traffic.jsonl")
+	if err := os.WriteFile(filename, []byte("aaa\nbbb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The size of the existing file counts towards the limit
+	file := NewRotatingFile(filename, 10, 0)
+	if err := file.WriteLine([]byte("ccc")); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if got := strings.Join(readLines(t, filename), " "); got != "ccc" {
+		t.Errorf("expected the file to be replaced without backups, got %q", got)
+	}
+	if _, err := os.Stat(filename + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected no backup, got %v", err)
+	}
+
+	// A line larger than the limit is written to an empty file
+	file = NewRotatingFile(filepath.Join(t.TempDir(), "big.jsonl"), 2, 1)
+	if err := file.WriteLine([]byte("larger than the limit")); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+}